@@ -0,0 +1,244 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type apolloParser struct {
+	prio       int
+	server     string
+	appID      string
+	cluster    string
+	namespaces []string
+	watch      bool
+}
+
+// NewApolloParser returns a new parser to read the options from the Apollo
+// config center by its HTTP API.
+//
+// server is the address of the config service, such as "http://127.0.0.1:8080".
+// cluster is "default" if empty, and namespaces is ["application"] if empty.
+//
+// The keys of the namespace are split into the group and the option by the group
+// separator, such as "db.mysql.conn" for the option "conn" in the group "db.mysql".
+// The namespaces should not contain the same key; or, the latter wins.
+//
+// If watch is true, it will long poll the notifications of the namespaces, and
+// update the option values by SetOptValue when a namespace is released.
+//
+// Notice: the keys that have not been registered as the options are ignored.
+// And only the namespaces of the properties format are supported.
+func NewApolloParser(priority int, server, appID, cluster string,
+	namespaces []string, watch bool) Parser {
+	if server == "" || appID == "" {
+		panic(fmt.Errorf("the server and the appID must not be empty"))
+	}
+
+	if cluster == "" {
+		cluster = "default"
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{"application"}
+	}
+
+	return apolloParser{
+		prio:       priority,
+		server:     strings.TrimRight(server, "/"),
+		appID:      appID,
+		cluster:    cluster,
+		namespaces: namespaces,
+		watch:      watch,
+	}
+}
+
+func (p apolloParser) Name() string {
+	return "apollo"
+}
+
+func (p apolloParser) Priority() int {
+	return p.prio
+}
+
+func (p apolloParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p apolloParser) Post(c *Config) error {
+	return nil
+}
+
+func (p apolloParser) Parse(c *Config) error {
+	w := &apolloWatcher{
+		apolloParser: p,
+		conf:         c,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		releases:     make(map[string]string, len(p.namespaces)),
+		values:       make(map[string]string, 32),
+	}
+
+	for _, ns := range p.namespaces {
+		if err := w.load(ns); err != nil {
+			return err
+		}
+	}
+
+	if p.watch {
+		c.goWatch(w.run)
+	}
+	return nil
+}
+
+type apolloWatcher struct {
+	apolloParser
+
+	conf     *Config
+	client   *http.Client
+	releases map[string]string
+	values   map[string]string
+}
+
+func (w *apolloWatcher) load(ns string) error {
+	path := fmt.Sprintf("%s/configs/%s/%s/%s", w.server, url.PathEscape(w.appID),
+		url.PathEscape(w.cluster), url.PathEscape(ns))
+	if key := w.releases[ns]; key != "" {
+		path += "?releaseKey=" + url.QueryEscape(key)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	default:
+		return fmt.Errorf("failed to get the namespace '%s': %s", ns, resp.Status)
+	}
+
+	var result struct {
+		Configurations map[string]string `json:"configurations"`
+		ReleaseKey     string            `json:"releaseKey"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	w.conf.Printf("[%s] Loading the namespace '%s', releaseKey=%s", w.Name(), ns, result.ReleaseKey)
+	for key, value := range result.Configurations {
+		if last, ok := w.values[key]; ok && last == value {
+			continue
+		}
+
+		group, name := w.conf.splitOptKey(key)
+		if err = w.conf.setOptValueIfExist(w.prio, group, name, value); err != nil {
+			return err
+		}
+		w.values[key] = value
+	}
+
+	w.releases[ns] = result.ReleaseKey
+	return nil
+}
+
+type apolloNotification struct {
+	NamespaceName  string `json:"namespaceName"`
+	NotificationID int64  `json:"notificationId"`
+}
+
+func (w *apolloWatcher) run(ctx context.Context) {
+	// The server holds the long poll request for 60s at most.
+	client := &http.Client{Timeout: 90 * time.Second}
+
+	ids := make(map[string]int64, len(w.namespaces))
+	for _, ns := range w.namespaces {
+		ids[ns] = -1
+	}
+
+	for {
+		notifications, err := w.poll(ctx, client, ids)
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			w.conf.Printf("[%s] Failed to poll the notifications: %s", w.Name(), err)
+			sleepContext(ctx, time.Second)
+			continue
+		}
+
+		for _, n := range notifications {
+			ids[n.NamespaceName] = n.NotificationID
+			if err = w.load(n.NamespaceName); err != nil {
+				w.conf.Printf("[%s] Failed to reload the namespace '%s': %s",
+					w.Name(), n.NamespaceName, err)
+			}
+		}
+	}
+}
+
+func (w *apolloWatcher) poll(ctx context.Context, client *http.Client,
+	ids map[string]int64) ([]apolloNotification, error) {
+	notifications := make([]apolloNotification, 0, len(ids))
+	for ns, id := range ids {
+		notifications = append(notifications, apolloNotification{ns, id})
+	}
+	data, err := json.Marshal(notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"appId":         []string{w.appID},
+		"cluster":       []string{w.cluster},
+		"notifications": []string{string(data)},
+	}
+	req, err := http.NewRequest(http.MethodGet, w.server+"/notifications/v2?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		notifications = notifications[:0]
+		err = json.NewDecoder(resp.Body).Decode(&notifications)
+		return notifications, err
+	case http.StatusNotModified:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+}
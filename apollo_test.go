@@ -0,0 +1,120 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testApolloServer struct {
+	lock    sync.Mutex
+	release int
+	port    string
+	notify  chan struct{}
+}
+
+func (s *testApolloServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/configs/myapp/default/application":
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		releaseKey := fmt.Sprintf("release-%d", s.release)
+		if r.URL.Query().Get("releaseKey") == releaseKey {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"releaseKey": releaseKey,
+			"configurations": map[string]string{
+				"port":          s.port,
+				"db.mysql.conn": "root@tcp",
+				"unused":        "xxx",
+			},
+		})
+
+	case "/notifications/v2":
+		select {
+		case <-s.notify:
+			s.lock.Lock()
+			id := s.release
+			s.lock.Unlock()
+			fmt.Fprintf(w, `[{"namespaceName": "application", "notificationId": %d}]`, id)
+		case <-r.Context().Done():
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testApolloServer) publish(port string) {
+	s.lock.Lock()
+	s.port = port
+	s.release++
+	s.lock.Unlock()
+	s.notify <- struct{}{}
+}
+
+func TestApolloParser(t *testing.T) {
+	apollo := &testApolloServer{release: 1, port: "80", notify: make(chan struct{})}
+	server := httptest.NewServer(apollo)
+	defer server.Close()
+
+	updated := make(chan interface{}, 1)
+	conf := NewConfig().AddParser(NewApolloParser(50, server.URL, "myapp", "", nil, true))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	conf.Observe(func(group, name string, value interface{}) { updated <- value })
+	apollo.publish("8080")
+	select {
+	case v := <-updated:
+		if v != 8080 {
+			t.Errorf("port: expected 8080, got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout to wait for the update")
+	}
+
+	// Close cancels the long polling request.
+	done := make(chan struct{})
+	go func() { conf.Close(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("timeout to close the long polling")
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord is the record of the change of the option value,
+// see EnableAudit.
+type AuditRecord struct {
+	Time time.Time
+	ChangeEvent
+}
+
+// auditTrail is the ring buffer of the audit records.
+type auditTrail struct {
+	lock    sync.Mutex
+	records []AuditRecord
+	next    int
+	full    bool
+}
+
+func (a *auditTrail) add(r AuditRecord) {
+	a.lock.Lock()
+	a.records[a.next] = r
+	if a.next++; a.next == len(a.records) {
+		a.next, a.full = 0, true
+	}
+	a.lock.Unlock()
+}
+
+func (a *auditTrail) all() []AuditRecord {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if !a.full {
+		return append([]AuditRecord(nil), a.records[:a.next]...)
+	}
+
+	records := make([]AuditRecord, 0, len(a.records))
+	records = append(records, a.records[a.next:]...)
+	return append(records, a.records[:a.next]...)
+}
+
+// EnableAudit records the last size changes of the option values into
+// the in-memory ring buffer, which contain the time, the source parser,
+// the old and new values and the generation, so "who changed this setting
+// and when" is answerable in production by AuditTrail or DumpAudit.
+// The values of the secret options are masked.
+//
+// If size is 0, disable it, which is the default.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) EnableAudit(size int) *Config {
+	c.panicIsParsed(true)
+	if size < 0 {
+		panic(fmt.Errorf("the audit size must not be the negative"))
+	} else if size == 0 {
+		c.audit = nil
+	} else {
+		c.audit = &auditTrail{records: make([]AuditRecord, size)}
+	}
+	return c
+}
+
+// AuditTrail returns the audit records from the oldest to the newest.
+//
+// Return nil if the audit is not enabled by EnableAudit.
+func (c *Config) AuditTrail() []AuditRecord {
+	if c.audit == nil {
+		return nil
+	}
+	return c.audit.all()
+}
+
+// DumpAudit writes the audit records into w line by line, such as
+//
+//    2006-01-02T15:04:05Z #3 [ini] db.dsn: old -> new
+//
+// The source is "-" if unknown, such as set by SetOptValue directly.
+func (c *Config) DumpAudit(w io.Writer) error {
+	buf := bytes.NewBuffer(nil)
+	for _, r := range c.AuditTrail() {
+		source := r.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(buf, "%s #%d [%s] %s: %s -> %s\n", r.Time.Format(time.RFC3339Nano),
+			r.Generation, source, c.optKey(r.Group, r.Name),
+			formatOptValue(r.Old), formatOptValue(r.New))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sourceName returns the name of the parser which has the priority,
+// "default" for the default value, or "" if unknown.
+func (c *Config) sourceName(priority int) string {
+	for _, p := range c.parsers {
+		if p.Priority() == priority {
+			return p.Name()
+		}
+	}
+	if priority == 1000 {
+		return "default"
+	}
+	return ""
+}
+
+// optChanged records the change of the option value into the audit trail,
+// then notifies the observers, which are ignored in the dry-run mode.
+func (c *Config) optChanged(e ChangeEvent, secret bool) {
+	if c.dryRun {
+		return
+	}
+
+	if c.audit != nil {
+		r := AuditRecord{Time: time.Now(), ChangeEvent: e}
+		if secret {
+			if formatOptValue(r.Old) != "" {
+				r.Old = SecretMask
+			}
+			if formatOptValue(r.New) != "" {
+				r.New = SecretMask
+			}
+		}
+		c.audit.add(r)
+	}
+
+	c.notifyObservers(e)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AWSSecretsClient is the client interface of AWS Secrets Manager
+// used by the AWS Secrets Manager parser.
+//
+// It can be adapted from the GetSecretValue API of the AWS SDK easily.
+type AWSSecretsClient interface {
+	// GetSecretValue returns the SecretString (or the SecretBinary)
+	// and the VersionId of the current version of the secret.
+	GetSecretValue(secretID string) (value []byte, versionID string, err error)
+}
+
+// NewAWSSecretsParser returns a new parser to resolve the options from
+// the secrets of AWS Secrets Manager.
+//
+// secrets is the mapping from the option to the secret. The key is the option
+// name with its group, such as "db.mysql.password", and the value is the name
+// or ARN of the secret plus the JSON key, such as "myapp/mysql#password".
+// If the JSON key is missing, the option name is used as the key if the secret
+// is a JSON object, or the whole secret is the option value.
+//
+// If refresh is greater than 0, the secrets will be checked every refresh
+// interval. When a secret has been rotated, that's, its VersionId has been
+// changed, the new values will be updated by SetOptValue.
+func NewAWSSecretsParser(priority int, client AWSSecretsClient,
+	secrets map[string]string, refresh time.Duration) Parser {
+	if client == nil {
+		panic(fmt.Errorf("the AWS Secrets Manager client must not be nil"))
+	}
+
+	return newSecretParser("aws-secrets", priority, secrets, refresh,
+		func(ref string) ([]byte, string, error) { return client.GetSecretValue(ref) })
+}
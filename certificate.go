@@ -0,0 +1,168 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+type certOpt struct {
+	name     string
+	short    string
+	help     string
+	_default string
+
+	validators []Validator
+}
+
+// CertificateOpt returns a new TLS certificate option, the value of which
+// is tls.Certificate loaded and validated when parsing, so the TLS setup
+// errors surface at startup rather than the first connection.
+//
+// For the string value, it is one of
+//
+//    "cert.pem,key.pem"  // The paths of the certificate and the private key.
+//    "bundle.pem"        // The path of the file containing both of them.
+//    "-----BEGIN ..."    // The inline PEM containing both of them.
+//
+// _default is the string value as above, which is loaded only if the option
+// has no other value, and is ignored if empty.
+func CertificateOpt(short, name, _default string, help string) ValidatorChainOpt {
+	return certOpt{name: name, short: short, help: help, _default: _default}
+}
+
+// Certificate is equal to CertificateOpt("", name, _default, help).
+func Certificate(name, _default string, help string) ValidatorChainOpt {
+	return CertificateOpt("", name, _default, help)
+}
+
+// Name returns the name of the option.
+func (o certOpt) Name() string {
+	return o.name
+}
+
+// Short returns the shorthand name of the option.
+func (o certOpt) Short() string {
+	return o.short
+}
+
+// Help returns the help doc of the option.
+func (o certOpt) Help() string {
+	return o.help
+}
+
+// Default returns the string value of the default certificate, or nil.
+func (o certOpt) Default() interface{} {
+	if o._default == "" {
+		return nil
+	}
+	return o._default
+}
+
+// Zero returns the empty tls.Certificate.
+func (o certOpt) Zero() interface{} {
+	return tls.Certificate{}
+}
+
+// Parse loads the certificate and the private key as tls.Certificate.
+func (o certOpt) Parse(data interface{}) (interface{}, error) {
+	switch v := data.(type) {
+	case tls.Certificate:
+		return v, nil
+	case *tls.Certificate:
+		return *v, nil
+	}
+
+	s, err := ToString(data)
+	if err != nil {
+		return nil, err
+	}
+	return ToCertificate(s)
+}
+
+// SetValidators resets the validator chain.
+func (o certOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = vs
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o certOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = append(append([]Validator(nil), o.validators...), vs...)
+	return o
+}
+
+// GetValidators returns the validator chain.
+func (o certOpt) GetValidators() []Validator {
+	return o.validators
+}
+
+// ToCertificate loads the TLS certificate and the private key from s, which is
+// "cert.pem,key.pem", "bundle.pem" or the inline PEM, see CertificateOpt.
+func ToCertificate(s string) (cert tls.Certificate, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return cert, fmt.Errorf("the certificate is empty")
+	}
+
+	if strings.Contains(s, "-----BEGIN") {
+		if cert, err = tls.X509KeyPair([]byte(s), []byte(s)); err != nil {
+			return cert, fmt.Errorf("invalid inline certificate: %s", err)
+		}
+	} else if index := strings.IndexByte(s, ','); index > -1 {
+		certFile := strings.TrimSpace(s[:index])
+		keyFile := strings.TrimSpace(s[index+1:])
+		if cert, err = tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return cert, fmt.Errorf("failed to load the certificate '%s' and the key '%s': %s",
+				certFile, keyFile, err)
+		}
+	} else {
+		var data []byte
+		if data, err = ioutil.ReadFile(s); err != nil {
+			return cert, fmt.Errorf("failed to load the certificate: %s", err)
+		} else if cert, err = tls.X509KeyPair(data, data); err != nil {
+			return cert, fmt.Errorf("failed to load the certificate '%s': %s", s, err)
+		}
+	}
+
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return cert, fmt.Errorf("invalid certificate: %s", err)
+	}
+	return
+}
+
+// certificateSummary returns the summary of the certificate without the key.
+func certificateSummary(cert tls.Certificate) string {
+	if cert.Leaf == nil {
+		if len(cert.Certificate) == 0 {
+			return ""
+		}
+
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return ""
+		}
+		cert.Leaf = leaf
+	}
+
+	return fmt.Sprintf("<certificate subject=%q expiry=%s>", cert.Leaf.Subject.String(),
+		cert.Leaf.NotAfter.UTC().Format("2006-01-02T15:04:05Z"))
+}
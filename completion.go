@@ -0,0 +1,243 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type completionOpt struct {
+	name    string
+	short   string
+	help    string
+	isBool  bool
+	choices []string
+}
+
+// optChoices returns the valid values of the option.
+//
+// The option or one of its validators, such as NewStrArrayValidator, may
+// have the method "Choices() []string" to return the valid values.
+func optChoices(opt Opt) []string {
+	type chooser interface {
+		Choices() []string
+	}
+
+	if c, ok := opt.(chooser); ok {
+		return c.Choices()
+	}
+
+	if vopt, ok := opt.(ValidatorChainOpt); ok {
+		for _, v := range vopt.GetValidators() {
+			if c, ok := v.(chooser); ok {
+				return c.Choices()
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) completionOpts(underlineToHyphen bool) []completionOpt {
+	opts := make([]completionOpt, 0, 16)
+	parsed := make(map[string]bool, 8)
+	for _, group := range c.Groups() {
+		gname := group.FullName()
+		if parsed[gname] {
+			continue
+		}
+		parsed[gname] = true
+
+		for _, opt := range group.CliOpts() {
+			name := opt.Name()
+			if gname != c.GetDefaultGroupName() {
+				name = fmt.Sprintf("%s%s%s", gname, c.GetGroupSeparator(), name)
+			}
+
+			if underlineToHyphen {
+				name = strings.Replace(name, "_", "-", -1)
+			}
+
+			_, isBool := opt.Zero().(bool)
+			isBool = isBool || isCountOpt(opt)
+			opts = append(opts, completionOpt{
+				name:    name,
+				short:   opt.Short(),
+				help:    c.optHelp(gname, opt),
+				isBool:  isBool,
+				choices: optChoices(opt),
+			})
+		}
+	}
+
+	sort.Slice(opts, func(i, j int) bool { return opts[i].name < opts[j].name })
+	return opts
+}
+
+// GenerateCompletion generates the completion script of the shell for all
+// the registered CLI options, and writes it into w.
+//
+// shell is one of "bash", "zsh" and "fish", and prog is the name of the
+// program to be completed. underlineToHyphen should be the same as that
+// of the CLI parser.
+//
+// The valid values of the option are completed if the option or one of its
+// validators, such as NewStrArrayValidator, has the method
+// "Choices() []string".
+//
+// For example,
+//
+//    source <(myapp --completion bash)
+func (c *Config) GenerateCompletion(w io.Writer, shell, prog string, underlineToHyphen bool) error {
+	opts := c.completionOpts(underlineToHyphen)
+	buf := bytes.NewBuffer(nil)
+	switch shell {
+	case "bash":
+		genBashCompletion(buf, prog, opts)
+	case "zsh":
+		genZshCompletion(buf, prog, opts)
+	case "fish":
+		genFishCompletion(buf, prog, opts)
+	default:
+		return fmt.Errorf("unsupported shell '%s'", shell)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// SetCompletion sets the name of the CLI option to print the completion
+// script of the given shell and exit, such as "--completion bash".
+//
+// If the CLI parser supports the completion function, it will register
+// the option, and hide it if possible.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) SetCompletion(name string) *Config {
+	c.panicIsParsed(true)
+	c.cName = name
+	return c
+}
+
+// GetCompletion returns the name of the CLI option to print the completion
+// script, which is set by SetCompletion.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) GetCompletion() string {
+	return c.cName
+}
+
+func completionFuncName(prog string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, prog) + "_completion"
+}
+
+func genBashCompletion(buf *bytes.Buffer, prog string, opts []completionOpt) {
+	words := make([]string, 0, len(opts)*2)
+	for _, opt := range opts {
+		words = append(words, "--"+opt.name)
+		if opt.short != "" {
+			words = append(words, "-"+opt.short)
+		}
+	}
+
+	fname := completionFuncName(prog)
+	fmt.Fprintf(buf, "%s() {\n", fname)
+	buf.WriteString("    local cur prev\n")
+	buf.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	buf.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n\n")
+	buf.WriteString("    case \"$prev\" in\n")
+	for _, opt := range opts {
+		if len(opt.choices) == 0 {
+			continue
+		}
+
+		names := "--" + opt.name
+		if opt.short != "" {
+			names += "|-" + opt.short
+		}
+		fmt.Fprintf(buf, "        %s)\n", names)
+		fmt.Fprintf(buf, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n",
+			strings.Join(opt.choices, " "))
+		buf.WriteString("            return\n")
+		buf.WriteString("            ;;\n")
+	}
+	buf.WriteString("    esac\n\n")
+	buf.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(buf, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(words, " "))
+	buf.WriteString("    else\n")
+	buf.WriteString("        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	buf.WriteString("    fi\n")
+	buf.WriteString("}\n\n")
+	fmt.Fprintf(buf, "complete -F %s %s\n", fname, prog)
+}
+
+func genZshCompletion(buf *bytes.Buffer, prog string, opts []completionOpt) {
+	escape := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+
+	fmt.Fprintf(buf, "#compdef %s\n\n", prog)
+	buf.WriteString("_arguments \\\n")
+	for _, opt := range opts {
+		var action string
+		if len(opt.choices) > 0 {
+			action = fmt.Sprintf(":%s:(%s)", opt.name, strings.Join(opt.choices, " "))
+		} else if !opt.isBool {
+			action = fmt.Sprintf(":%s:_files", opt.name)
+		}
+
+		help := escape.Replace(opt.help)
+		if opt.short == "" {
+			fmt.Fprintf(buf, "    '--%s[%s]%s' \\\n", opt.name, help, action)
+		} else {
+			fmt.Fprintf(buf, "    '(-%s --%s)'{-%s,--%s}'[%s]%s' \\\n", opt.short, opt.name,
+				opt.short, opt.name, help, action)
+		}
+	}
+	buf.WriteString("    '*:file:_files'\n")
+}
+
+func genFishCompletion(buf *bytes.Buffer, prog string, opts []completionOpt) {
+	escape := strings.NewReplacer("\\", "\\\\", "'", "\\'")
+
+	for _, opt := range opts {
+		fmt.Fprintf(buf, "complete -c %s -l '%s'", prog, escape.Replace(opt.name))
+		if len(opt.short) == 1 {
+			fmt.Fprintf(buf, " -s '%s'", escape.Replace(opt.short))
+		} else if opt.short != "" {
+			fmt.Fprintf(buf, " -o '%s'", escape.Replace(opt.short))
+		}
+
+		if len(opt.choices) > 0 {
+			fmt.Fprintf(buf, " -x -a '%s'", escape.Replace(strings.Join(opt.choices, " ")))
+		} else if !opt.isBool {
+			buf.WriteString(" -r")
+		}
+
+		if opt.help != "" {
+			fmt.Fprintf(buf, " -d '%s'", escape.Replace(opt.help))
+		}
+		buf.WriteString("\n")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextUnmarshaler reports whether the pointer to the type t implements
+// the interface encoding.TextUnmarshaler.
+func isTextUnmarshaler(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+type customOpt struct {
+	name     string
+	short    string
+	help     string
+	_type    reflect.Type
+	_default interface{}
+
+	validators []Validator
+}
+
+// CustomOpt returns a new option of the custom type, the pointer to which
+// implements the interface encoding.TextUnmarshaler, so any user type can be
+// used as an option without implementing Opt.
+//
+// prototype is the pointer to the custom type, such as new(MyType), which is
+// only used to get the type. The value of the option is the custom type, such
+// as MyType, not the pointer. If _default is not empty, it is the default
+// parsed by UnmarshalText. If the custom type also implements the interface
+// encoding.TextMarshaler, the value is formatted by MarshalText.
+//
+// For the string value, it is parsed by UnmarshalText.
+func CustomOpt(short, name string, prototype encoding.TextUnmarshaler, _default string,
+	help string) ValidatorChainOpt {
+	t := reflect.TypeOf(prototype)
+	if t == nil || t.Kind() != reflect.Ptr {
+		panic(fmt.Errorf("the prototype of the option '%s' must be a pointer", name))
+	}
+
+	o := customOpt{short: short, name: name, help: help, _type: t.Elem()}
+	if _default != "" {
+		v, err := o.Parse(_default)
+		if err != nil {
+			panic(fmt.Errorf("the default of the option '%s' is invalid: %s", name, err))
+		}
+		o._default = v
+	}
+	return o
+}
+
+// Custom is equal to CustomOpt("", name, prototype, _default, help).
+func Custom(name string, prototype encoding.TextUnmarshaler, _default string,
+	help string) ValidatorChainOpt {
+	return CustomOpt("", name, prototype, _default, help)
+}
+
+// Name returns the name of the option.
+func (o customOpt) Name() string {
+	return o.name
+}
+
+// Short returns the shorthand name of the option.
+func (o customOpt) Short() string {
+	return o.short
+}
+
+// Help returns the help doc of the option.
+func (o customOpt) Help() string {
+	return o.help
+}
+
+// Default returns the default value of the option.
+func (o customOpt) Default() interface{} {
+	return o._default
+}
+
+// Zero returns the zero value of the custom type.
+func (o customOpt) Zero() interface{} {
+	return reflect.Zero(o._type).Interface()
+}
+
+// Parse parses the value of the option to the custom type.
+func (o customOpt) Parse(data interface{}) (interface{}, error) {
+	if v := reflect.ValueOf(data); v.IsValid() && v.Type() == o._type {
+		return data, nil
+	}
+
+	text, err := ToString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.New(o._type)
+	if err = v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// SetValidators resets the validator chain.
+func (o customOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = vs
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o customOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = append(append([]Validator(nil), o.validators...), vs...)
+	return o
+}
+
+// GetValidators returns the validator chain.
+func (o customOpt) GetValidators() []Validator {
+	return o.validators
+}
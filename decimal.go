@@ -0,0 +1,184 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is the fixed-point decimal number, such as the price or the rate,
+// which is represented exactly as value * 10^(-scale) without the rounding
+// errors of float64.
+//
+// The zero value is 0.
+type Decimal struct {
+	value *big.Int
+	scale int
+}
+
+// NewDecimal returns a new Decimal as value * 10^(-scale),
+// such as NewDecimal(12345, 2) for 123.45.
+func NewDecimal(value int64, scale int) Decimal {
+	if scale < 0 {
+		return Decimal{value: new(big.Int).Mul(big.NewInt(value), pow10(-scale))}
+	}
+	return Decimal{value: big.NewInt(value), scale: scale}
+}
+
+// ParseDecimal parses the decimal number, such as "123.45", "-0.5" or "1.2e3".
+func ParseDecimal(s string) (Decimal, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+
+	var exp int
+	if index := strings.IndexAny(s, "eE"); index > -1 {
+		e, err := strconv.Atoi(s[index+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal '%s'", orig)
+		}
+		s, exp = s[:index], e
+	}
+
+	var scale int
+	if index := strings.IndexByte(s, '.'); index > -1 {
+		scale = len(s) - index - 1
+		s = s[:index] + s[index+1:]
+	}
+
+	if s == "" || s == "+" || s == "-" || strings.IndexAny(s[1:], "+-") > -1 {
+		return Decimal{}, fmt.Errorf("invalid decimal '%s'", orig)
+	}
+
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal '%s'", orig)
+	}
+
+	if scale -= exp; scale < 0 {
+		value.Mul(value, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{value: value, scale: scale}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) unscaled() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// Scale returns the number of the digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Rat returns the decimal as the rational number.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.unscaled(), pow10(d.scale))
+}
+
+// Float64 returns the nearest float64 value of the decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares d and other, and returns -1 if d < other, 0 if d == other,
+// or +1 if d > other.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Rat().Cmp(other.Rat())
+}
+
+// String returns the decimal string, such as "123.45".
+func (d Decimal) String() string {
+	value := d.unscaled()
+	s := new(big.Int).Abs(value).String()
+	if d.scale > 0 {
+		if len(s) <= d.scale {
+			s = strings.Repeat("0", d.scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+	}
+
+	if value.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalText implements the interface encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the interface encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(data []byte) (err error) {
+	*d, err = ParseDecimal(string(data))
+	return
+}
+
+// ToDecimal does the best to convert a certain value to Decimal.
+//
+// If the value is string, it is parsed by ParseDecimal. The float value
+// is converted by its shortest decimal representation.
+func ToDecimal(_v interface{}) (Decimal, error) {
+	switch v := _v.(type) {
+	case Decimal:
+		return v, nil
+	case string:
+		return ParseDecimal(v)
+	case []byte:
+		return ParseDecimal(string(v))
+	case float32:
+		return ParseDecimal(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	case uint, uint8, uint16, uint32, uint64:
+		u, err := ToUint64(v)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return Decimal{value: new(big.Int).SetUint64(u)}, nil
+	}
+
+	i, err := ToInt64(_v)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return NewDecimal(i, 0), nil
+}
+
+// DecimalOpt return a new Decimal option for the monetary value, such as
+// the price or the rate, which is not parsed through float64.
+//
+// For the string value, it is parsed by ParseDecimal.
+func DecimalOpt(short, name string, _default Decimal, help string) ValidatorChainOpt {
+	return newBaseOpt(short, name, _default, help, decimalType)
+}
+
+// DecimalNum is equal to DecimalOpt("", name, _default, help).
+func DecimalNum(name string, _default Decimal, help string) ValidatorChainOpt {
+	return DecimalOpt("", name, _default, help)
+}
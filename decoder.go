@@ -0,0 +1,349 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Decoder decodes the data of the config source in a certain format,
+// such as INI or property.
+//
+// It returns the mapping from the group name to the options in the group,
+// which is the mapping from the option name to the option value.
+// The name of the default group is "".
+//
+// sep is the group separator, which may be used to split the key into
+// the group name and the option name.
+type Decoder func(data []byte, sep string) (map[string]map[string]string, error)
+
+func addDecodedValue(values map[string]map[string]string, group, name, value string) {
+	opts, ok := values[group]
+	if !ok {
+		opts = make(map[string]string, 8)
+		values[group] = opts
+	}
+	opts[name] = value
+}
+
+// changedValues returns the values that are new or different from the last.
+func changedValues(last, values map[string]map[string]string) map[string]map[string]string {
+	changes := make(map[string]map[string]string, len(values))
+	for group, opts := range values {
+		for name, value := range opts {
+			if v, ok := last[group][name]; ok && v == value {
+				continue
+			}
+			addDecodedValue(changes, group, name, value)
+		}
+	}
+	return changes
+}
+
+// DecodeIni is a Decoder to decode the data of the INI format.
+//
+// It supports the line comments starting with "#", "//" or ";". The key and
+// the value is separated by an equal sign, that's =. The key must be in one of
+// _, -, number and letter.
+//
+// If the value ends with "\", it will continue the next line. The lines will
+// be joined by "\n" together. For the long multi-line value, such as the PEM
+// block, use the triple-quoted or heredoc block, the whitespaces of which are
+// kept, see DecodeProperty.
+//
+// Notice: the options that have not been assigned to a certain group will be
+// divided into the default group.
+func DecodeIni(data []byte, sep string) (map[string]map[string]string, error) {
+	gname := ""
+	values := make(map[string]map[string]string, 8)
+	lines := strings.Split(string(data), "\n")
+	for index, maxIndex := 0, len(lines); index < maxIndex; {
+		line := strings.TrimSpace(lines[index])
+		index++
+
+		// Ignore the empty line.
+		if len(line) == 0 {
+			continue
+		}
+
+		// Ignore the line comments starting with "#", ";" or "//".
+		if (line[0] == '#') || (line[0] == ';') ||
+			(len(line) > 1 && line[0] == '/' && line[1] == '/') {
+			continue
+		}
+
+		// Start a new group
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			gname = strings.TrimSpace(line[1 : len(line)-1])
+			if gname == "" {
+				return nil, fmt.Errorf("the group is empty")
+			}
+			continue
+		}
+
+		n := strings.Index(line, "=")
+		if n == -1 {
+			return nil, fmt.Errorf("the %dth line misses the separator '='", index)
+		}
+
+		key := strings.TrimSpace(line[0:n])
+		for _, r := range key {
+			if r != '_' && r != '-' && !unicode.IsNumber(r) && !unicode.IsLetter(r) {
+				return nil, fmt.Errorf("invalid identifier key '%s'", key)
+			}
+		}
+		value := strings.TrimSpace(line[n+1:])
+
+		// The multi-line block or the continuation line
+		if v, next, ok, err := decodeMultiLineValue(lines, index, value); err != nil {
+			return nil, fmt.Errorf("the %dth line: %s", index, err)
+		} else if ok {
+			value, index = v, next
+		} else if value != "" && value[len(value)-1] == '\\' {
+			vs := []string{strings.TrimSpace(strings.TrimRight(value, "\\"))}
+			for index < maxIndex {
+				value = strings.TrimSpace(lines[index])
+				vs = append(vs, strings.TrimSpace(strings.TrimRight(value, "\\")))
+				index++
+				if value == "" || value[len(value)-1] != '\\' {
+					break
+				}
+			}
+			value = strings.TrimSpace(strings.Join(vs, "\n"))
+		}
+
+		addDecodedValue(values, gname, key, value)
+	}
+
+	return values, nil
+}
+
+// DecodeProperty is a Decoder to decode the data of the property format.
+//
+// It supports the line comments starting with "#", "//" or ";". The key and
+// the value is separated by an equal sign, that's =. The key is split into
+// the group name and the option name by the group separator, such as
+// "group1.group2.opt".
+//
+// If the value ends with "\", it will continue the next line.
+//
+// The long multi-line value, such as the PEM block or the SQL snippet,
+// may be in the triple-quoted block, which is kept as it is:
+//
+//    sql = """
+//    SELECT *
+//      FROM users
+//    """
+//
+// Or the heredoc block, which ends with the line of the identifier.
+// For "<<-", the common leading whitespaces of the lines are removed:
+//
+//    cert = <<-EOF
+//        -----BEGIN CERTIFICATE-----
+//        ...
+//        -----END CERTIFICATE-----
+//        EOF
+func DecodeProperty(data []byte, sep string) (map[string]map[string]string, error) {
+	values := make(map[string]map[string]string, 8)
+	err := parseProperties(string(data), "=", func(line int, key, value string) error {
+		group, name := "", key
+		if index := strings.LastIndex(key, sep); index > -1 {
+			group, name = key[:index], key[index+len(sep):]
+		}
+		addDecodedValue(values, group, name, value)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseProperties parses the data of the property format, and calls the function
+// f with the line number, the key and the value for each property.
+//
+// It supports the line comments starting with "#", "//" or ";". The key and
+// the value is separated by sep. If the value ends with "\", it will continue
+// the next line. The value may be the multi-line block, see DecodeProperty.
+func parseProperties(data, sep string, f func(line int, key, value string) error) error {
+	lines := strings.Split(data, "\n")
+	for index, maxIndex := 0, len(lines); index < maxIndex; {
+		line := strings.TrimSpace(lines[index])
+		index++
+
+		// Ignore the empty line.
+		if len(line) == 0 {
+			continue
+		}
+
+		// Ignore the line comments starting with "#", ";" or "//".
+		if (line[0] == '#') || (line[0] == ';') ||
+			(len(line) > 1 && line[0] == '/' && line[1] == '/') {
+			continue
+		}
+
+		ss := strings.SplitN(line, sep, 2)
+		if len(ss) != 2 {
+			return fmt.Errorf("the %dth line misses the separator '%s'", index, sep)
+		}
+
+		lineno := index
+		key := strings.TrimSpace(ss[0])
+		value := strings.TrimSpace(ss[1])
+		if v, next, ok, err := decodeMultiLineValue(lines, index, value); err != nil {
+			return fmt.Errorf("the %dth line: %s", index, err)
+		} else if ok {
+			value, index = v, next
+		} else if value != "" {
+			for index < maxIndex && value[len(value)-1] == '\\' {
+				value = strings.TrimRight(value, "\\") + strings.TrimSpace(lines[index])
+				index++
+			}
+		}
+
+		if err := f(lineno, key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeMultiLineValue decodes the multi-line value starting with value,
+// which is the triple-quoted or heredoc block, from the line lines[index].
+//
+// It returns the value and the index of the line after the block. If value
+// does not start the block, ok is false.
+func decodeMultiLineValue(lines []string, index int, value string) (v string,
+	next int, ok bool, err error) {
+	// The triple-quoted block
+	for _, quote := range []string{`"""`, "'''"} {
+		if !strings.HasPrefix(value, quote) {
+			continue
+		}
+
+		first := value[len(quote):]
+		if n := strings.Index(first, quote); n > -1 {
+			if strings.TrimSpace(first[n+len(quote):]) != "" {
+				return "", index, false, fmt.Errorf("unexpected characters after %s", quote)
+			}
+			return first[:n], index, true, nil
+		}
+
+		var vs []string
+		if first != "" {
+			vs = append(vs, first)
+		}
+		for ; index < len(lines); index++ {
+			line := strings.TrimRight(lines[index], "\r")
+			if n := strings.Index(line, quote); n > -1 {
+				if strings.TrimSpace(line[n+len(quote):]) != "" {
+					return "", index, false, fmt.Errorf("unexpected characters after %s", quote)
+				}
+				if last := line[:n]; strings.TrimSpace(last) != "" {
+					vs = append(vs, last)
+				}
+				return strings.Join(vs, "\n"), index + 1, true, nil
+			}
+			vs = append(vs, line)
+		}
+		return "", index, false, fmt.Errorf("the block is not terminated by %s", quote)
+	}
+
+	// The heredoc block
+	if !strings.HasPrefix(value, "<<") {
+		return "", index, false, nil
+	}
+	id, dedent := value[2:], false
+	if strings.HasPrefix(id, "-") {
+		id, dedent = id[1:], true
+	}
+	if id == "" {
+		return "", index, false, nil
+	}
+	for _, r := range id {
+		if r != '_' && !unicode.IsNumber(r) && !unicode.IsLetter(r) {
+			return "", index, false, nil
+		}
+	}
+
+	var vs []string
+	for ; index < len(lines); index++ {
+		line := strings.TrimRight(lines[index], "\r")
+		if strings.TrimSpace(line) == id {
+			if dedent {
+				dedentLines(vs)
+			}
+			return strings.Join(vs, "\n"), index + 1, true, nil
+		}
+		vs = append(vs, line)
+	}
+	return "", index, false, fmt.Errorf("the heredoc is not terminated by %s", id)
+}
+
+// dedentLines removes the common leading whitespaces of the non-blank lines.
+func dedentLines(lines []string) {
+	prefix, first := "", true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+
+		n := 0
+		for n < len(prefix) && n < len(indent) && prefix[n] == indent[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			lines[i] = line[len(prefix):]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t") // The blank line
+		}
+	}
+}
+
+// encodeMultiLineValue returns the triple-quoted block of the value if it
+// contains the newline, which can be decoded by DecodeIni and DecodeProperty.
+func encodeMultiLineValue(value string) string {
+	if !strings.Contains(value, "\n") {
+		return value
+	}
+
+	for _, quote := range []string{`"""`, "'''"} {
+		if !strings.Contains(value, quote) {
+			return quote + "\n" + value + "\n" + quote
+		}
+	}
+
+	id := "EOF"
+	for strings.Contains(value, id) {
+		id += "_"
+	}
+	return "<<" + id + "\n" + value + "\n" + id
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestDecodeMultiLineValue(t *testing.T) {
+	data := `[group]
+sql = """
+SELECT *
+  FROM users
+"""
+cert = <<-EOF
+    -----BEGIN CERTIFICATE-----
+      MIIB
+    -----END CERTIFICATE-----
+    EOF
+name = '''one line'''
+`
+
+	values, err := DecodeIni([]byte(data), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := map[string]string{
+		"sql":  "SELECT *\n  FROM users",
+		"cert": "-----BEGIN CERTIFICATE-----\n  MIIB\n-----END CERTIFICATE-----",
+		"name": "one line",
+	}
+	for name, expect := range expects {
+		if v := values["group"][name]; v != expect {
+			t.Errorf("%s: expect '%s', but got '%s'", name, expect, v)
+		}
+	}
+
+	values, err = DecodeProperty([]byte("group.sql = "+encodeMultiLineValue(expects["sql"])), ".")
+	if err != nil {
+		t.Error(err)
+	} else if v := values["group"]["sql"]; v != expects["sql"] {
+		t.Errorf("expect '%s', but got '%s'", expects["sql"], v)
+	}
+
+	if _, err = DecodeIni([]byte("sql = \"\"\"\nSELECT *"), "."); err == nil {
+		t.Error("expect an error for the unterminated block")
+	}
+}
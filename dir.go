@@ -0,0 +1,271 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// kubeDataDir is the symlink that Kubernetes swaps atomically when updating
+// the projected ConfigMap or Secret volume.
+const kubeDataDir = "..data"
+
+type dirParser struct {
+	name     string
+	dir      string
+	prio     int
+	interval time.Duration
+
+	// The mapping from the file name to the option key.
+	files map[string]string
+
+	// If true, ignore the directory which does not exist.
+	optional bool
+}
+
+// NewDirParser returns a new parser which treats a directory as the config
+// source, each file of which is an option, such as the volume of a mounted
+// Kubernetes ConfigMap or Secret.
+//
+// The file name is the option name with its group, such as "port" for the
+// default group or "db.mysql.conn" for the group "db.mysql", and the content
+// of the file is the option value. The hidden files starting with "." and
+// the sub-directories are ignored.
+//
+// If interval is greater than 0, it will check whether the directory has been
+// changed every interval, and re-read it if changed, then update the option
+// values by SetOptValue. For Kubernetes, the change is detected by the swap
+// of the symlink "..data"; or, by the modification time of the files.
+//
+// Notice: the files that have not been registered as the options are ignored.
+func NewDirParser(priority int, dir string, interval time.Duration) Parser {
+	return dirParser{name: "dir", dir: dir, prio: priority, interval: interval}
+}
+
+// DefaultDockerSecretsDir is the default directory of the Docker secrets.
+const DefaultDockerSecretsDir = "/run/secrets"
+
+// NewDockerSecretsParser returns a new parser to read the options from
+// the Docker secrets, which are the files in the directory dir.
+// If dir is empty, it is DefaultDockerSecretsDir, and it's not an error
+// that the directory does not exist.
+//
+// By default, the file name is the option name with its group, which is
+// the same as NewDirParser. But files can override the file name of the option,
+// the key of which is the option name with its group, such as "db.password",
+// and the value is the file name, such as "mysql_root_password".
+//
+// Notice: the files that have not been registered as the options are ignored.
+func NewDockerSecretsParser(priority int, dir string, files map[string]string) Parser {
+	var optional bool
+	if dir == "" {
+		dir = DefaultDockerSecretsDir
+		optional = true
+	}
+
+	names := make(map[string]string, len(files))
+	for key, name := range files {
+		names[name] = key
+	}
+	return dirParser{name: "docker-secrets", dir: dir, prio: priority,
+		files: names, optional: optional}
+}
+
+// NewSystemdCredentialsParser returns a new parser to read the options from
+// the systemd credentials, such as LoadCredential= and SetCredential=, which
+// are the files in the directory of the environment variable
+// $CREDENTIALS_DIRECTORY. If the environment variable is not set, the parser
+// does nothing.
+//
+// files is the same as NewDockerSecretsParser, which maps the option name with
+// its group to the credential name, such as "db.password" to "mysql-password".
+//
+// Notice: the credentials that have not been registered as the options
+// are ignored.
+func NewSystemdCredentialsParser(priority int, files map[string]string) Parser {
+	names := make(map[string]string, len(files))
+	for key, name := range files {
+		names[name] = key
+	}
+	return dirParser{name: "systemd-credentials", dir: os.Getenv("CREDENTIALS_DIRECTORY"),
+		prio: priority, files: names, optional: true}
+}
+
+func (p dirParser) Name() string {
+	return p.name
+}
+
+func (p dirParser) Priority() int {
+	return p.prio
+}
+
+func (p dirParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p dirParser) Post(c *Config) error {
+	return nil
+}
+
+func (p dirParser) Parse(c *Config) error {
+	if p.dir == "" && p.optional {
+		c.Printf("[%s] No the directory", p.Name())
+		return nil
+	}
+
+	w := &dirWatcher{dirParser: p, conf: c, values: make(map[string]string)}
+	version, err := w.version()
+	if err != nil {
+		if p.optional && os.IsNotExist(err) {
+			c.Printf("[%s] Ignore the nonexistent directory '%s'", p.Name(), p.dir)
+			return nil
+		}
+		return err
+	}
+	if err = w.load(); err != nil {
+		return err
+	}
+
+	if p.interval > 0 {
+		c.goWatch(func(ctx context.Context) { w.watch(ctx, version) })
+	}
+	return nil
+}
+
+type dirWatcher struct {
+	dirParser
+
+	conf   *Config
+	values map[string]string
+}
+
+// readFiles returns the mapping from the file name to the file content.
+func (w *dirWatcher) readFiles() (map[string]string, error) {
+	infos, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(infos))
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		// The file of Kubernetes is the symlink to the file in "..data".
+		path := filepath.Join(w.dir, name)
+		if info, err = os.Stat(path); err != nil {
+			return nil, err
+		} else if !info.Mode().IsRegular() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = strings.TrimSpace(string(data))
+	}
+	return files, nil
+}
+
+func (w *dirWatcher) load() error {
+	files, err := w.readFiles()
+	if err != nil {
+		return err
+	}
+
+	for name, value := range files {
+		if last, ok := w.values[name]; ok && last == value {
+			continue
+		}
+
+		key := name
+		if k, ok := w.files[name]; ok {
+			key = k
+		}
+
+		w.conf.Printf("[%s] Parsing file '%s'", w.Name(), name)
+		group, opt := w.conf.splitOptKey(key)
+		if err = w.conf.setOptValueIfExist(w.prio, group, opt, value); err != nil {
+			return err
+		}
+		w.values[name] = value
+	}
+	return nil
+}
+
+// version returns the version of the directory, which will be changed
+// when the directory is updated.
+func (w *dirWatcher) version() (string, error) {
+	if target, err := os.Readlink(filepath.Join(w.dir, kubeDataDir)); err == nil {
+		return target, nil
+	}
+
+	infos, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return "", err
+	}
+
+	versions := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if name := info.Name(); !strings.HasPrefix(name, ".") {
+			if info, err = os.Stat(filepath.Join(w.dir, name)); err == nil {
+				versions = append(versions, fmt.Sprintf("%s:%d:%d", name,
+					info.Size(), info.ModTime().UnixNano()))
+			}
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ","), nil
+}
+
+func (w *dirWatcher) watch(ctx context.Context, last string) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		version, err := w.version()
+		if err != nil {
+			w.conf.Printf("[%s] Failed to check the directory '%s': %s", w.Name(), w.dir, err)
+			continue
+		} else if version == last {
+			continue
+		}
+
+		w.conf.Printf("[%s] The directory '%s' changed", w.Name(), w.dir)
+		if err = w.load(); err != nil {
+			w.conf.Printf("[%s] Failed to reload the directory '%s': %s", w.Name(), w.dir, err)
+			continue
+		}
+		last = version
+	}
+}
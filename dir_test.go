@@ -0,0 +1,132 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirParser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"port":          "80\n",
+		"db.mysql.conn": "root@tcp",
+		"unused":        "xxx",
+		".port":         "90",
+	})
+	if err = os.Mkdir(filepath.Join(dir, "db"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig().AddParser(NewDirParser(50, dir, time.Millisecond*10))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	writeTestFiles(t, dir, map[string]string{"port": "8080"})
+	for i := 0; i < 100 && conf.Int("port") != 8080; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
+
+func TestDockerSecretsParser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{"mysql_root_password": "pass"})
+	conf := NewConfig().AddParser(NewDockerSecretsParser(50, dir,
+		map[string]string{"db.password": "mysql_root_password"}))
+	conf.RegisterOpt("db", Str("password", "", ""))
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if v := conf.Group("db").String("password"); v != "pass" {
+		t.Errorf("password: expected 'pass', got '%s'", v)
+	}
+
+	// The nonexistent directory is an error unless it's the default one.
+	conf = NewConfig().AddParser(NewDockerSecretsParser(50, filepath.Join(dir, "none"), nil))
+	if err = conf.Parse(); err == nil {
+		t.Error("expect an error for the nonexistent directory")
+	}
+}
+
+func TestSystemdCredentialsParser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{"mysql-password": "pass", "port": "80"})
+	defer os.Setenv("CREDENTIALS_DIRECTORY", os.Getenv("CREDENTIALS_DIRECTORY"))
+	os.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	conf := NewConfig().AddParser(NewSystemdCredentialsParser(50,
+		map[string]string{"db.password": "mysql-password"}))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db", Str("password", "", ""))
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db").String("password"); v != "pass" {
+		t.Errorf("password: expected 'pass', got '%s'", v)
+	}
+
+	// The parser does nothing without the credentials directory.
+	os.Unsetenv("CREDENTIALS_DIRECTORY")
+	conf = NewConfig().AddParser(NewSystemdCredentialsParser(50, nil))
+	if err = conf.Parse(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type dnsParser struct {
+	prio     int
+	zone     string
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+// NewDNSParser returns a new parser to read the options from the DNS TXT
+// records under the zone, such as "config.example.com".
+//
+// The domain name of the option is the option name, the group name and
+// the zone joined by the dot, such as "conn.db.mysql.config.example.com"
+// for the option "conn" in the group "db.mysql", and "opt.config.example.com"
+// for the option "opt" in the default group. If an option has more than one
+// TXT record, the records will be joined by the comma.
+//
+// If resolver is nil, it is net.DefaultResolver.
+//
+// Notice: the options that have no TXT records are ignored. And the names
+// of the options and groups should be the valid DNS labels.
+func NewDNSParser(priority int, zone string, resolver *net.Resolver) Parser {
+	zone = strings.Trim(zone, ".")
+	if zone == "" {
+		panic(fmt.Errorf("the DNS zone must not be empty"))
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return dnsParser{prio: priority, zone: zone, resolver: resolver, timeout: 10 * time.Second}
+}
+
+func (p dnsParser) Name() string {
+	return "dns"
+}
+
+func (p dnsParser) Priority() int {
+	return p.prio
+}
+
+func (p dnsParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p dnsParser) Post(c *Config) error {
+	return nil
+}
+
+func (p dnsParser) Parse(c *Config) error {
+	defaultGroup := c.GetDefaultGroupName()
+	parsed := make(map[string]bool, 8)
+	for _, group := range c.Groups() {
+		gname := group.FullName()
+		if parsed[gname] {
+			continue
+		}
+		parsed[gname] = true
+
+		suffix := p.zone
+		if gname != defaultGroup {
+			suffix = strings.Replace(gname, c.GetGroupSeparator(), ".", -1) + "." + suffix
+		}
+
+		for _, opt := range group.AllOpts() {
+			domain := opt.Name() + "." + suffix
+			records, err := p.lookupTXT(domain)
+			if err != nil {
+				return fmt.Errorf("failed to lookup the TXT records of '%s': %s", domain, err)
+			} else if len(records) == 0 {
+				continue
+			}
+
+			c.Printf("[%s] Parsing the TXT records of '%s'", p.Name(), domain)
+			value := strings.Join(records, ",")
+			if err = c.SetOptValue(p.prio, gname, opt.Name(), value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p dnsParser) lookupTXT(domain string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	records, err := p.resolver.LookupTXT(ctx, domain)
+	if e, ok := err.(*net.DNSError); ok && e.IsNotFound {
+		// The domain or its TXT records do not exist.
+		return nil, nil
+	}
+	return records, err
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+// testDNSConn returns the connection to the fake DNS server over TCP,
+// which answers all the queries with rcode.
+func testDNSConn(rcode byte) net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		for {
+			var size uint16
+			if err := binary.Read(server, binary.BigEndian, &size); err != nil {
+				return
+			}
+
+			query := make([]byte, size)
+			if _, err := io.ReadFull(server, query); err != nil {
+				return
+			}
+
+			// Reply the header and the question of the query without answers.
+			resp := append([]byte{}, query...)
+			resp[2], resp[3] = 0x81, 0x80|rcode
+			binary.Write(server, binary.BigEndian, uint16(len(resp)))
+			server.Write(resp)
+		}
+	}()
+	return client
+}
+
+func TestDNSParserLookupError(t *testing.T) {
+	for _, c := range []struct {
+		rcode byte
+		fail  bool
+	}{
+		{3, false}, // NXDOMAIN
+		{2, true},  // SERVFAIL
+		{5, true},  // REFUSED
+	} {
+		resolver := &net.Resolver{PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return testDNSConn(c.rcode), nil
+			}}
+
+		conf := NewConfig().AddParser(NewDNSParser(50, "config.example.com", resolver))
+		conf.RegisterOpt("", Str("opt", "", ""))
+		if err := conf.Parse(); c.fail && err == nil {
+			t.Errorf("rcode %d: expect an error", c.rcode)
+		} else if !c.fail && err != nil {
+			t.Errorf("rcode %d: unexpected error: %s", c.rcode, err)
+		}
+	}
+
+	resolver := &net.Resolver{PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, fmt.Errorf("unreachable")
+		}}
+	conf := NewConfig().AddParser(NewDNSParser(50, "config.example.com", resolver))
+	conf.RegisterOpt("", Str("opt", "", ""))
+	if err := conf.Parse(); err == nil {
+		t.Error("expect an error for the unreachable DNS server")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type enumOpt struct {
+	baseOpt
+	choices []string
+}
+
+// EnumOpt returns a new enum option, the value of which is a string
+// and must be one of choices.
+//
+// The value is matched case-insensitively and normalized to the choice,
+// so "Debug" is parsed as "debug" for the choices ["debug", "info"].
+// The choices are listed in the help output and completed by the shell.
+func EnumOpt(short, name string, choices []string, _default string, help string) ValidatorChainOpt {
+	if len(choices) == 0 {
+		panic(fmt.Errorf("the enum option '%s' has no choices", name))
+	}
+
+	o := enumOpt{
+		baseOpt: newBaseOpt(short, name, nil, help, stringType),
+		choices: append([]string(nil), choices...),
+	}
+
+	if _default != "" {
+		v, err := o.Parse(_default)
+		if err != nil {
+			panic(fmt.Errorf("the default of the enum option '%s' is invalid: %s", name, err))
+		}
+		o._default = v
+	}
+	return o
+}
+
+// Enum is equal to EnumOpt("", name, choices, _default, help).
+func Enum(name string, choices []string, _default string, help string) ValidatorChainOpt {
+	return EnumOpt("", name, choices, _default, help)
+}
+
+// Choices returns the valid values of the option.
+func (o enumOpt) Choices() []string {
+	return o.choices
+}
+
+// SetValidators resets the validator chain.
+func (o enumOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o enumOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
+
+// Parse parses the value of the option to one of the choices.
+func (o enumOpt) Parse(data interface{}) (interface{}, error) {
+	s, err := ToString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s = strings.TrimSpace(s)
+	for _, choice := range o.choices {
+		if strings.EqualFold(s, choice) {
+			return choice, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid value '%s', which must be one of '%s'",
+		s, strings.Join(o.choices, "', '"))
+}
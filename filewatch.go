@@ -0,0 +1,182 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// FileWatchInterval is the interval to poll the config file if the change
+// notification of the file system, such as inotify, is not supported.
+var FileWatchInterval = time.Second
+
+// fileWatchDelay is the delay to reload the config file after it changes,
+// which coalesces the burst of the file system events of one update.
+const fileWatchDelay = time.Millisecond * 100
+
+// fileParser is the parser based on the config file, such as the ini parser.
+type fileParser interface {
+	Parser
+
+	// fileName returns the path of the config file, or "" if no file.
+	fileName(*Config) string
+}
+
+// WatchFiles enables or disables to watch the config files of the parsers,
+// such as the ini and property parsers, after parsing.
+//
+// If enabled, when the config file changes, the parser will be re-run
+// into the staging values, which are applied all or nothing by the transaction
+// only if all of them are valid, see Begin, so the observers registered
+// by Observe are notified. The change is detected by inotify on Linux.
+// On the other platforms, such as macOS, FreeBSD and Windows, kqueue and
+// the likes are not used, and the file is polled every FileWatchInterval,
+// which is one second by default, instead.
+// The error to reload the file is only logged by Printf.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) WatchFiles(enable bool) *Config {
+	c.panicIsParsed(true)
+	c.watchFiles = enable
+	return c
+}
+
+// watchFileParsers starts to watch the config files of all the file parsers.
+func (c *Config) watchFileParsers() {
+	for _, parser := range c.parsers {
+		if p, ok := parser.(fileParser); ok {
+			if filename := p.fileName(c); filename != "" {
+				c.watchFile(p, filename)
+			}
+		}
+	}
+}
+
+func (c *Config) watchFile(p fileParser, filename string) {
+	last, err := ioutil.ReadFile(filename)
+	if err != nil {
+		c.Printf("[%s] Failed to watch the file '%s': %s", p.Name(), filename, err)
+		return
+	}
+
+	// The values parsed from the file at present, which the changes of
+	// the file are compared to.
+	values, err := c.stageParser(p)
+	if err != nil {
+		c.Printf("[%s] Failed to stage the file '%s': %s", p.Name(), filename, err)
+	}
+
+	events := make(chan struct{}, 1)
+	notify, err := notifyFile(filename, events)
+	if err != nil {
+		c.Printf("[%s] Poll the file '%s' instead of notification: %s", p.Name(), filename, err)
+		notify = func(ctx context.Context) { pollFile(ctx, filename, FileWatchInterval, events) }
+	}
+
+	c.goWatch(notify)
+	c.goWatch(func(ctx context.Context) { c.reloadFile(ctx, p, filename, last, values, events) })
+}
+
+// reloadFile re-runs the parser when the content of the config file changes,
+// and only applies the option values changed compared to the last values.
+func (c *Config) reloadFile(ctx context.Context, p fileParser, filename string,
+	last []byte, values map[string]map[string]interface{}, events <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-events:
+		}
+
+		if !sleepContext(ctx, fileWatchDelay) {
+			return
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			c.Printf("[%s] Failed to read the file '%s': %s", p.Name(), filename, err)
+			continue
+		} else if bytes.Equal(data, last) {
+			continue
+		}
+
+		c.Printf("[%s] The file '%s' changed", p.Name(), filename)
+		staged, err := c.reloadParser(p, values)
+		if err != nil {
+			c.Printf("[%s] Failed to reload the file '%s': %s", p.Name(), filename, err)
+			continue
+		}
+		last, values = data, staged
+	}
+}
+
+// pollFile sends the event to events when the size or the modification time
+// of the file changes until ctx is done.
+func pollFile(ctx context.Context, filename string, interval time.Duration,
+	events chan<- struct{}) {
+	var size int64
+	var mtime time.Time
+	if info, err := os.Stat(filename); err == nil {
+		size, mtime = info.Size(), info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(filename)
+		if err != nil || (info.Size() == size && info.ModTime().Equal(mtime)) {
+			continue
+		}
+
+		size, mtime = info.Size(), info.ModTime()
+		sendFileEvent(events)
+	}
+}
+
+// sendFileEvent sends the event without blocking, which is coalesced
+// with the pending one.
+func sendFileEvent(events chan<- struct{}) {
+	select {
+	case events <- struct{}{}:
+	default:
+	}
+}
+
+// reloadParser re-runs the parser into the staging values, and only if all
+// of them are valid, applies those changed compared to last to the
+// configuration at once, so the bad config file does not leave
+// the configuration in a mixed state. It returns the staging values.
+func (c *Config) reloadParser(p Parser, last map[string]map[string]interface{}) (
+	map[string]map[string]interface{}, error) {
+	values, err := c.stageParser(p)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.applyStaged(p.Priority(), values, last); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
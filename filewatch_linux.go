@@ -0,0 +1,102 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO |
+	syscall.IN_CREATE | syscall.IN_DELETE
+
+// notifyFile watches the file by inotify, and returns the function to send
+// the event to events when the file changes until ctx is done.
+//
+// It watches the directory of the file instead of the file itself, because
+// the file may be replaced by renaming, such as the editors or the symlink
+// swapped by Kubernetes.
+func notifyFile(filename string, events chan<- struct{}) (func(context.Context), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, base := filepath.Split(filepath.Clean(filename))
+	if dir == "" {
+		dir = "."
+	}
+
+	wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return func(ctx context.Context) {
+		// Removing the watch generates the event IN_IGNORED,
+		// which wakes up the blocking read when ctx is done.
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				syscall.InotifyRmWatch(fd, uint32(wd))
+			case <-done:
+			}
+		}()
+
+		readInotify(ctx, fd, base, events)
+		close(done)
+		<-stopped
+		syscall.Close(fd)
+	}, nil
+}
+
+func readInotify(ctx context.Context, fd int, base string, events chan<- struct{}) {
+	buf := make([]byte, (syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)*16)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if ctx.Err() != nil {
+			return
+		} else if err == syscall.EINTR {
+			continue
+		} else if err != nil || n <= 0 {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			offset = start + int(event.Len)
+
+			name := buf[start:offset]
+			if index := bytes.IndexByte(name, 0); index > -1 {
+				name = name[:index]
+			}
+
+			// The Kubernetes volume swaps the symlink "..data" atomically.
+			if string(name) == base || string(name) == kubeDataDir {
+				sendFileEvent(events)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const firebaseRemoteConfigURL = "https://firebaseremoteconfig.googleapis.com/v1/projects/%s/remoteConfig"
+
+type firebaseParameter struct {
+	DefaultValue *struct {
+		Value string `json:"value"`
+	} `json:"defaultValue"`
+}
+
+type firebaseRemoteConfig struct {
+	Parameters      map[string]firebaseParameter `json:"parameters"`
+	ParameterGroups map[string]struct {
+		Parameters map[string]firebaseParameter `json:"parameters"`
+	} `json:"parameterGroups"`
+	Version struct {
+		VersionNumber string `json:"versionNumber"`
+	} `json:"version"`
+}
+
+type firebaseParser struct {
+	prio     int
+	url      string
+	token    func() (string, error)
+	interval time.Duration
+	client   *http.Client
+}
+
+// NewFirebaseRemoteConfigParser returns a new parser to read the options
+// from the template of Firebase Remote Config by its REST API.
+//
+// token returns the OAuth2 access token with the scope
+// "https://www.googleapis.com/auth/firebase.remoteconfig", which may be
+// adapted from golang.org/x/oauth2/google, such as
+//
+//    ts, _ := google.DefaultTokenSource(ctx, scope)
+//    token := func() (string, error) {
+//        t, err := ts.Token()
+//        if err != nil {
+//            return "", err
+//        }
+//        return t.AccessToken, nil
+//    }
+//
+// The parameter key, including the key in the parameter groups, is split into
+// the group and the option by the group separator, such as "db.mysql.conn"
+// for the option "conn" in the group "db.mysql". Only the default value of
+// the parameter is used, and the conditional values are ignored.
+//
+// If interval is greater than 0, it will re-fetch the template periodically,
+// and update the option values by SetOptValue when the template is published
+// with a new version.
+//
+// Notice: the parameters that have not been registered as the options
+// are ignored.
+func NewFirebaseRemoteConfigParser(priority int, project string,
+	token func() (string, error), interval time.Duration) Parser {
+	if project == "" || token == nil {
+		panic(fmt.Errorf("the project and the token must not be empty"))
+	}
+
+	return firebaseParser{
+		prio:     priority,
+		url:      fmt.Sprintf(firebaseRemoteConfigURL, url.PathEscape(project)),
+		token:    token,
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p firebaseParser) Name() string {
+	return "firebase-remote-config"
+}
+
+func (p firebaseParser) Priority() int {
+	return p.prio
+}
+
+func (p firebaseParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p firebaseParser) Post(c *Config) error {
+	return nil
+}
+
+func (p firebaseParser) Parse(c *Config) error {
+	r := &firebaseRefresher{
+		firebaseParser: p,
+		conf:           c,
+		values:         make(map[string]map[string]string, 8),
+	}
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	if p.interval > 0 {
+		c.goWatch(r.run)
+	}
+	return nil
+}
+
+type firebaseRefresher struct {
+	firebaseParser
+
+	conf    *Config
+	version string
+	values  map[string]map[string]string
+}
+
+func (r *firebaseRefresher) load() error {
+	token, err := r.token()
+	if err != nil {
+		return fmt.Errorf("failed to get the access token: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var template firebaseRemoteConfig
+	if err = doHTTPJSON(r.client, req, &template); err != nil {
+		return err
+	}
+
+	version := template.Version.VersionNumber
+	if version != "" && version == r.version {
+		return nil
+	}
+	r.conf.Printf("[%s] Loading the template, version=%s", r.Name(), version)
+
+	values := make(map[string]map[string]string, 8)
+	addParameters := func(params map[string]firebaseParameter) {
+		for key, param := range params {
+			if param.DefaultValue != nil {
+				group, name := r.conf.splitOptKey(key)
+				addDecodedValue(values, group, name, param.DefaultValue.Value)
+			}
+		}
+	}
+
+	addParameters(template.Parameters)
+	for _, group := range template.ParameterGroups {
+		addParameters(group.Parameters)
+	}
+
+	changes := changedValues(r.values, values)
+	if err = r.conf.setOptValues(r.prio, changes, true); err != nil {
+		return err
+	}
+
+	r.version = version
+	r.values = values
+	return nil
+}
+
+func (r *firebaseRefresher) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.load(); err != nil {
+			r.conf.Printf("[%s] Failed to reload: %s", r.Name(), err)
+		}
+	}
+}
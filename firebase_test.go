@@ -0,0 +1,97 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testFirebaseServer struct {
+	lock    sync.Mutex
+	version int
+	port    string
+}
+
+func (s *testFirebaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fmt.Fprintf(w, `{
+		"parameters": {
+			"port": {"defaultValue": {"value": "%s"}},
+			"unused": {"defaultValue": {"value": "xxx"}},
+			"debug": {"conditionalValues": {"beta": {"value": "true"}}}
+		},
+		"parameterGroups": {
+			"database": {"parameters": {"db.mysql.conn": {"defaultValue": {"value": "root@tcp"}}}}
+		},
+		"version": {"versionNumber": "%d"}
+	}`, s.port, s.version)
+}
+
+func (s *testFirebaseServer) publish(port string) {
+	s.lock.Lock()
+	s.port = port
+	s.version++
+	s.lock.Unlock()
+}
+
+func TestFirebaseRemoteConfigParser(t *testing.T) {
+	firebase := &testFirebaseServer{version: 1, port: "80"}
+	server := httptest.NewServer(firebase)
+	defer server.Close()
+
+	token := func() (string, error) { return "token", nil }
+	p := NewFirebaseRemoteConfigParser(50, "myproject", token, time.Millisecond*10).(firebaseParser)
+	p.url, p.client = server.URL, server.Client()
+
+	conf := NewConfig().AddParser(p)
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("", Bool("debug", false, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Bool("debug"); v {
+		t.Error("debug: expected false, got true")
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	firebase.publish("8080")
+	for i := 0; i < 100 && conf.Int("port") != 8080; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
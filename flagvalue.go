@@ -0,0 +1,135 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type flagValueOpt struct {
+	name     string
+	short    string
+	help     string
+	value    flag.Value
+	lock     *sync.Mutex
+	_default interface{}
+
+	validators []Validator
+}
+
+// FlagValueOpt returns a new option wrapping the existing flag.Value,
+// so the third-party flag types, such as the log level of glog, can be used
+// as an option directly.
+//
+// When parsing the value, the string is set into value by its Set method,
+// so value is updated like flag.Var. If value implements flag.Getter,
+// the option value is the result of its Get method, or value itself.
+// The default is the current value of value.
+func FlagValueOpt(short, name string, value flag.Value, help string) ValidatorChainOpt {
+	if value == nil {
+		panic(fmt.Errorf("the flag value of the option '%s' must not be nil", name))
+	}
+
+	o := flagValueOpt{
+		name:  name,
+		short: short,
+		help:  help,
+		value: value,
+		lock:  new(sync.Mutex),
+	}
+	o._default = o.get()
+	return o
+}
+
+// FlagValue is equal to FlagValueOpt("", name, value, help).
+func FlagValue(name string, value flag.Value, help string) ValidatorChainOpt {
+	return FlagValueOpt("", name, value, help)
+}
+
+func (o flagValueOpt) get() interface{} {
+	if g, ok := o.value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return o.value
+}
+
+// Name returns the name of the option.
+func (o flagValueOpt) Name() string {
+	return o.name
+}
+
+// Short returns the shorthand name of the option.
+func (o flagValueOpt) Short() string {
+	return o.short
+}
+
+// Help returns the help doc of the option.
+func (o flagValueOpt) Help() string {
+	return o.help
+}
+
+// Default returns the default value of the option.
+func (o flagValueOpt) Default() interface{} {
+	return o._default
+}
+
+// Zero returns the zero value of the type of the option value.
+func (o flagValueOpt) Zero() interface{} {
+	if o._default == nil {
+		return nil
+	}
+	return reflect.Zero(reflect.TypeOf(o._default)).Interface()
+}
+
+// Parse sets the value into the flag.Value and returns its value.
+func (o flagValueOpt) Parse(data interface{}) (interface{}, error) {
+	if data != nil && o._default != nil && reflect.TypeOf(data) == reflect.TypeOf(o._default) {
+		return data, nil
+	}
+
+	s, err := ToString(data)
+	if err != nil {
+		s = fmt.Sprintf("%v", data)
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if err = o.value.Set(s); err != nil {
+		return nil, err
+	}
+	return o.get(), nil
+}
+
+// SetValidators resets the validator chain.
+func (o flagValueOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = vs
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o flagValueOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.validators = append(append([]Validator(nil), o.validators...), vs...)
+	return o
+}
+
+// GetValidators returns the validator chain.
+func (o flagValueOpt) GetValidators() []Validator {
+	return o.validators
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GCPSecretsClient is the client interface of Google Secret Manager
+// used by the GCP Secret Manager parser.
+//
+// It can be adapted from the AccessSecretVersion API of the Google Cloud
+// client library easily.
+type GCPSecretsClient interface {
+	// AccessSecretVersion returns the payload of the secret version
+	// by its resource name, such as "projects/p/secrets/s/versions/latest",
+	// and the resource name of the actual version, such as
+	// "projects/p/secrets/s/versions/3".
+	AccessSecretVersion(name string) (payload []byte, version string, err error)
+}
+
+// NewGCPSecretsParser returns a new parser to resolve the options from
+// the secrets of Google Secret Manager.
+//
+// secrets is the mapping from the option to the secret. The key is the option
+// name with its group, such as "db.mysql.password", and the value is the secret
+// name with the optional version and JSON key, the format of which is
+// "SECRET[@VERSION][#KEY]", such as "mysql", "mysql@3" or "mysql@latest#password".
+// The version is "latest" by default, or pinned to the explicit version.
+// The secret may also be the full resource name, such as
+// "projects/p/secrets/mysql/versions/3#password", and project is ignored.
+// If the JSON key is missing, the option name is used as the key if the secret
+// is a JSON object, or the whole secret is the option value.
+//
+// If refresh is greater than 0, the secrets will be checked every refresh
+// interval. When the "latest" version has been changed, the new values will
+// be updated by SetOptValue. The secrets pinned to the explicit version are
+// never changed.
+func NewGCPSecretsParser(priority int, client GCPSecretsClient, project string,
+	secrets map[string]string, refresh time.Duration) Parser {
+	if client == nil {
+		panic(fmt.Errorf("the GCP Secret Manager client must not be nil"))
+	}
+
+	return newSecretParser("gcp-secrets", priority, secrets, refresh,
+		func(ref string) ([]byte, string, error) {
+			name, err := gcpSecretVersionName(project, ref)
+			if err != nil {
+				return nil, "", err
+			}
+			return client.AccessSecretVersion(name)
+		})
+}
+
+func gcpSecretVersionName(project, ref string) (string, error) {
+	if strings.HasPrefix(ref, "projects/") {
+		if !strings.Contains(ref, "/versions/") {
+			ref += "/versions/latest"
+		}
+		return ref, nil
+	}
+
+	if project == "" {
+		return "", fmt.Errorf("the GCP project is empty")
+	}
+
+	version := "latest"
+	if index := strings.IndexByte(ref, '@'); index > -1 {
+		ref, version = ref[:index], ref[index+1:]
+		if version == "" {
+			return "", fmt.Errorf("the version of the secret '%s' is empty", ref)
+		}
+	}
+
+	return fmt.Sprintf("projects/%s/secrets/%s/versions/%s", project, ref, version), nil
+}
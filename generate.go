@@ -0,0 +1,139 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GenerateConfig writes the template of the config file into w, which
+// contains all the registered options with their defaults and the help
+// information as the comments, to bootstrap the new deployment.
+//
+// format is one of "ini" and "property", which can be parsed by the INI
+// and the property parser. The option having no default is commented out.
+func (c *Config) GenerateConfig(w io.Writer, format string) error {
+	if format != "ini" && format != "property" {
+		return fmt.Errorf("unsupported config format '%s'", format)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, group := range c.sortedGroups() {
+		gname := group.FullName()
+		opts := group.AllOpts()
+		sort.Slice(opts, func(i, j int) bool { return opts[i].Name() < opts[j].Name() })
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		if format == "ini" {
+			fmt.Fprintf(buf, "[%s]\n", gname)
+		}
+
+		for i, opt := range opts {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+
+			if help := c.optHelp(gname, opt); help != "" {
+				for _, line := range strings.Split(help, "\n") {
+					fmt.Fprintf(buf, "# %s\n", line)
+				}
+			}
+
+			if choices := optChoices(opt); len(choices) > 0 {
+				fmt.Fprintf(buf, "# Choices: %s\n", strings.Join(choices, ", "))
+			}
+			if d, ok := c.getOptRedirect(gname, opt.Name()); ok && !d.alias {
+				fmt.Fprintf(buf, "# DEPRECATED: %s\n", d.warning(c))
+			}
+
+			key := opt.Name()
+			if format == "property" {
+				key = c.optKey(gname, key)
+			}
+
+			if v := opt.Default(); v != nil {
+				fmt.Fprintf(buf, "%s = %s\n", key, encodeMultiLineValue(formatOptValue(v)))
+			} else {
+				fmt.Fprintf(buf, "# %s =\n", key)
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// generateConfigFile writes the template of the config file into the file,
+// the format of which is "property" if its extension is ".properties",
+// or "ini".
+func (c *Config) generateConfigFile(path string) error {
+	format := "ini"
+	if filepath.Ext(path) == ".properties" {
+		format = "property"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := c.GenerateConfig(buf, format); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// SetGenerateConfig sets the information of the CLI option to write the
+// template of the config file, see GenerateConfig, into the given path and
+// exit, such as "--generate-config myapp.ini". The format is "property" if
+// the extension of the path is ".properties", or "ini".
+//
+// It supports:
+//     SetGenerateConfig()           // SetGenerateConfig("generate-config")
+//     SetGenerateConfig(name)       // SetGenerateConfig("generate-config")
+//     SetGenerateConfig(name, help) // SetGenerateConfig("generate-config", "Generate the config")
+//
+// Notice: it is for the CLI parser.
+func (c *Config) SetGenerateConfig(args ...string) *Config {
+	name := "generate-config"
+	help := "Write the template of the config file into the path and exit."
+	if len(args) == 1 {
+		name = args[0]
+	} else if len(args) > 1 {
+		name = args[0]
+		help = args[1]
+	}
+
+	if name == "" || help == "" {
+		panic(fmt.Errorf("The arguments about generate config must not be empty"))
+	}
+
+	c.gName = name
+	c.gHelp = help
+	return c
+}
+
+// GetGenerateConfig returns the information about the generate config option.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) GetGenerateConfig() (name, help string) {
+	return c.gName, c.gHelp
+}
@@ -0,0 +1,421 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The argument specifiers of getopt.
+const (
+	getoptNoArg = iota
+	getoptRequiredArg
+	getoptOptionalArg
+)
+
+// The kinds of the getopt options.
+const (
+	getoptOption = iota
+	getoptVersion
+	getoptCompletion
+	getoptPrintConfig
+	getoptGenerateConfig
+	getoptOverride
+	getoptHelp
+)
+
+type getoptOpt struct {
+	kind   int
+	hasArg int
+	group  string
+	name   string
+	opt    Opt
+}
+
+type getoptValue struct {
+	opt   *getoptOpt
+	value string
+}
+
+type getoptParser struct {
+	optstring string
+	utoh      bool
+}
+
+// NewGetoptCliParser returns a new CLI parser implementing the semantics of
+// getopt_long of GNU C library, which is used to port the C daemons.
+//
+// optstring is the same as that of getopt, such as "vc:l::", which declares
+// the short options and their argument specifiers: the character followed by
+// nothing takes no argument, by a colon takes a required argument, and by two
+// colons takes an optional argument. The character is the short name of the
+// registered CLI option. If optstring starts with "+", it stops parsing at the
+// first non-option argument like POSIXLY_CORRECT, or the non-option arguments
+// are permuted to the end, which are the rest arguments.
+//
+// All the registered CLI options are the long options, the names of which are
+// the option names with their groups, such as "--db.mysql.conn". The long
+// option of the bool or count option takes no argument, and the others take
+// a required argument, except that the short option is declared by two colons.
+// The long option may be abbreviated to its unique prefix.
+//
+// So it supports:
+//
+//    -a -b -c, -abc, -c value, -cvalue, -l, -lvalue,
+//    --name, --name value, --name=value, --na=value, --
+//
+// If underlineToHyphen is true, it will convert the underline to the hyphen.
+func NewGetoptCliParser(optstring string, underlineToHyphen bool) Parser {
+	return getoptParser{optstring: optstring, utoh: underlineToHyphen}
+}
+
+func (p getoptParser) Name() string {
+	return "getopt"
+}
+
+func (p getoptParser) isCliParser() {}
+
+func (p getoptParser) Priority() int {
+	return 0
+}
+
+func (p getoptParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p getoptParser) Post(c *Config) error {
+	return nil
+}
+
+func (p getoptParser) options(c *Config) (longs map[string]*getoptOpt,
+	shorts map[byte]*getoptOpt, posix bool, err error) {
+	optstring := p.optstring
+	if strings.HasPrefix(optstring, "+") {
+		posix = true
+		optstring = optstring[1:]
+	}
+	optstring = strings.TrimLeft(optstring, "-:")
+
+	// Parse the argument specifiers of the short options.
+	specs := make(map[string]int, len(optstring))
+	for i := 0; i < len(optstring); i++ {
+		spec := getoptNoArg
+		if strings.HasPrefix(optstring[i+1:], "::") {
+			spec = getoptOptionalArg
+		} else if strings.HasPrefix(optstring[i+1:], ":") {
+			spec = getoptRequiredArg
+		}
+		specs[optstring[i:i+1]] = spec
+		i += spec
+	}
+
+	longs = make(map[string]*getoptOpt, 16)
+	shorts = make(map[byte]*getoptOpt, len(specs))
+	for _, group := range c.Groups() {
+		gname := group.FullName()
+		for _, opt := range group.CliOpts() {
+			name := c.cliOptName(gname, opt.Name(), p.utoh)
+			if _, ok := longs[name]; ok {
+				continue
+			}
+
+			o := &getoptOpt{group: gname, name: opt.Name(), opt: opt, hasArg: getoptRequiredArg}
+			if _, ok := opt.Zero().(bool); ok || isCountOpt(opt) {
+				o.hasArg = getoptNoArg
+			}
+
+			if spec, ok := specs[opt.Short()]; ok {
+				if spec == getoptOptionalArg {
+					o.hasArg = getoptOptionalArg
+				}
+
+				so := *o
+				so.hasArg = spec
+				shorts[opt.Short()[0]] = &so
+			}
+
+			longs[name] = o
+		}
+	}
+
+	for short := range specs {
+		if _, ok := shorts[short[0]]; !ok {
+			return nil, nil, false, fmt.Errorf("the short option '%s' in optstring is not registered", short)
+		}
+	}
+
+	// The aliases and the old names of the deprecated options.
+	for _, d := range c.optRedirects() {
+		if d.newName == "" {
+			continue
+		}
+
+		old := c.cliOptName(d.group, d.name, p.utoh)
+		if target := longs[c.cliOptName(d.newGroup, d.newName, p.utoh)]; target != nil && longs[old] == nil {
+			o := *target
+			o.group, o.name = d.group, d.name
+			longs[old] = &o
+		}
+	}
+
+	// The builtin options.
+	if name, _, _ := c.GetVersion(); name != "" && longs[name] == nil {
+		longs[name] = &getoptOpt{kind: getoptVersion}
+	}
+	if name := c.GetCompletion(); name != "" && longs[name] == nil {
+		longs[name] = &getoptOpt{kind: getoptCompletion, hasArg: getoptRequiredArg}
+	}
+	if name, _ := c.GetPrintConfig(); name != "" && longs[name] == nil {
+		longs[name] = &getoptOpt{kind: getoptPrintConfig}
+	}
+	if name, _ := c.GetGenerateConfig(); name != "" && longs[name] == nil {
+		longs[name] = &getoptOpt{kind: getoptGenerateConfig, hasArg: getoptRequiredArg}
+	}
+	if name, _ := c.GetOverride(); name != "" && longs[name] == nil {
+		longs[name] = &getoptOpt{kind: getoptOverride, hasArg: getoptRequiredArg}
+	}
+	if longs["help"] == nil {
+		longs["help"] = &getoptOpt{kind: getoptHelp}
+	}
+
+	return
+}
+
+// lookupLong returns the long option by the name, which may be abbreviated.
+func (p getoptParser) lookupLong(c *Config, longs map[string]*getoptOpt, name string) (*getoptOpt, error) {
+	if o, ok := longs[name]; ok {
+		return o, nil
+	}
+
+	var candidates []string
+	for long := range longs {
+		if strings.HasPrefix(long, name) {
+			candidates = append(candidates, long)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		names := make([]string, 0, len(longs))
+		for long := range longs {
+			names = append(names, long)
+		}
+		return nil, c.didYouMean(fmt.Errorf("unrecognized option '--%s'", name), name, "--", names)
+	case 1:
+		return longs[candidates[0]], nil
+	default:
+		sort.Strings(candidates)
+		return nil, fmt.Errorf("option '--%s' is ambiguous; possibilities: '--%s'",
+			name, strings.Join(candidates, "' '--"))
+	}
+}
+
+func (p getoptParser) parseArgs(c *Config, args []string, longs map[string]*getoptOpt,
+	shorts map[byte]*getoptOpt, posix bool) (values []getoptValue, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		} else if len(arg) < 2 || arg[0] != '-' {
+			if posix {
+				rest = append(rest, args[i:]...)
+				break
+			}
+			rest = append(rest, arg)
+			continue
+		}
+
+		// The long option
+		if strings.HasPrefix(arg, "--") {
+			name, value := arg[2:], ""
+			index := strings.IndexByte(name, '=')
+			if index > -1 {
+				name, value = name[:index], name[index+1:]
+			}
+
+			var o *getoptOpt
+			if o, err = p.lookupLong(c, longs, name); err != nil {
+				return
+			}
+
+			switch o.hasArg {
+			case getoptNoArg:
+				if index > -1 {
+					return nil, nil, fmt.Errorf("option '--%s' doesn't allow an argument", name)
+				}
+			case getoptRequiredArg:
+				if index < 0 {
+					if i+1 >= len(args) {
+						return nil, nil, fmt.Errorf("option '--%s' requires an argument", name)
+					}
+					i++
+					value = args[i]
+				}
+			}
+
+			values = append(values, getoptValue{opt: o, value: value})
+			continue
+		}
+
+		// The short options
+		for j := 1; j < len(arg); j++ {
+			o := shorts[arg[j]]
+			if o == nil {
+				return nil, nil, fmt.Errorf("invalid option -- '%c'", arg[j])
+			}
+
+			var value string
+			if o.hasArg == getoptNoArg {
+				values = append(values, getoptValue{opt: o})
+				continue
+			} else if j+1 < len(arg) {
+				value = arg[j+1:]
+			} else if o.hasArg == getoptRequiredArg {
+				if i+1 >= len(args) {
+					return nil, nil, fmt.Errorf("option requires an argument -- '%c'", arg[j])
+				}
+				i++
+				value = args[i]
+			}
+
+			values = append(values, getoptValue{opt: o, value: value})
+			break
+		}
+	}
+
+	return
+}
+
+func (p getoptParser) Parse(c *Config) (err error) {
+	longs, shorts, posix, err := p.options(c)
+	if err != nil {
+		return
+	}
+
+	values, rest, err := p.parseArgs(c, c.CliArgs(), longs, shorts, posix)
+	if err != nil {
+		return
+	}
+
+	// Merge the values of the options in order.
+	type optValue struct {
+		group string
+		name  string
+		value string
+	}
+
+	var overrides []string
+	var printConfig bool
+	results := make([]optValue, 0, len(values))
+	indexes := make(map[string]int, len(values))
+	prog := filepath.Base(os.Args[0])
+	for _, v := range values {
+		switch v.opt.kind {
+		case getoptVersion:
+			if !c.IsDryRun() {
+				c.PrintVersion(os.Stdout)
+				os.Exit(0)
+			}
+			continue
+		case getoptCompletion:
+			if !c.IsDryRun() {
+				if err = c.GenerateCompletion(os.Stdout, v.value, prog, p.utoh); err != nil {
+					return
+				}
+				os.Exit(0)
+			}
+			continue
+		case getoptPrintConfig:
+			printConfig = true
+			continue
+		case getoptGenerateConfig:
+			if !c.IsDryRun() {
+				if err = c.generateConfigFile(v.value); err != nil {
+					return
+				}
+				os.Exit(0)
+			}
+			continue
+		case getoptOverride:
+			overrides = append(overrides, v.value)
+			continue
+		case getoptHelp:
+			c.PrintHelp(os.Stderr, prog, p.utoh)
+			return flag.ErrHelp
+		}
+
+		value := v.value
+		if v.opt.hasArg != getoptRequiredArg && value == "" {
+			switch _default := v.opt.opt.Default(); v.opt.opt.Zero().(type) {
+			case bool:
+				value = "true"
+			default:
+				if !isCountOpt(v.opt.opt) {
+					value = formatOptValue(_default)
+				}
+			}
+		}
+
+		key := c.optKey(v.opt.group, v.opt.name)
+		index, ok := indexes[key]
+		if !ok {
+			indexes[key] = len(results)
+			results = append(results, optValue{group: v.opt.group, name: v.opt.name, value: value})
+			if isCountOpt(v.opt.opt) {
+				results[len(results)-1].value = "1"
+			}
+			continue
+		}
+
+		// The repeated option
+		switch v.opt.opt.Zero().(type) {
+		case []string, []int, []int64, []uint, []uint64, []float64,
+			[]time.Duration, []time.Time, []*net.IPNet:
+			results[index].value = fmt.Sprintf("%s,%s", results[index].value, value)
+		default:
+			if isCountOpt(v.opt.opt) {
+				var count int64
+				fmt.Sscan(results[index].value, &count)
+				value = fmt.Sprint(count + 1)
+			}
+			results[index].value = value
+		}
+	}
+
+	c.SetArgs(rest)
+	for _, r := range results {
+		c.Printf("[%s] Parsing option '%s'", p.Name(), c.optKey(r.group, r.name))
+		if err = c.SetOptValue(0, r.group, r.name, r.value); err != nil {
+			return
+		}
+	}
+
+	if printConfig {
+		c.requestPrintConfig()
+	}
+
+	// Override the options at last.
+	return c.applyOverrides(overrides)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetoptCliParser(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		expect string
+		err    string
+	}{
+		{"bundled shorts", []string{"-abc"}, "all=true brief=true color=true", ""},
+		{"separated shorts", []string{"-a", "-c"}, "all=true color=true", ""},
+		{"short with attached value", []string{"-ofile"}, "output=file", ""},
+		{"short with separated value", []string{"-o", "file"}, "output=file", ""},
+		{"bundled shorts with value", []string{"-acofile"}, "all=true color=true output=file", ""},
+		{"optional short value", []string{"-l"}, "level=info", ""},
+		{"optional short attached value", []string{"-ldebug"}, "level=debug", ""},
+		{"long with equal value", []string{"--output=file"}, "output=file", ""},
+		{"long with separated value", []string{"--output", "file"}, "output=file", ""},
+		{"long with empty value", []string{"--output="}, "output=", ""},
+		{"long bool", []string{"--brief"}, "brief=true", ""},
+		{"unambiguous long prefix", []string{"--out=file", "--verbo"}, "output=file verbose=true", ""},
+		{"exact long over prefix", []string{"--all"}, "all=true", ""},
+		{"terminated by --", []string{"-a", "--", "-b", "--output=x"}, "all=true args=-b,--output=x", ""},
+		{"permuted arguments", []string{"x", "-a", "y"}, "all=true args=x,y", ""},
+		{"ambiguous long prefix", []string{"--verb"}, "", "option '--verb' is ambiguous; possibilities: '--verbatim' '--verbose'"},
+		{"unrecognized long", []string{"--nothing"}, "", "unrecognized option '--nothing'"},
+		{"invalid short", []string{"-x"}, "", "invalid option -- 'x'"},
+		{"short missing value", []string{"-o"}, "", "option requires an argument -- 'o'"},
+		{"long missing value", []string{"--output"}, "", "option '--output' requires an argument"},
+		{"long bool with value", []string{"--brief=1"}, "", "option '--brief' doesn't allow an argument"},
+	}
+
+	names := []string{"all", "brief", "color", "output", "level", "verbose", "verbatim"}
+	for _, c := range cases {
+		conf := NewConfig().AddParser(NewGetoptCliParser("abco:l::", true))
+		conf.RegisterCliOpt("", BoolOpt("a", "all", false, ""))
+		conf.RegisterCliOpt("", BoolOpt("b", "brief", false, ""))
+		conf.RegisterCliOpt("", BoolOpt("c", "color", false, ""))
+		conf.RegisterCliOpt("", StrOpt("o", "output", "", ""))
+		conf.RegisterCliOpt("", StrOpt("l", "level", "info", ""))
+		conf.RegisterCliOpt("", Bool("verbose", false, ""))
+		conf.RegisterCliOpt("", Bool("verbatim", false, ""))
+
+		err := conf.Parse(c.args...)
+		if c.err != "" {
+			if err == nil {
+				t.Errorf("%s: expected the error '%s', got nil", c.name, c.err)
+			} else if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected the error '%s', got '%s'", c.name, c.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+
+		var results []string
+		for _, name := range names {
+			if conf.Group("").Priority(name) == 0 {
+				results = append(results, name+"="+formatOptValue(conf.Value(name)))
+			}
+		}
+		if args := conf.Args(); len(args) > 0 {
+			results = append(results, "args="+strings.Join(args, ","))
+		}
+
+		if result := strings.Join(results, " "); result != c.expect {
+			t.Errorf("%s: expected '%s', got '%s'", c.name, c.expect, result)
+		}
+	}
+}
+
+func TestGetoptCliParserPosix(t *testing.T) {
+	conf := NewConfig().AddParser(NewGetoptCliParser("+a", true))
+	conf.RegisterCliOpt("", BoolOpt("a", "all", false, ""))
+	conf.RegisterCliOpt("", Bool("brief", false, ""))
+
+	if err := conf.Parse("-a", "x", "--brief"); err != nil {
+		t.Fatal(err)
+	}
+	if !conf.Bool("all") || conf.Bool("brief") {
+		t.Errorf("expected all=true brief=false, got all=%v brief=%v", conf.Bool("all"), conf.Bool("brief"))
+	}
+	if args := strings.Join(conf.Args(), ","); args != "x,--brief" {
+		t.Errorf("expected the args 'x,--brief', got '%s'", args)
+	}
+}
+
+func TestGetoptCliParserCompletion(t *testing.T) {
+	newConfig := func() *Config {
+		conf := NewConfig().AddParser(NewGetoptCliParser("a", true)).SetCompletion("completion")
+		conf.RegisterCliOpt("", BoolOpt("a", "all", false, ""))
+		return conf
+	}
+
+	if err := newConfig().Validate("--completion", "bash", "-a"); err != nil {
+		t.Errorf("expected no error, got '%s'", err)
+	}
+	if err := newConfig().Validate("--completion"); err == nil {
+		t.Error("expected the error of the missing shell, got nil")
+	} else if !strings.Contains(err.Error(), "option '--completion' requires an argument") {
+		t.Errorf("unexpected error '%s'", err)
+	}
+}
@@ -1,3 +1,6 @@
 module github.com/xgfone/go-config
 
-require github.com/xgfone/go-tools v5.5.2+incompatible
+require (
+	github.com/spf13/pflag v1.0.6
+	github.com/xgfone/go-tools v5.5.2+incompatible
+)
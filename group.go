@@ -17,9 +17,12 @@ limitations under the License.
 package config
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
-	"strings"
 	"sync"
 	"time"
 )
@@ -31,6 +34,30 @@ type option struct {
 	opt   Opt
 	prio  int
 	isCli bool
+
+	timer  *time.Timer // The timer to expire the value, see SetOptTTL.
+	ttlSeq uint64
+
+	counts map[int]int // The counts of the count option by the priorities.
+}
+
+// addCount records the count of the count option set by the source with
+// the priority, which replaces the last one of the same source, and returns
+// the total count across all the sources and the highest priority of them.
+func (o *option) addCount(priority int, count int) (total int, prio int) {
+	if o.counts == nil {
+		o.counts = make(map[int]int, 2)
+	}
+	o.counts[priority] = count
+
+	for _, count := range o.counts {
+		total += count
+	}
+
+	if prio = o.prio; priority < prio {
+		prio = priority
+	}
+	return
 }
 
 // OptGroup is the group of the option.
@@ -97,6 +124,8 @@ func (g *OptGroup) Priority(name string) int {
 
 // AllOpts returns all the registered options, including the CLI options.
 func (g *OptGroup) AllOpts() []Opt {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
 	opts := make([]Opt, 0, len(g.opts))
 	for _, opt := range g.opts {
 		opts = append(opts, opt.opt)
@@ -106,6 +135,8 @@ func (g *OptGroup) AllOpts() []Opt {
 
 // Opts returns all the registered options, except the CLI options.
 func (g *OptGroup) Opts() []Opt {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
 	opts := make([]Opt, 0, len(g.opts))
 	for _, opt := range g.opts {
 		if !opt.isCli {
@@ -117,6 +148,8 @@ func (g *OptGroup) Opts() []Opt {
 
 // CliOpts returns all the registered CLI options, except the non-CLI options.
 func (g *OptGroup) CliOpts() []Opt {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
 	opts := make([]Opt, 0, len(g.opts))
 	for _, opt := range g.opts {
 		if opt.isCli {
@@ -128,10 +161,20 @@ func (g *OptGroup) CliOpts() []Opt {
 
 // HasOpt reports whether the group contains the option named 'name'.
 func (g *OptGroup) HasOpt(name string) bool {
+	g.lock.RLock()
 	_, ok := g.opts[name]
+	g.lock.RUnlock()
 	return ok
 }
 
+// isSet reports whether the option named name has been set by a parser.
+func (g *OptGroup) isSet(name string) (ok bool) {
+	g.lock.RLock()
+	_, ok = g.values[name]
+	g.lock.RUnlock()
+	return
+}
+
 // HasGroup reports whether the group contains the sub-group named 'name'.
 func (g *OptGroup) HasGroup(name string) bool {
 	if name == "" {
@@ -158,7 +201,9 @@ func (g *OptGroup) parseOptValue(name string, value interface{}) (interface{}, e
 		return nil, nil
 	}
 
+	g.lock.RLock()
 	opt, ok := g.opts[name]
+	g.lock.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("no the option '%s' in the group '%s'", name, g.name)
 	}
@@ -187,33 +232,60 @@ func (g *OptGroup) parseOptValue(name string, value interface{}) (interface{}, e
 		}
 	}
 
+	if err = g.checkImmutable(name, value); err != nil {
+		return nil, err
+	}
 	return value, nil
 }
 
+// checkImmutable returns an error if the option marked by MarkImmutable
+// is changed to the value after parsing.
+func (g *OptGroup) checkImmutable(name string, value interface{}) error {
+	if !g.conf.frozen || !g.conf.isOptImmutable(g.name, name) {
+		return nil
+	}
+
+	g.lock.RLock()
+	old, ok := g.values[name]
+	g.lock.RUnlock()
+	if ok && !reflect.DeepEqual(old, value) {
+		return fmt.Errorf("the option '%s' in the group '%s' is immutable after parsing",
+			name, g.name)
+	}
+	return nil
+}
+
 func (g *OptGroup) _setOptValue(priority int, name string, value interface{}) (ok bool) {
+	var old interface{}
+	var secret bool
 	func() {
 		g.lock.Lock()
 		defer g.lock.Unlock()
 
 		opt := g.opts[name]
-		if priority > opt.prio {
+		if isCountOpt(opt.opt) {
+			value, priority = opt.addCount(priority, value.(int))
+		} else if priority > opt.prio {
 			g.conf.debug("Ignore the option [%s]:[%s]: %d > %d", g.name, name, priority, opt.prio)
 			return
 		}
 		opt.prio = priority
+		secret = optIsSecret(opt.opt)
+		g.resetTTL(name, opt)
 		ok = true
 
+		old = g.values[name]
 		g.values[name] = value
 		if field, ok := g.fields[name]; ok {
-			field.Set(reflect.ValueOf(value))
+			g.conf.setStructField(field, value)
 		}
 	}()
 
 	if ok {
+		gen := g.conf.nextGeneration()
 		g.conf.debug("Set [%s]:[%s] to [%v]", g.name, name, value)
-		if g.conf.watch != nil {
-			g.conf.watch(g.name, name, value)
-		}
+		g.conf.optChanged(ChangeEvent{Group: g.name, Name: name, Old: old, New: value,
+			Source: g.conf.sourceName(priority), Generation: gen}, secret)
 	}
 
 	return
@@ -246,7 +318,7 @@ func (g *OptGroup) checkRequiredOption() (err error) {
 				}
 			}
 
-			if g.conf.isRequired {
+			if g.conf.isRequired && !isAnyOpt(opt.opt) {
 				return fmt.Errorf("the option '%s' in the group '%s' has no value",
 					name, g.name)
 			}
@@ -258,134 +330,77 @@ func (g *OptGroup) checkRequiredOption() (err error) {
 //////////////////////////////////////////////////////////////////////////////
 /// Register Options
 
-func (g *OptGroup) registerStruct(s interface{}, cli bool) {
-	sv := reflect.ValueOf(s)
-	if sv.IsNil() || !sv.IsValid() {
-		panic(fmt.Errorf("the struct is invalid or can't be set"))
-	}
-
-	if sv.Kind() == reflect.Ptr {
-		sv = sv.Elem()
-	}
-
-	if sv.Kind() != reflect.Struct {
-		panic(fmt.Errorf("the struct is not a struct"))
-	}
-
-	g.registerStructByValue(g.name, sv, cli)
-}
-
-func (g *OptGroup) registerStructByValue(parent string, sv reflect.Value, cli bool) {
-	if sv.Kind() == reflect.Ptr {
-		sv = sv.Elem()
-	}
-	st := sv.Type()
-
-	// Register the field as the option
-	num := sv.NumField()
-	for i := 0; i < num; i++ {
-		field := st.Field(i)
-		fieldV := sv.Field(i)
-
-		// Check whether the field can be set.
-		if !fieldV.CanSet() {
-			continue
-		}
-
-		name := strings.ToLower(field.Name)
-		tagname := strings.TrimSpace(field.Tag.Get("name"))
-		if tagname == "-" {
-			continue
-		} else if tagname != "" {
-			name = tagname
-		}
-
-		isCli := cli
-		if _cli := strings.TrimSpace(field.Tag.Get("cli")); _cli != "" {
-			switch _cli {
-			case "1", "t", "T", "on", "On", "ON", "true", "True", "TRUE":
-				isCli = true
-			case "0", "f", "F", "off", "Off", "OFF", "false", "False", "FALSE":
-				isCli = false
-			default:
-				panic(fmt.Errorf("no support '%s' for cli", field.Tag.Get("cli")))
-			}
-		}
-
-		gname := g.name
-		taggroup, resetgroup := field.Tag.Lookup("group")
-		if resetgroup {
-			taggroup = strings.TrimSpace(taggroup)
-			gname = taggroup
-		}
-
-		// Check whether the field is the struct.
-		if t := field.Type.Kind(); t == reflect.Struct {
-			if _, ok := fieldV.Interface().(time.Time); !ok {
-				parentGroup := g.conf.mergeGroupName(parent, name)
-				if resetgroup {
-					if strings.Contains(taggroup, g.conf.groupSep) {
-						parentGroup = strings.Trim(taggroup, g.conf.groupSep)
-					} else if taggroup == "" {
-						parentGroup = g.conf.groupName // Default Group
-					} else {
-						parentGroup = g.conf.mergeGroupName(parent, taggroup)
-					}
-				}
-
-				g.conf.getGroupByName(parentGroup, true).registerStructByValue(parentGroup, fieldV, isCli)
-				continue
-			}
-		}
-
-		_type := getOptType(fieldV)
-		if _type == int64Type {
-			if _, ok := fieldV.Interface().(time.Duration); ok {
-				_type = durationType
-			}
-		}
-
-		// Get the short name from the tag "short"
-		short := strings.TrimSpace(field.Tag.Get("short"))
-
-		// Get the help doc from the tag "help"
-		help := strings.TrimSpace(field.Tag.Get("help"))
-
-		// Get the default value from the tag "default"
-		var err error
-		var _default interface{}
-		if v, ok := field.Tag.Lookup("default"); ok {
-			if _default, err = parseOpt(strings.TrimSpace(v), _type); err != nil {
-				panic(fmt.Errorf("can't parse the default in the field %s: %s",
-					field.Name, err))
-			}
-		}
-
-		opt := newBaseOpt(short, name, _default, help, _type)
-		group := g.conf.getGroupByName(gname, true)
-		group.registerOpt(isCli, opt)
-		group.fields[name] = fieldV
-	}
-}
-
 // registerOpt registers the option into the group.
 //
 // The first argument, cli, indicates whether the option is as the CLI option,
 // too.
-func (g *OptGroup) registerOpt(cli bool, opt Opt) {
+//
+// It reports whether the option has been registered, which is false if there
+// is the conflict and the registered one is kept, see SetConflictPolicy.
+func (g *OptGroup) registerOpt(cli bool, opt Opt) bool {
 	if opt == nil {
-		return
+		return false
 	}
 
-	if _, ok := g.opts[opt.Name()]; ok {
-		if g.conf.isPanic {
+	if old, ok := g.opts[opt.Name()]; ok {
+		switch g.conf.conflict {
+		case ConflictIgnore:
+			g.conf.debug("WARNING: Ingore to reregister group=%s, name=%s, cli=%t", g.name, opt.Name(), cli)
+			return false
+		case ConflictError:
+			g.conf.addConflict(fmt.Errorf(
+				"the option '%s' has been registered into the group '%s'", opt.Name(), g.name))
+			return false
+		case ConflictOverride:
+			g.conf.debug("WARNING: Override to reregister group=%s, name=%s, cli=%t", g.name, opt.Name(), cli)
+			delete(g.fields, opt.Name())
+		case ConflictMergeHelp:
+			g.conf.mergeOptHelp(g.fname, old.opt, opt.Help())
+			if cli && !old.isCli {
+				g.checkShort(old.opt)
+			}
+			old.isCli = old.isCli || cli
+			g.conf.debug("Merge the help to reregister group=%s, name=%s, cli=%t", g.name, opt.Name(), cli)
+			return false
+		default:
 			panic(fmt.Errorf("the option '%s' has been registered into the group '%s'", opt.Name(), g.name))
 		}
-		g.conf.debug("WARNING: Ingore to reregister group=%s, name=%s, cli=%t", g.name, opt.Name(), cli)
-		return
 	}
+
+	if cli {
+		g.checkShort(opt)
+	}
+
 	g.opts[opt.Name()] = &option{isCli: cli, opt: opt, prio: 1 << 31}
 	g.conf.debug("Register group=%s, name=%s, cli=%t", g.name, opt.Name(), cli)
+	return true
+}
+
+// checkShort records the conflict if the short name of the CLI option has been
+// used by another CLI option, because the short names are shared by all the groups.
+func (g *OptGroup) checkShort(opt Opt) {
+	if short := opt.Short(); short != "" {
+		key := g.conf.optKey(g.name, opt.Name())
+		if other := g.conf.lookupShort(short, key); other != "" {
+			g.conf.addConflict(fmt.Errorf(
+				"the short name '%s' of the option '%s' has been used by the option '%s'",
+				short, key, other))
+		}
+	}
+}
+
+// unregisterOpt removes the option named name, including its value
+// and the field of the struct bound to it.
+func (g *OptGroup) unregisterOpt(name string) {
+	if opt, ok := g.opts[name]; ok && opt.timer != nil {
+		opt.timer.Stop()
+	}
+
+	delete(g.opts, name)
+	delete(g.values, name)
+	delete(g.fields, name)
+
+	g.conf.purgeOpt(g.name, name)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -437,7 +452,7 @@ func (g *OptGroup) getValue(name string, _type optType) (interface{}, error) {
 		if v, ok := opt.(int32); ok {
 			return v, nil
 		}
-	case int64Type:
+	case int64Type, sizeType:
 		if v, ok := opt.(int64); ok {
 			return v, nil
 		}
@@ -509,6 +524,40 @@ func (g *OptGroup) getValue(name string, _type optType) (interface{}, error) {
 		if v, ok := opt.([]time.Time); ok {
 			return v, nil
 		}
+	case certType:
+		if v, ok := opt.(tls.Certificate); ok {
+			return v, nil
+		}
+	case jsonType:
+		if v, ok := opt.(json.RawMessage); ok {
+			return v, nil
+		}
+	case anyType:
+		return opt, nil
+	case decimalType:
+		if v, ok := opt.(Decimal); ok {
+			return v, nil
+		}
+	case bigIntType:
+		if v, ok := opt.(*big.Int); ok {
+			return v, nil
+		}
+	case secretType:
+		if v, ok := opt.(Secret); ok {
+			return v, nil
+		}
+	case levelType:
+		if v, ok := opt.(Level); ok {
+			return v, nil
+		}
+	case cidrType:
+		if v, ok := opt.(*net.IPNet); ok {
+			return v, nil
+		}
+	case cidrsType:
+		if v, ok := opt.([]*net.IPNet); ok {
+			return v, nil
+		}
 	default:
 		return nil, fmt.Errorf("don't support the type '%s'", _type)
 	}
@@ -1199,3 +1248,273 @@ func (g *OptGroup) Times(name string) []time.Time {
 	}
 	return value
 }
+
+// CIDRE returns the option value, the type of which is *net.IPNet.
+//
+// Return an error if no the option or the type of the option isn't *net.IPNet.
+func (g *OptGroup) CIDRE(name string) (*net.IPNet, error) {
+	v, err := g.getValue(name, cidrType)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*net.IPNet), nil
+}
+
+// CIDRD is the same as CIDRE, but returns the default value if there is
+// an error.
+func (g *OptGroup) CIDRD(name string, _default *net.IPNet) *net.IPNet {
+	if value, err := g.CIDRE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// CIDR is the same as CIDRE, but panic if there is an error.
+func (g *OptGroup) CIDR(name string) *net.IPNet {
+	value, err := g.CIDRE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// CIDRsE returns the option value, the type of which is []*net.IPNet.
+//
+// Return an error if no the option or the type of the option isn't []*net.IPNet.
+func (g *OptGroup) CIDRsE(name string) ([]*net.IPNet, error) {
+	v, err := g.getValue(name, cidrsType)
+	if err != nil {
+		return nil, err
+	}
+	return v.([]*net.IPNet), nil
+}
+
+// CIDRsD is the same as CIDRsE, but returns the default value if there is
+// an error.
+func (g *OptGroup) CIDRsD(name string, _default []*net.IPNet) []*net.IPNet {
+	if value, err := g.CIDRsE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// CIDRs is the same as CIDRsE, but panic if there is an error.
+func (g *OptGroup) CIDRs(name string) []*net.IPNet {
+	value, err := g.CIDRsE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// SizeE returns the option value, the type of which is the int64 byte count.
+//
+// Return an error if no the option or the type of the option isn't int64.
+func (g *OptGroup) SizeE(name string) (int64, error) {
+	v, err := g.getValue(name, sizeType)
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+// SizeD is the same as SizeE, but returns the default value if there is
+// an error.
+func (g *OptGroup) SizeD(name string, _default int64) int64 {
+	if value, err := g.SizeE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// Size is the same as SizeE, but panic if there is an error.
+func (g *OptGroup) Size(name string) int64 {
+	value, err := g.SizeE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// LogLevelE returns the option value, the type of which is Level.
+//
+// Return an error if no the option or the type of the option isn't Level.
+func (g *OptGroup) LogLevelE(name string) (Level, error) {
+	v, err := g.getValue(name, levelType)
+	if err != nil {
+		return 0, err
+	}
+	return v.(Level), nil
+}
+
+// LogLevelD is the same as LogLevelE, but returns the default value if there is
+// an error.
+func (g *OptGroup) LogLevelD(name string, _default Level) Level {
+	if value, err := g.LogLevelE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// LogLevel is the same as LogLevelE, but panic if there is an error.
+func (g *OptGroup) LogLevel(name string) Level {
+	value, err := g.LogLevelE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// SecretE returns the option value, the type of which is Secret.
+//
+// Return an error if no the option or the type of the option isn't Secret.
+func (g *OptGroup) SecretE(name string) (Secret, error) {
+	v, err := g.getValue(name, secretType)
+	if err != nil {
+		return Secret{}, err
+	}
+	return v.(Secret), nil
+}
+
+// SecretD is the same as SecretE, but returns the default value if there is
+// an error.
+func (g *OptGroup) SecretD(name string, _default Secret) Secret {
+	if value, err := g.SecretE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// Secret is the same as SecretE, but panic if there is an error.
+func (g *OptGroup) Secret(name string) Secret {
+	value, err := g.SecretE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// BigIntE returns the option value, the type of which is *big.Int.
+//
+// Return an error if no the option or the type of the option isn't *big.Int.
+func (g *OptGroup) BigIntE(name string) (*big.Int, error) {
+	v, err := g.getValue(name, bigIntType)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*big.Int), nil
+}
+
+// BigIntD is the same as BigIntE, but returns the default value if there is
+// an error.
+func (g *OptGroup) BigIntD(name string, _default *big.Int) *big.Int {
+	if value, err := g.BigIntE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// BigInt is the same as BigIntE, but panic if there is an error.
+func (g *OptGroup) BigInt(name string) *big.Int {
+	value, err := g.BigIntE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// DecimalE returns the option value, the type of which is Decimal.
+//
+// Return an error if no the option or the type of the option isn't Decimal.
+func (g *OptGroup) DecimalE(name string) (Decimal, error) {
+	v, err := g.getValue(name, decimalType)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return v.(Decimal), nil
+}
+
+// DecimalD is the same as DecimalE, but returns the default value if there is
+// an error.
+func (g *OptGroup) DecimalD(name string, _default Decimal) Decimal {
+	if value, err := g.DecimalE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// Decimal is the same as DecimalE, but panic if there is an error.
+func (g *OptGroup) Decimal(name string) Decimal {
+	value, err := g.DecimalE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// JSONE returns the option value, the type of which is json.RawMessage.
+//
+// Return an error if no the option or the type of the option isn't json.RawMessage.
+func (g *OptGroup) JSONE(name string) (json.RawMessage, error) {
+	v, err := g.getValue(name, jsonType)
+	if err != nil {
+		return nil, err
+	}
+	return v.(json.RawMessage), nil
+}
+
+// JSOND is the same as JSONE, but returns the default value if there is
+// an error.
+func (g *OptGroup) JSOND(name string, _default json.RawMessage) json.RawMessage {
+	if value, err := g.JSONE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// JSON is the same as JSONE, but panic if there is an error.
+func (g *OptGroup) JSON(name string) json.RawMessage {
+	value, err := g.JSONE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// DecodeJSON decodes the value of the JSON option into v by json.Unmarshal.
+func (g *OptGroup) DecodeJSON(name string, v interface{}) error {
+	data, err := g.JSONE(name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// CertificateE returns the option value, the type of which is tls.Certificate.
+//
+// Return an error if no the option or the type of the option isn't tls.Certificate.
+func (g *OptGroup) CertificateE(name string) (tls.Certificate, error) {
+	v, err := g.getValue(name, certType)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return v.(tls.Certificate), nil
+}
+
+// CertificateD is the same as CertificateE, but returns the default value if there is
+// an error.
+func (g *OptGroup) CertificateD(name string, _default tls.Certificate) tls.Certificate {
+	if value, err := g.CertificateE(name); err == nil {
+		return value
+	}
+	return _default
+}
+
+// Certificate is the same as CertificateE, but panic if there is an error.
+func (g *OptGroup) Certificate(name string) tls.Certificate {
+	value, err := g.CertificateE(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultHelpTemplate is the default template of the help output,
+// which is executed with HelpData.
+const DefaultHelpTemplate = `{{if .Description}}{{.Description}}
+
+{{end}}Usage: {{.Prog}} [OPTIONS]{{range .Args}} {{.Usage}}{{else}} [ARGS...]{{end}}
+{{if .Args}}
+Arguments:
+{{range .Args}}  {{.Name}} {{.Type}}
+        {{.Help}}{{if .Default}} (default: {{.Default}}){{end}}
+{{end}}{{end}}{{range .Groups}}
+{{if .Name}}Options of the group '{{.Name}}':{{else}}Options:{{end}}
+{{range .Opts}}  {{.Flags}}{{if not .IsBool}} {{.Type}}{{end}}
+        {{.Help}}{{if .Choices}} (choices: {{join .Choices ", "}}){{end}}{{if .Default}} (default: {{.Default}}){{end}}{{if .Env}} [env: {{.Env}}]{{end}}{{if .Required}} [required]{{end}}{{if .Deprecated}} [deprecated]{{end}}
+{{end}}{{end}}{{if .Examples}}
+Examples:
+{{range .Examples}}{{if .Desc}}  # {{.Desc}}
+{{end}}  $ {{.Cmd}}
+
+{{end}}{{end}}`
+
+// HelpOpt is the information of a CLI option used by the help template.
+type HelpOpt struct {
+	Name     string   // The full name of the option, such as "db.mysql.conn".
+	Short    string   // The short name of the option.
+	Flags    string   // The flags of the option, such as "-c, --config-file".
+	Type     string   // The type of the option, such as "int" or "[]string".
+	Help     string   // The help information of the option.
+	Default  string   // The default value, which is empty if having no default.
+	Choices  []string // The valid values of the option, which may be empty.
+	Env      string   // The environment variable name, which may be empty.
+	IsBool   bool     // Whether the option is the bool option without value.
+	Required bool     // Whether the option is required.
+
+	// Deprecated is the deprecated message if the option is deprecated.
+	Deprecated string
+}
+
+// HelpGroup is the information of a group used by the help template.
+type HelpGroup struct {
+	// Name is the full name of the group, which is empty for the default group.
+	Name string
+	Opts []HelpOpt
+}
+
+// HelpArg is the information of a positional argument used by the help template.
+type HelpArg struct {
+	Name     string // The name of the argument.
+	Usage    string // The usage of the argument, such as "<src>" or "[dsts...]".
+	Type     string // The type of the argument, such as "int" or "[]string".
+	Help     string // The help information of the argument.
+	Default  string // The default value, which is empty if having no default.
+	Optional bool   // Whether the argument is optional.
+	Variadic bool   // Whether the argument consumes all the rest arguments.
+}
+
+// HelpExample is a worked example of the program used by the help template.
+type HelpExample struct {
+	Desc string // The description of the example, which may be empty.
+	Cmd  string // The command line of the example, such as "myapp --port 80".
+}
+
+// HelpData is the data to execute the help template.
+type HelpData struct {
+	Prog        string
+	Description string
+	Args        []HelpArg
+	Groups      []HelpGroup
+	Examples    []HelpExample
+}
+
+// SetDescription sets the description of the program, which is printed
+// at the beginning of the help output.
+func (c *Config) SetDescription(desc string) *Config {
+	c.description = strings.TrimSpace(desc)
+	return c
+}
+
+// SetExamples sets the worked examples of the program, which are printed
+// at the end of the help output, such as
+//
+//    SetExamples(
+//        HelpExample{Desc: "Start the server on port 80", Cmd: "myapp --port 80"},
+//        HelpExample{Cmd: "myapp --config-file /etc/myapp.ini"},
+//    )
+func (c *Config) SetExamples(examples ...HelpExample) *Config {
+	c.examples = append([]HelpExample(nil), examples...)
+	return c
+}
+
+// SetHelpTemplate resets the template of the help output, which is executed
+// with HelpData by text/template. The default is DefaultHelpTemplate.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetHelpTemplate(tmpl string) *Config {
+	c.panicIsParsed(true)
+	c.helpTmpl = template.Must(template.New("help").Funcs(helpFuncs).Parse(tmpl))
+	return c
+}
+
+func optTypeName(opt Opt) string {
+	switch o := opt.(type) {
+	case baseOpt:
+		return o._type.String()
+	case levelOpt:
+		return o._type.String()
+	case secretValueOpt:
+		return o._type.String()
+	case certOpt:
+		return "certificate"
+	case localeOpt:
+		return "locale"
+	case percentOpt:
+		return "percent"
+	case textOpt:
+		return "text"
+	case pathOpt:
+		if o.dir {
+			return "dir"
+		}
+		return "file"
+	}
+
+	switch opt.Zero().(type) {
+	case time.Duration:
+		return "duration"
+	case time.Time:
+		return "time"
+	default:
+		return fmt.Sprintf("%T", opt.Zero())
+	}
+}
+
+func optDefaultString(opt Opt) string {
+	v := opt.Default()
+	switch _v := v.(type) {
+	case bool:
+		if !_v {
+			return ""
+		}
+	case time.Time:
+		if _v.IsZero() {
+			return ""
+		}
+	case string:
+		if _, ok := opt.(certOpt); ok && strings.Contains(_v, "-----BEGIN") {
+			return "<inline PEM>" // Don't print the private key.
+		}
+	}
+
+	if isCountOpt(opt) && v == 0 {
+		return ""
+	}
+	return formatOptValue(v)
+}
+
+// HelpData returns the data to render the help output of all the registered
+// CLI options. prog is the name of the program, and underlineToHyphen should
+// be the same as that of the CLI parser.
+func (c *Config) HelpData(prog string, underlineToHyphen bool) HelpData {
+	data := HelpData{Prog: prog, Description: c.description, Examples: c.examples}
+	for _, opt := range c.argOpts {
+		arg := HelpArg{
+			Name:     opt.Name(),
+			Type:     optTypeName(opt),
+			Help:     opt.Help(),
+			Default:  optDefaultString(opt),
+			Optional: !opt.required,
+			Variadic: isVariadicArg(opt),
+		}
+
+		arg.Usage = arg.Name
+		if arg.Variadic {
+			arg.Usage += "..."
+		}
+		if arg.Optional {
+			arg.Usage = "[" + arg.Usage + "]"
+		} else {
+			arg.Usage = "<" + arg.Usage + ">"
+		}
+
+		data.Args = append(data.Args, arg)
+	}
+
+	parsed := make(map[string]bool, 8)
+	for _, group := range c.Groups() {
+		gname := group.FullName()
+		if parsed[gname] {
+			continue
+		}
+		parsed[gname] = true
+
+		hgroup := HelpGroup{}
+		if gname != c.GetDefaultGroupName() {
+			hgroup.Name = gname
+		}
+
+		for _, opt := range group.CliOpts() {
+			name := opt.Name()
+			if hgroup.Name != "" {
+				name = fmt.Sprintf("%s%s%s", gname, c.GetGroupSeparator(), name)
+			}
+
+			if underlineToHyphen {
+				name = strings.Replace(name, "_", "-", -1)
+			}
+
+			flags := "--" + name
+			for _, alias := range c.optAliases(gname, opt.Name()) {
+				flags = fmt.Sprintf("%s, --%s", flags, c.cliOptName(gname, alias, underlineToHyphen))
+			}
+			if short := opt.Short(); short != "" {
+				flags = fmt.Sprintf("-%s, %s", short, flags)
+			}
+
+			var deprecated string
+			if d, ok := c.getOptRedirect(gname, opt.Name()); ok && !d.alias {
+				deprecated = d.warning(c)
+			}
+
+			required := c.isOptRequired(gname, opt.Name()) ||
+				(c.isRequired && !c.isZero && opt.Default() == nil)
+
+			_, isBool := opt.Zero().(bool)
+			hgroup.Opts = append(hgroup.Opts, HelpOpt{
+				Name:     name,
+				Short:    opt.Short(),
+				Flags:    flags,
+				Type:     optTypeName(opt),
+				Help:     c.optHelp(gname, opt),
+				Default:  optDefaultString(opt),
+				Choices:  optChoices(opt),
+				Env:      c.envVarName(gname, opt.Name()),
+				IsBool:   isBool || isCountOpt(opt),
+				Required: required,
+
+				Deprecated: deprecated,
+			})
+		}
+
+		if len(hgroup.Opts) > 0 {
+			data.Groups = append(data.Groups, hgroup)
+		}
+	}
+
+	// The default group is the first.
+	sort.Slice(data.Groups, func(i, j int) bool {
+		return data.Groups[i].Name < data.Groups[j].Name
+	})
+
+	// The builtin options of the CLI parser belong to the default group.
+	var builtins []HelpOpt
+	if c.vName != "" {
+		builtins = append(builtins, HelpOpt{Name: c.vName, Flags: "--" + c.vName,
+			Type: "bool", Help: c.vHelp, IsBool: true})
+	}
+	if c.oName != "" {
+		builtins = append(builtins, HelpOpt{Name: c.oName, Flags: "--" + c.oName,
+			Type: "group.option=value", Help: c.oHelp})
+	}
+	if c.pName != "" {
+		builtins = append(builtins, HelpOpt{Name: c.pName, Flags: "--" + c.pName,
+			Type: "bool", Help: c.pHelp, IsBool: true})
+	}
+	if c.gName != "" {
+		builtins = append(builtins, HelpOpt{Name: c.gName, Flags: "--" + c.gName,
+			Type: "path", Help: c.gHelp})
+	}
+	if len(builtins) > 0 {
+		if len(data.Groups) == 0 || data.Groups[0].Name != "" {
+			data.Groups = append([]HelpGroup{{}}, data.Groups...)
+		}
+		data.Groups[0].Opts = append(data.Groups[0].Opts, builtins...)
+	}
+
+	for _, group := range data.Groups {
+		sort.Slice(group.Opts, func(i, j int) bool {
+			return group.Opts[i].Name < group.Opts[j].Name
+		})
+	}
+
+	return data
+}
+
+// PrintHelp renders the help output of all the registered CLI options
+// by the help template, and writes it into w.
+//
+// prog is the name of the program, and underlineToHyphen should be the same
+// as that of the CLI parser.
+func (c *Config) PrintHelp(w io.Writer, prog string, underlineToHyphen bool) error {
+	tmpl := c.helpTmpl
+	if tmpl == nil {
+		tmpl = defaultHelpTmpl
+	}
+	return tmpl.Execute(w, c.HelpData(prog, underlineToHyphen))
+}
+
+// helpFuncs is the functions used by the help template.
+var helpFuncs = template.FuncMap{"join": strings.Join}
+
+var defaultHelpTmpl = template.Must(template.New("help").Funcs(helpFuncs).Parse(DefaultHelpTemplate))
+
+// cliOptUsage returns the usage of the option registered into the flag set
+// by the CLI parser, which contains the name of the environment variable
+// if the environment variable parser has been added.
+func (c *Config) cliOptUsage(group string, opt Opt) string {
+	usage := c.optHelp(group, opt)
+	if env := c.envVarName(group, opt.Name()); env != "" {
+		usage = strings.TrimSpace(fmt.Sprintf("%s [env: %s]", usage, env))
+	}
+	return usage
+}
+
+// envVarName returns the name of the environment variable of the option
+// if the environment variable parser has been added. Or return "".
+func (c *Config) envVarName(group, opt string) string {
+	for _, parser := range c.parsers {
+		if p, ok := parser.(envVarParser); ok {
+			return p.varName(c, group, opt)
+		}
+	}
+	return ""
+}
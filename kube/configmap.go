@@ -0,0 +1,391 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kube supplies the parser to watch the ConfigMap by the Kubernetes API,
+// so the in-cluster application can be reconfigured without the file mounts.
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	config "github.com/xgfone/go-config"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// Client is a minimal client of the Kubernetes API.
+type Client struct {
+	// Host is the address of the Kubernetes API server,
+	// such as "https://10.0.0.1:443".
+	Host string
+
+	// Token is the bearer token. If TokenFile is not empty, the token
+	// will be read from it for each request, which supports the token
+	// rotation of the service account.
+	Token     string
+	TokenFile string
+
+	HTTPClient *http.Client
+}
+
+// NewInClusterClient returns a new Client by the service account
+// which Kubernetes mounts into the pod.
+func NewInClusterClient() (*Client, error) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not in the Kubernetes cluster")
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid CA certificate of the service account")
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     &tls.Config{RootCAs: pool},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	return &Client{
+		Host:       "https://" + net.JoinHostPort(host, port),
+		TokenFile:  serviceAccountDir + "token",
+		HTTPClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := strings.TrimRight(c.Host, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := c.Token
+	if c.TokenFile != "" {
+		data, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req = req.WithContext(ctx)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, statusError{code: resp.StatusCode,
+			msg: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
+
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.code, http.StatusText(e.code), e.msg)
+}
+
+type configMap struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type configMapParser struct {
+	prio      int
+	name      string
+	namespace string
+	watch     bool
+	client    *Client
+	watcher   *watcher
+}
+
+// NewConfigMapParser returns a new parser to read the options from the ConfigMap
+// named name in the namespace by the Kubernetes API.
+//
+// The keys of the ConfigMap are the option names with their groups, such as
+// "port" for the default group or "db.mysql.conn" for the group "db.mysql".
+// The keys that have not been registered as the options are ignored.
+//
+// If client is nil, it will use NewInClusterClient() to create one.
+// If namespace is empty, it is the namespace of the service account.
+//
+// If watch is true, it will watch the ConfigMap after parsing, and update
+// the option values when it is modified, see config.WatchParser.
+func NewConfigMapParser(priority int, client *Client, namespace, name string,
+	watch bool) config.Parser {
+	if name == "" {
+		panic(fmt.Errorf("the name of the ConfigMap is empty"))
+	}
+
+	return &configMapParser{
+		prio:      priority,
+		name:      name,
+		namespace: namespace,
+		watch:     watch,
+		client:    client,
+	}
+}
+
+func (p *configMapParser) Name() string {
+	return "kube-configmap"
+}
+
+func (p *configMapParser) Priority() int {
+	return p.prio
+}
+
+func (p *configMapParser) Pre(c *config.Config) (err error) {
+	if p.client == nil {
+		if p.client, err = NewInClusterClient(); err != nil {
+			return
+		}
+	}
+
+	if p.namespace == "" {
+		data, err := ioutil.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return err
+		}
+		p.namespace = strings.TrimSpace(string(data))
+	}
+	return
+}
+
+func (p *configMapParser) Post(c *config.Config) error {
+	return nil
+}
+
+func (p *configMapParser) Parse(c *config.Config) (err error) {
+	w := &watcher{configMapParser: p, conf: c, values: make(map[string]string)}
+	if err = w.load(context.Background(), w.set); err == nil && p.watch {
+		p.watcher = w
+	}
+	return
+}
+
+// Watch implements the interface config.WatchParser.
+func (p *configMapParser) Watch(ctx context.Context,
+	apply func(values map[string]map[string]interface{}) error) error {
+	if p.watcher == nil {
+		return nil
+	}
+	p.watcher.run(ctx, apply)
+	return nil
+}
+
+type watcher struct {
+	*configMapParser
+
+	conf    *config.Config
+	version string
+	values  map[string]string
+}
+
+func (w *watcher) path() string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/configmaps", url.PathEscape(w.namespace))
+}
+
+func (w *watcher) load(ctx context.Context,
+	set func(map[string]map[string]interface{}) error) error {
+	resp, err := w.client.get(ctx, w.path()+"/"+url.PathEscape(w.name), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var cm configMap
+	if err = json.NewDecoder(resp.Body).Decode(&cm); err != nil {
+		return err
+	} else if err = w.update(cm.Data, set); err != nil {
+		return err
+	}
+
+	w.version = cm.Metadata.ResourceVersion
+	return nil
+}
+
+// update sets the changed option values by set.
+func (w *watcher) update(data map[string]string,
+	set func(map[string]map[string]interface{}) error) error {
+	sep := w.conf.GetGroupSeparator()
+	values := make(map[string]map[string]interface{}, 4)
+	for key, value := range data {
+		if last, ok := w.values[key]; ok && last == value {
+			continue
+		}
+
+		group, name := "", key
+		if index := strings.LastIndex(key, sep); index > -1 {
+			group, name = key[:index], key[index+len(sep):]
+		}
+
+		if !w.conf.HasGroup(group) || !w.conf.Group(group).HasOpt(name) {
+			w.conf.Printf("[%s] Ignore the unregistered key '%s'", w.Name(), key)
+			continue
+		}
+
+		w.conf.Printf("[%s] Parsing key '%s'", w.Name(), key)
+		if _, ok := values[group]; !ok {
+			values[group] = make(map[string]interface{}, 4)
+		}
+		values[group][name] = value
+	}
+
+	if err := set(values); err != nil {
+		return err
+	}
+
+	for key, value := range data {
+		w.values[key] = value
+	}
+	return nil
+}
+
+// set sets the option values by SetOptValue.
+func (w *watcher) set(values map[string]map[string]interface{}) error {
+	for group, opts := range values {
+		for name, value := range opts {
+			if err := w.conf.SetOptValue(w.prio, group, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// run watches the ConfigMap until ctx is done.
+func (w *watcher) run(ctx context.Context, apply func(map[string]map[string]interface{}) error) {
+	for {
+		err := w.watchOnce(ctx, apply)
+		if ctx.Err() != nil {
+			return
+		} else if err == nil {
+			continue
+		}
+
+		w.conf.Printf("[%s] Failed to watch the ConfigMap '%s/%s': %s",
+			w.Name(), w.namespace, w.name, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+
+		// The resource version is too old, so re-read the ConfigMap.
+		if e, ok := err.(statusError); ok && e.code == http.StatusGone || w.version == "" {
+			if err = w.load(ctx, apply); err != nil {
+				w.conf.Printf("[%s] Failed to read the ConfigMap '%s/%s': %s",
+					w.Name(), w.namespace, w.name, err)
+				w.version = ""
+			}
+		}
+	}
+}
+
+// watchOnce watches the ConfigMap until the connection is closed,
+// and updates the last resource version.
+func (w *watcher) watchOnce(ctx context.Context,
+	apply func(map[string]map[string]interface{}) error) error {
+	query := url.Values{
+		"watch":           []string{"true"},
+		"fieldSelector":   []string{"metadata.name=" + w.name},
+		"resourceVersion": []string{w.version},
+	}
+
+	resp, err := w.client.get(ctx, w.path(), query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err = dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			var cm configMap
+			if err = json.Unmarshal(event.Object, &cm); err != nil {
+				return err
+			}
+
+			w.conf.Printf("[%s] The ConfigMap '%s/%s' changed", w.Name(), w.namespace, w.name)
+			if err = w.update(cm.Data, apply); err != nil {
+				w.conf.Printf("[%s] Failed to update the options: %s", w.Name(), err)
+			}
+			w.version = cm.Metadata.ResourceVersion
+		case "ERROR":
+			var status struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			json.Unmarshal(event.Object, &status)
+			return statusError{code: status.Code, msg: status.Message}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	config "github.com/xgfone/go-config"
+)
+
+func TestConfigMapParser(t *testing.T) {
+	const path = "/api/v1/namespaces/ns/configmaps"
+	watched := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == path+"/app":
+			fmt.Fprint(w, `{"metadata": {"resourceVersion": "1"},
+				"data": {"port": "80", "db.mysql.conn": "root@tcp", "unused": "xxx"}}`)
+		case r.URL.Path == path && r.URL.Query().Get("watch") == "true":
+			if v := r.URL.Query().Get("resourceVersion"); v != "1" {
+				t.Errorf("expect the resource version '1', but got '%s'", v)
+			}
+
+			fmt.Fprint(w, `{"type": "MODIFIED", "object": {"metadata": {"resourceVersion": "2"},
+				"data": {"port": "8080", "db.mysql.conn": "root@tcp"}}}`+"\n")
+			w.(http.Flusher).Flush()
+
+			// Hold the watch until the client closes it.
+			<-r.Context().Done()
+			close(watched)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	updated := make(chan interface{}, 1)
+	client := &Client{Host: server.URL, Token: "token", HTTPClient: server.Client()}
+	conf := config.NewConfig().AddParser(NewConfigMapParser(50, client, "ns", "app", true))
+	conf.RegisterOpt("", config.Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", config.Str("conn", "", ""))
+	conf.Observe(func(group, name string, value interface{}) {
+		if name == "port" && value == 8080 {
+			updated <- value
+		}
+	})
+
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	select {
+	case <-updated:
+	case <-time.After(time.Second):
+		t.Fatal("timeout to wait for the update")
+	}
+
+	conf.Close()
+	select {
+	case <-watched:
+	case <-time.After(time.Second):
+		t.Error("expect the watch to be closed")
+	}
+}
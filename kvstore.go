@@ -0,0 +1,155 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KeyValue is the changed key-value pair sent by KeyValueStore.Watch.
+type KeyValue struct {
+	Key     string
+	Value   string
+	Deleted bool
+}
+
+// KeyValueStore is the generic interface of the key-value store, such as
+// etcd, Consul, Redis, or a custom backend, which can be used as the config
+// source by NewKeyValueStoreParser.
+type KeyValueStore interface {
+	// List returns all the keys which have the prefix.
+	List(prefix string) ([]string, error)
+
+	// Get returns the value of the key.
+	//
+	// If the key does not exist, ok should be false, not an error.
+	Get(key string) (value string, ok bool, err error)
+
+	// Watch watches the keys which have the prefix, and returns a channel
+	// to receive the changed key-value pairs.
+	//
+	// If the store does not support the watch, it should return (nil, nil).
+	Watch(prefix string) (<-chan KeyValue, error)
+}
+
+type kvStoreParser struct {
+	name   string
+	prio   int
+	store  KeyValueStore
+	prefix string
+	watch  bool
+}
+
+// NewKeyValueStoreParser returns a new parser named name, which adapts
+// the key-value store to Parser.
+//
+// The key is prefix plus the full name of the option, that's, the group name
+// and the option name joined by the group separator, such as
+// "/myapp/db.mysql.conn" for the option "conn" in the group "db.mysql" if
+// prefix is "/myapp/". The options in the default group may omit the group
+// name, such as "/myapp/opt".
+//
+// If watch is true, it will watch the keys with the prefix, and update
+// the option values by SetOptValue when the keys are changed. The deleted
+// keys are ignored.
+//
+// Notice: the keys that have not been registered as the options are ignored.
+func NewKeyValueStoreParser(name string, priority int, store KeyValueStore,
+	prefix string, watch bool) Parser {
+	if name == "" || store == nil {
+		panic(fmt.Errorf("the name and the store must not be empty"))
+	}
+	return kvStoreParser{name: name, prio: priority, store: store, prefix: prefix, watch: watch}
+}
+
+func (p kvStoreParser) Name() string {
+	return p.name
+}
+
+func (p kvStoreParser) Priority() int {
+	return p.prio
+}
+
+func (p kvStoreParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p kvStoreParser) Post(c *Config) error {
+	return nil
+}
+
+func (p kvStoreParser) Parse(c *Config) error {
+	keys, err := p.store.List(p.prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		value, ok, err := p.store.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get the key '%s': %s", key, err)
+		} else if !ok {
+			continue
+		}
+
+		if err = p.setValue(c, key, value); err != nil {
+			return err
+		}
+	}
+
+	if p.watch && !c.staging {
+		kvs, err := p.store.Watch(p.prefix)
+		if err != nil {
+			return err
+		} else if kvs != nil {
+			c.goWatch(func(ctx context.Context) { p.watchKeys(ctx, c, kvs) })
+		}
+	}
+
+	return nil
+}
+
+func (p kvStoreParser) setValue(c *Config, key, value string) error {
+	c.Printf("[%s] Parsing the key '%s'", p.Name(), key)
+	group, name := c.splitOptKey(strings.TrimPrefix(key, p.prefix))
+	return c.setOptValueIfExist(p.prio, group, name, value)
+}
+
+func (p kvStoreParser) watchKeys(ctx context.Context, c *Config, kvs <-chan KeyValue) {
+	for {
+		var kv KeyValue
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case kv, ok = <-kvs:
+			if !ok {
+				return
+			}
+		}
+
+		if kv.Deleted || !strings.HasPrefix(kv.Key, p.prefix) {
+			continue
+		}
+
+		if err := p.setValue(c, kv.Key, kv.Value); err != nil {
+			c.Printf("[%s] Failed to update the key '%s': %s", p.Name(), kv.Key, err)
+		}
+	}
+}
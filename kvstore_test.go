@@ -0,0 +1,89 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testKeyValueStore struct {
+	kvs    map[string]string
+	events chan KeyValue
+}
+
+func (s testKeyValueStore) List(prefix string) (keys []string, err error) {
+	for key := range s.kvs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+func (s testKeyValueStore) Get(key string) (string, bool, error) {
+	value, ok := s.kvs[key]
+	return value, ok, nil
+}
+
+func (s testKeyValueStore) Watch(prefix string) (<-chan KeyValue, error) {
+	return s.events, nil
+}
+
+func TestKeyValueStoreParser(t *testing.T) {
+	store := testKeyValueStore{
+		kvs: map[string]string{
+			"/myapp/port":            "80",
+			"/myapp/db.mysql.conn":   "root@tcp",
+			"/myapp/db.mysql.unused": "xxx",
+			"/other/port":            "90",
+		},
+		events: make(chan KeyValue, 1),
+	}
+
+	updated := make(chan struct{})
+	conf := NewConfig().AddParser(NewKeyValueStoreParser("test", 50, store, "/myapp/", true))
+	conf.Observe(func(group, name string, value interface{}) {
+		if name == "port" && value == 8080 {
+			close(updated)
+		}
+	})
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	store.events <- KeyValue{Key: "/myapp/port", Value: "8080"}
+
+	select {
+	case <-updated:
+	case <-time.After(time.Second):
+		t.Fatal("timeout to wait for the update")
+	}
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "fmt"
+
+// isLateParser reports whether the parser populates the options registered
+// after parsing, which are the environment variable parser and the config
+// file parsers.
+func isLateParser(p Parser) bool {
+	switch p.(type) {
+	case envVarParser, fileParser:
+		return true
+	default:
+		return false
+	}
+}
+
+// populateLateOpts populates the values of the options registered after
+// parsing by the environment variable parser and the config file parsers,
+// then by the defaults.
+//
+// It does not stop at the invalid value or the required option without value,
+// but returns ValidateError listing all of them, which are left unset.
+func (c *Config) populateLateOpts(opts []structOpt) error {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	var errs []error
+	// The parsers have been sorted by the priority, so the first parser
+	// setting the option has the highest priority.
+	for _, parser := range c.parsers {
+		if !isLateParser(parser) {
+			continue
+		}
+
+		values, err := c.stageParser(parser)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[%s] %s", parser.Name(), err))
+			continue
+		}
+
+		for _, so := range opts {
+			group := c.getGroupByName(so.Group, false)
+			if v, ok := values[group.name][so.Opt.Name()]; ok {
+				if err = group.setOptValue(parser.Priority(), so.Opt.Name(), v); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	for _, so := range opts {
+		group := c.getGroupByName(so.Group, false)
+		if group.isSet(so.Opt.Name()) {
+			continue
+		}
+
+		if v := so.Opt.Default(); v != nil {
+			if err := group.setOptValue(1000, so.Opt.Name(), v); err != nil {
+				errs = append(errs, err)
+			}
+		} else if v = so.Opt.Zero(); c.isZero && v != nil {
+			if err := group.setOptValue(1000, so.Opt.Name(), v); err != nil {
+				errs = append(errs, err)
+			}
+		} else if c.isRequired && !isAnyOpt(so.Opt) {
+			errs = append(errs, fmt.Errorf("the option '%s' in the group '%s' has no value",
+				so.Opt.Name(), group.name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return ValidateError{Errors: errs}
+	}
+	return nil
+}
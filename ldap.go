@@ -0,0 +1,148 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LDAPEntry is the entry of LDAP returned by LDAPClient.
+type LDAPEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// LDAPClient is the LDAP client interface used by the LDAP parser.
+//
+// It is a small subset of the LDAP operations, so any LDAP client,
+// such as github.com/go-ldap/ldap, can be adapted to it easily.
+type LDAPClient interface {
+	// Search returns the entry baseDN if subtree is false, that's, the scope
+	// is "base". Or, it returns the entry baseDN and all its descendants,
+	// that's, the scope is "sub". The filter is "(objectClass=*)".
+	Search(baseDN string, subtree bool) ([]LDAPEntry, error)
+}
+
+type ldapParser struct {
+	prio    int
+	client  LDAPClient
+	baseDN  string
+	subtree bool
+}
+
+// NewLDAPParser returns a new parser to read the options from the attributes
+// of the LDAP entries.
+//
+// The attributes of the entry baseDN are the options of the default group.
+// If subtree is true, the descendants of baseDN are the groups, the names of
+// which are the values of the relative DN from the top to the bottom, such as
+// the entry "cn=mysql,cn=db,ou=myapp,dc=example,dc=com" for the group
+// "db.mysql" if baseDN is "ou=myapp,dc=example,dc=com".
+//
+// The attribute name matches the option name case-insensitively, and the
+// multiple values of the attribute are joined by the comma.
+//
+// Notice: the attributes that have not been registered as the options
+// are ignored.
+func NewLDAPParser(priority int, client LDAPClient, baseDN string, subtree bool) Parser {
+	if client == nil || baseDN == "" {
+		panic(fmt.Errorf("the LDAP client and the base DN must not be empty"))
+	}
+	return ldapParser{prio: priority, client: client, baseDN: baseDN, subtree: subtree}
+}
+
+func (p ldapParser) Name() string {
+	return "ldap"
+}
+
+func (p ldapParser) Priority() int {
+	return p.prio
+}
+
+func (p ldapParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p ldapParser) Post(c *Config) error {
+	return nil
+}
+
+func (p ldapParser) Parse(c *Config) error {
+	entries, err := p.client.Search(p.baseDN, p.subtree)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		gname, ok := p.groupName(c, entry.DN)
+		if !ok {
+			continue
+		}
+
+		group := c.getGroupByName(gname, false)
+		if group == nil {
+			c.debug("Ignore the LDAP entry '%s'", entry.DN)
+			continue
+		}
+
+		// The attribute name is case-insensitive.
+		allOpts := group.AllOpts()
+		opts := make(map[string]string, len(allOpts))
+		for _, opt := range allOpts {
+			opts[strings.ToLower(opt.Name())] = opt.Name()
+		}
+
+		c.Printf("[%s] Parsing the entry '%s'", p.Name(), entry.DN)
+		for attr, values := range entry.Attributes {
+			name, ok := opts[strings.ToLower(attr)]
+			if !ok {
+				continue
+			}
+
+			value := strings.Join(values, ",")
+			if err = c.SetOptValue(p.prio, group.FullName(), name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// groupName returns the group name of the entry dn under the base DN.
+func (p ldapParser) groupName(c *Config, dn string) (string, bool) {
+	dn = strings.TrimSpace(dn)
+	if strings.EqualFold(dn, p.baseDN) {
+		return "", true
+	}
+
+	suffix := "," + p.baseDN
+	if len(dn) <= len(suffix) || !strings.EqualFold(dn[len(dn)-len(suffix):], suffix) {
+		return "", false
+	}
+
+	rdns := strings.Split(dn[:len(dn)-len(suffix)], ",")
+	names := make([]string, len(rdns))
+	for i, rdn := range rdns {
+		if index := strings.IndexByte(rdn, '='); index > -1 {
+			rdn = rdn[index+1:]
+		}
+		names[len(rdns)-i-1] = strings.TrimSpace(rdn)
+	}
+	return strings.Join(names, c.GetGroupSeparator()), true
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+type testLDAPClient []LDAPEntry
+
+func (c testLDAPClient) Search(baseDN string, subtree bool) (entries []LDAPEntry, err error) {
+	for _, entry := range c {
+		if strings.EqualFold(entry.DN, baseDN) ||
+			(subtree && strings.HasSuffix(strings.ToLower(entry.DN), ","+strings.ToLower(baseDN))) {
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+func TestLDAPParser(t *testing.T) {
+	client := testLDAPClient{
+		{DN: "ou=myapp,dc=example,dc=com", Attributes: map[string][]string{
+			"Port":        {"80"},
+			"objectClass": {"organizationalUnit"},
+		}},
+		{DN: "cn=mysql,cn=db,ou=myapp,dc=example,dc=com", Attributes: map[string][]string{
+			"conn":  {"root@tcp"},
+			"hosts": {"a", "b"},
+		}},
+		{DN: "cn=redis,ou=myapp,dc=example,dc=com", Attributes: map[string][]string{
+			"conn": {"redis://127.0.0.1"},
+		}},
+		{DN: "ou=other,dc=example,dc=com", Attributes: map[string][]string{"port": {"90"}}},
+	}
+
+	for _, subtree := range []bool{true, false} {
+		conf := NewConfig().AddParser(NewLDAPParser(50, client, "OU=myapp,dc=example,dc=com", subtree))
+		conf.RegisterOpt("", Int("port", 0, ""))
+		conf.RegisterOpts("db.mysql", []Opt{Str("conn", "", ""), Strings("hosts", nil, "")})
+		if err := conf.Parse(); err != nil {
+			t.Fatal(err)
+		}
+
+		mysql := conf.Group("db.mysql")
+		if v := conf.Int("port"); v != 80 {
+			t.Errorf("port: expected 80, got %d", v)
+		}
+
+		if !subtree {
+			if v := mysql.String("conn"); v != "" {
+				t.Errorf("conn: expected '', got '%s'", v)
+			}
+			continue
+		}
+
+		if v := mysql.String("conn"); v != "root@tcp" {
+			t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+		}
+		if v := mysql.Strings("hosts"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+			t.Errorf("hosts: expected [a b], got %v", v)
+		}
+	}
+}
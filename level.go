@@ -0,0 +1,119 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the log level, the value of the log level option.
+type Level int
+
+// Predefine some log levels.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{"debug", "info", "warn", "error", "fatal"}
+
+// String returns the name of the level, such as "info".
+func (l Level) String() string {
+	if l >= LevelDebug && l <= LevelFatal {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel parses the log level case-insensitively, which is one of
+// "debug", "info", "warn" or "warning", "error" and "fatal", or the numeric
+// alias from "0" for debug to "4" for fatal.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "0":
+		return LevelDebug, nil
+	case "info", "1":
+		return LevelInfo, nil
+	case "warn", "warning", "2":
+		return LevelWarn, nil
+	case "error", "3":
+		return LevelError, nil
+	case "fatal", "4":
+		return LevelFatal, nil
+	default:
+		return 0, fmt.Errorf("invalid log level '%s'", s)
+	}
+}
+
+// ToLevel does the best to convert a certain value to Level.
+//
+// If the value is string, it is parsed by ParseLevel.
+func ToLevel(_v interface{}) (Level, error) {
+	switch v := _v.(type) {
+	case Level:
+		return v, nil
+	case string:
+		return ParseLevel(v)
+	case []byte:
+		return ParseLevel(string(v))
+	}
+
+	v, err := ToInt64(_v)
+	if err != nil {
+		return 0, err
+	} else if v < int64(LevelDebug) || v > int64(LevelFatal) {
+		return 0, fmt.Errorf("invalid log level '%d'", v)
+	}
+	return Level(v), nil
+}
+
+type levelOpt struct {
+	baseOpt
+}
+
+// LogLevelOpt returns a new log level option, the value of which is Level.
+//
+// For the string value, it is parsed by ParseLevel.
+func LogLevelOpt(short, name string, _default Level, help string) ValidatorChainOpt {
+	return levelOpt{newBaseOpt(short, name, _default, help, levelType)}
+}
+
+// LogLevel is equal to LogLevelOpt("", name, _default, help).
+func LogLevel(name string, _default Level, help string) ValidatorChainOpt {
+	return LogLevelOpt("", name, _default, help)
+}
+
+// Choices returns the names of the log levels.
+func (o levelOpt) Choices() []string {
+	return levelNames
+}
+
+// SetValidators resets the validator chain.
+func (o levelOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o levelOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
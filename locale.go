@@ -0,0 +1,180 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type localeOpt struct {
+	baseOpt
+}
+
+// LocaleOpt returns a new locale option, the value of which is a string
+// as the BCP 47 language tag, such as "en", "en-US" or "zh-Hans-CN".
+//
+// The value is validated and canonicalized by ParseLocale when parsing.
+func LocaleOpt(short, name string, _default string, help string) ValidatorChainOpt {
+	o := localeOpt{newBaseOpt(short, name, nil, help, stringType)}
+	if _default != "" {
+		v, err := ParseLocale(_default)
+		if err != nil {
+			panic(fmt.Errorf("the default of the locale option '%s' is invalid: %s", name, err))
+		}
+		o._default = v
+	}
+	return o
+}
+
+// Locale is equal to LocaleOpt("", name, _default, help).
+func Locale(name string, _default string, help string) ValidatorChainOpt {
+	return LocaleOpt("", name, _default, help)
+}
+
+// SetValidators resets the validator chain.
+func (o localeOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o localeOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
+
+// Parse parses the value of the option to the canonical language tag.
+func (o localeOpt) Parse(data interface{}) (interface{}, error) {
+	s, err := ToString(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLocale(s)
+}
+
+// ParseLocale validates the well-formed BCP 47 language tag and returns it
+// in the canonical case, that's, the language in lower case, the script
+// in title case and the region in upper case, such as "zh-Hans-CN".
+//
+// The underscore is accepted as the separator, so "en_us" is parsed
+// as "en-US". But the grandfathered tags, such as "i-klingon", are not
+// supported, and the subtags are not checked against the IANA registry.
+func ParseLocale(s string) (string, error) {
+	orig := s
+	s = strings.Replace(strings.TrimSpace(s), "_", "-", -1)
+	if s == "" {
+		return "", fmt.Errorf("the locale is empty")
+	}
+
+	parts := strings.Split(strings.ToLower(s), "-")
+	for _, part := range parts {
+		if part == "" || len(part) > 8 || !isAlnum(part) {
+			return "", fmt.Errorf("invalid locale '%s'", orig)
+		}
+	}
+
+	i, n := 0, len(parts)
+	if parts[0] != "x" {
+		// Language
+		switch lang := parts[0]; {
+		case !isAlpha(lang):
+			return "", fmt.Errorf("invalid locale '%s'", orig)
+		case len(lang) < 4:
+			if len(lang) < 2 {
+				return "", fmt.Errorf("invalid locale '%s'", orig)
+			}
+
+			// Extended language subtags
+			for i++; i < n && i < 4 && len(parts[i]) == 3 && isAlpha(parts[i]); i++ {
+			}
+		default:
+			i++
+		}
+
+		// Script
+		if i < n && len(parts[i]) == 4 && isAlpha(parts[i]) {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			i++
+		}
+
+		// Region
+		if i < n && ((len(parts[i]) == 2 && isAlpha(parts[i])) ||
+			(len(parts[i]) == 3 && isDigit(parts[i]))) {
+			parts[i] = strings.ToUpper(parts[i])
+			i++
+		}
+
+		// Variants
+		for ; i < n; i++ {
+			if p := parts[i]; len(p) < 4 || (len(p) == 4 && !isDigit(p[:1])) {
+				break
+			}
+		}
+
+		// Extensions
+		for i < n && len(parts[i]) == 1 && parts[i] != "x" {
+			start := i
+			for i++; i < n && len(parts[i]) > 1; i++ {
+			}
+			if i == start+1 {
+				return "", fmt.Errorf("invalid locale '%s': empty extension '%s'",
+					orig, parts[start])
+			}
+		}
+	}
+
+	// Private use
+	if i < n && parts[i] == "x" {
+		if i++; i == n {
+			return "", fmt.Errorf("invalid locale '%s': empty private use", orig)
+		}
+		i = n
+	}
+
+	if i < n {
+		return "", fmt.Errorf("invalid locale '%s': unexpected subtag '%s'", orig, parts[i])
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+func isAlpha(s string) bool {
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
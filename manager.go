@@ -26,10 +26,19 @@ package config
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net"
 	"os"
+	"reflect"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 )
 
@@ -41,6 +50,15 @@ var (
 	ErrNotParsed = fmt.Errorf("the config manager has not been parsed")
 )
 
+// StructDefaulter is used to compute the defaults of the struct, which is
+// called before registering the struct by RegisterStruct, and the non-zero
+// field values set by SetDefaults are used as the defaults of the options.
+//
+// The tag "default" takes precedence over it.
+type StructDefaulter interface {
+	SetDefaults()
+}
+
 // StructValidator is used to validate the struct value.
 type StructValidator interface {
 	Validate() error
@@ -48,28 +66,83 @@ type StructValidator interface {
 
 // Config is used to manage the configuration parsers.
 type Config struct {
+	// It's the first field to be 64-bit aligned for the atomic operations.
+	generation uint64
+
 	parsed bool
 
 	isRequired bool
 	isDebug    bool
-	isPanic    bool
 	isZero     bool
+	dryRun     bool
 
 	vName    string
 	vHelp    string
 	vVersion string
+	vPrinter VersionPrinter
+
+	cName string // The name of the completion option
+	oName string // The name of the override option
+	oHelp string
+
+	pName      string // The name of the print config option
+	pHelp      string
+	pRequested bool
+
+	gName string // The name of the generate config option
+	gHelp string
+
+	description string
+	examples    []HelpExample
+
+	helpTmpl   *template.Template
+	prompter   Prompter
+	redirects  map[string]optRedirect
+	required   map[string]bool
+	immutables map[string]bool
+	optTTLs    map[string]time.Duration
+	sourceTTLs map[int]time.Duration
+	frozen     bool
+	optHelps   map[string]string
+	conflicts  []error
+	conflict   ConflictPolicy
+	structLock sync.Locker
+	fieldNamer func(string) string
+	envNames   map[string]string
+	exclusives [][]string
+	tagKey     string
+
+	watchFiles bool
+	staging    bool // The snapshot to stage a parser, which watches nothing.
+
+	watchOnce   sync.Once
+	watchCtx    context.Context
+	watchCancel context.CancelFunc
+	watchWG     sync.WaitGroup
+
+	ignoreUnknown bool
 
 	args    []string
 	cliArgs []string
+	argOpts []argOpt
+	argGrp  *OptGroup
 	parsers []Parser
 
 	groupSep    string
 	groupName   string // Default Group Name
 	groupPrefix string // The prefix of the default group name.
 
-	watch      func(string, string, interface{})
+	audit      *auditTrail
+	obsLock    sync.RWMutex
+	observers  []*observer
 	groups     map[string]*OptGroup
 	validators []func() error
+
+	// lock guards groups, conflicts and the maps of the options, such as
+	// required and redirects, which may be changed by the options registered
+	// after parsing.
+	// No other lock is acquired by holding it.
+	lock sync.RWMutex
 }
 
 // NewConfig returns a new Config.
@@ -78,9 +151,9 @@ type Config struct {
 func NewConfig() *Config {
 	conf := &Config{
 		isZero:     true,
-		isPanic:    true,
 		isRequired: true,
 		groupName:  DefaultGroupName,
+		tagKey:     DefaultStructTagKey,
 		groups:     make(map[string]*OptGroup, 2),
 	}
 	return conf.SetGroupSeparator(".")
@@ -129,16 +202,348 @@ func (c *Config) SetRequired(required bool) *Config {
 	return c
 }
 
+// MarkRequired marks the options in the group required, which must be set
+// explicitly by a parser, such as the CLI or the config file, that's, neither
+// the default value nor the ZERO value counts.
+//
+// Parse will fail with all the missing required options at once, and the CLI
+// help output annotates them with "[required]".
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) MarkRequired(group string, optNames ...string) *Config {
+	c.panicIsParsed(true)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.required == nil {
+		c.required = make(map[string]bool, len(optNames))
+	}
+	for _, name := range optNames {
+		c.required[c.optKey(group, name)] = true
+	}
+	return c
+}
+
+// MarkImmutable marks the options in the group immutable after parsing,
+// such as the data directory, which the hot reload, such as Reload or
+// SetOptValue, can't change at runtime, and the attempt is rejected
+// with an error. But setting the same value is allowed.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) MarkImmutable(group string, optNames ...string) *Config {
+	c.panicIsParsed(true)
+	return c.markImmutable(group, optNames...)
+}
+
+func (c *Config) markImmutable(group string, optNames ...string) *Config {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.immutables == nil {
+		c.immutables = make(map[string]bool, len(optNames))
+	}
+	for _, name := range optNames {
+		c.immutables[c.optKey(group, name)] = true
+	}
+	return c
+}
+
+// SetOptEnv sets the name of the environment variable of the option in the
+// group, which is looked up by the environment variable parser exactly instead
+// of the name "PREFIX_GROUP_OPTION", see NewEnvVarParser. It's useful for the
+// platform dictating the names, such as "DATABASE_URL" or "PORT".
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetOptEnv(group, name, env string) *Config {
+	c.panicIsParsed(true)
+	return c.setOptEnv(group, name, env)
+}
+
+func (c *Config) setOptEnv(group, name, env string) *Config {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.envNames == nil {
+		c.envNames = make(map[string]string, 4)
+	}
+	c.envNames[c.optKey(group, name)] = env
+	return c
+}
+
+// isOptRequired reports whether the option is marked by MarkRequired.
+func (c *Config) isOptRequired(group, name string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.required[c.optKey(group, name)]
+}
+
+// purgeOpt removes all the information of the option, such as marked by
+// MarkRequired, when the option is unregistered.
+func (c *Config) purgeOpt(group, name string) {
+	key := c.optKey(group, name)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.envNames, key)
+	delete(c.required, key)
+	delete(c.optHelps, key)
+	delete(c.optTTLs, key)
+	delete(c.immutables, key)
+
+	// The aliases and the old names of the option, and the option itself
+	// as the deprecated option.
+	delete(c.redirects, key)
+	for k, d := range c.redirects {
+		if d.newName != "" && c.optKey(d.newGroup, d.newName) == key {
+			delete(c.redirects, k)
+		}
+	}
+
+	// The mutually exclusive options are copied on write,
+	// because they are read without holding the lock.
+	exclusives := make([][]string, 0, len(c.exclusives))
+	for _, keys := range c.exclusives {
+		_keys := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if k != key {
+				_keys = append(_keys, k)
+			}
+		}
+		if len(_keys) > 1 {
+			exclusives = append(exclusives, _keys)
+		}
+	}
+	c.exclusives = exclusives
+}
+
+// isOptImmutable reports whether the option is marked by MarkImmutable.
+func (c *Config) isOptImmutable(group, name string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.immutables[c.optKey(group, name)]
+}
+
+// optEnv returns the name of the environment variable of the option
+// set by SetOptEnv.
+func (c *Config) optEnv(group, name string) (env string, ok bool) {
+	c.lock.RLock()
+	env, ok = c.envNames[c.optKey(group, name)]
+	c.lock.RUnlock()
+	return
+}
+
+// checkMarkedRequired checks whether all the options marked by MarkRequired
+// have been set, and returns all the missing ones.
+func (c *Config) checkMarkedRequired() error {
+	c.lock.RLock()
+	keys := make([]string, 0, len(c.required))
+	for key := range c.required {
+		keys = append(keys, key)
+	}
+	c.lock.RUnlock()
+
+	missings := make([]string, 0, len(keys))
+	for _, key := range keys {
+		gname, name := c.splitOptKey(key)
+		if group := c.getGroupByName(gname, false); group == nil || !group.isSet(name) {
+			missings = append(missings, key)
+		}
+	}
+
+	if len(missings) == 0 {
+		return nil
+	}
+
+	sort.Strings(missings)
+	return fmt.Errorf("missing the required options: %s", strings.Join(missings, ", "))
+}
+
+// MarkMutuallyExclusive marks the options in the group mutually exclusive,
+// that's, Parse will fail if more than one of them are set explicitly by
+// the parsers, such as "--json" and "--yaml" for the output format.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) MarkMutuallyExclusive(group string, optNames ...string) *Config {
+	c.panicIsParsed(true)
+	if len(optNames) < 2 {
+		panic(fmt.Errorf("the mutually exclusive options must be more than one"))
+	}
+
+	group = c.normalizeGroupName(group)
+	keys := make([]string, len(optNames))
+	for i, name := range optNames {
+		keys[i] = c.optKey(group, name)
+	}
+
+	c.lock.Lock()
+	c.exclusives = append(c.exclusives, keys)
+	c.lock.Unlock()
+	return c
+}
+
+// checkMutuallyExclusive checks whether more than one of the options marked
+// by MarkMutuallyExclusive have been set.
+func (c *Config) checkMutuallyExclusive() error {
+	c.lock.RLock()
+	exclusives := c.exclusives
+	c.lock.RUnlock()
+
+	for _, keys := range exclusives {
+		sets := make([]string, 0, len(keys))
+		for _, key := range keys {
+			gname, name := c.splitOptKey(key)
+			if group := c.getGroupByName(gname, false); group != nil && group.isSet(name) {
+				sets = append(sets, key)
+			}
+		}
+
+		if len(sets) > 1 {
+			return fmt.Errorf("the options %s are mutually exclusive, but set: %s",
+				strings.Join(keys, ", "), strings.Join(sets, ", "))
+		}
+	}
+	return nil
+}
+
 // IgnoreReregister decides whether it will panic when reregistering an option
 // into a certain group.
 //
 // The default is not to ignore it, but you can set it to false to ignore it.
+//
+// It's equal to SetConflictPolicy(ConflictIgnore) or SetConflictPolicy(ConflictPanic).
 func (c *Config) IgnoreReregister(ignore bool) *Config {
+	if ignore {
+		return c.SetConflictPolicy(ConflictIgnore)
+	}
+	return c.SetConflictPolicy(ConflictPanic)
+}
+
+// IgnoreUnknown decides whether the config file parsers, such as the ini and
+// property parsers, ignore the options which have not been registered,
+// such as the options of the plugin registered after parsing, see RegisterOpt.
+//
+// The default is not to ignore them, that's, to return an error.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) IgnoreUnknown(ignore bool) *Config {
+	c.panicIsParsed(true)
+	c.ignoreUnknown = ignore
+	return c
+}
+
+// ConflictPolicy is the policy to handle the option registered into the same
+// group repeatedly, for example, by two libraries registering "timeout".
+type ConflictPolicy int
+
+// Predefine some conflict policies.
+const (
+	// ConflictPanic panics when registering the option repeatedly.
+	ConflictPanic ConflictPolicy = iota
+
+	// ConflictIgnore ignores the later option and keeps the registered one.
+	ConflictIgnore
+
+	// ConflictError keeps the registered option, and Parse returns the error
+	// reporting all the conflicted options.
+	ConflictError
+
+	// ConflictOverride replaces the registered option with the later one.
+	ConflictOverride
+
+	// ConflictMergeHelp keeps the registered option, but appends the help
+	// of the later option to it.
+	ConflictMergeHelp
+)
+
+// SetConflictPolicy sets the policy to handle the option registered into
+// the same group repeatedly. The default is ConflictPanic.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetConflictPolicy(policy ConflictPolicy) *Config {
 	c.panicIsParsed(true)
-	c.isPanic = !ignore
+	c.conflict = policy
 	return c
 }
 
+// checkConflicts returns the error of the conflicted options if the conflict
+// policy is ConflictError, or the short names of the CLI options collide,
+// including those reported by the CLI parsers.
+func (c *Config) checkConflicts() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if len(c.conflicts) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(c.conflicts))
+	for i, err := range c.conflicts {
+		msgs[i] = err.Error()
+	}
+	return fmt.Errorf("the options conflict: %s", strings.Join(msgs, "; "))
+}
+
+// addConflict records the conflict of the options, see checkConflicts.
+func (c *Config) addConflict(err error) {
+	c.lock.Lock()
+	c.conflicts = append(c.conflicts, err)
+	c.lock.Unlock()
+}
+
+// lookupShort returns the key of the CLI option with the short name except
+// the option key, or "" if no such option.
+func (c *Config) lookupShort(short, key string) string {
+	for _, group := range c.AllGroups() {
+		for name, opt := range group.opts {
+			if opt.isCli && opt.opt.Short() == short {
+				if other := c.optKey(group.name, name); other != key {
+					return other
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// mergeOptHelp appends help to the help of the registered option in group.
+func (c *Config) mergeOptHelp(group string, opt Opt, help string) {
+	if help = strings.TrimSpace(help); help == "" {
+		return
+	}
+
+	key := c.optKey(group, opt.Name())
+	old := c.optHelp(group, opt)
+	if old == help || strings.Contains(old, help) {
+		return
+	} else if old != "" {
+		help = old + "; " + help
+	}
+
+	c.lock.Lock()
+	if c.optHelps == nil {
+		c.optHelps = make(map[string]string, 4)
+	}
+	c.optHelps[key] = help
+	c.lock.Unlock()
+}
+
+// optHelp returns the help of the option in group, which may be merged
+// by the conflict policy ConflictMergeHelp.
+func (c *Config) optHelp(group string, opt Opt) string {
+	c.lock.RLock()
+	help, ok := c.optHelps[c.optKey(group, opt.Name())]
+	c.lock.RUnlock()
+	if ok {
+		return help
+	}
+	return opt.Help()
+}
+
 // SetZero sets the value of the option to the zero value of its type
 // if the option has no value.
 //
@@ -213,6 +618,100 @@ func (c *Config) Parsed() bool {
 //
 // If parsed, it will panic when calling it.
 func (c *Config) Parse(args ...string) (err error) {
+	return c.parse(args)
+}
+
+// Reload re-runs all the parsers except the CLI parsers after parsing,
+// which is used to implement the reload trigger, such as the signal SIGHUP.
+//
+// The parsers are run into the staging values, which are applied all or
+// nothing by the transaction only if all of them are valid, see Begin.
+// And only the changed option values are applied.
+//
+// Notice: the parser which watches or refreshes the source by itself,
+// such as NewSQLParser with the interval, should not be reloaded,
+// because it would watch the staging values instead of the configuration.
+func (c *Config) Reload() error {
+	if !c.parsed {
+		return fmt.Errorf("the config has not been parsed")
+	}
+
+	txn := c.Begin()
+	staged := make([]map[string]map[string]interface{}, len(c.parsers))
+	for i, parser := range c.parsers {
+		if _, ok := parser.(cliParser); ok {
+			continue
+		}
+
+		c.debug("Reloading the parser '%s'", parser.Name())
+		values, err := c.stageParser(parser)
+		if err != nil {
+			return fmt.Errorf("The '%s' parser failed: %s", parser.Name(), err)
+		}
+		staged[i] = values
+
+		for gname, opts := range values {
+			group := c.getGroupByName(gname, false)
+			if group == nil {
+				continue
+			}
+
+			for name, value := range opts {
+				if !reflect.DeepEqual(group.Value(name), value) {
+					txn.SetOptValue(parser.Priority(), gname, name, value)
+				}
+			}
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+
+	// Refresh the TTLs of the unchanged values, see SetOptTTL.
+	for i, values := range staged {
+		c.touchOpts(c.parsers[i].Priority(), values)
+	}
+	return nil
+}
+
+// ValidateError is the aggregated error report returned by Validate.
+type ValidateError struct {
+	Errors []error
+}
+
+func (e ValidateError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("the config is invalid:\n%s", strings.Join(msgs, "\n"))
+}
+
+// Validate is the same as Parse, but it is the dry-run mode to check whether
+// the configuration is valid, such as the config file in CI. It runs all the
+// parsers and the validators, and returns all the errors by ValidateError
+// instead of stopping at the first one.
+//
+// It guarantees that there is no side effect: it does not exit the program,
+// such as for the version option, prompt the user, or call the observers.
+// The parsers may check it by IsDryRun.
+//
+// Notice: after validating, it has been parsed, so you cannot call Parse.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) Validate(args ...string) error {
+	c.panicIsParsed(true)
+	c.dryRun = true
+	return c.parse(args)
+}
+
+// IsDryRun reports whether it is validating the configuration by Validate.
+func (c *Config) IsDryRun() bool {
+	return c.dryRun
+}
+
+func (c *Config) parse(args []string) (err error) {
 	c.panicIsParsed(true)
 	c.getGroupByName(c.groupName, true) // Ensure that the default group exists.
 
@@ -222,41 +721,101 @@ func (c *Config) Parse(args ...string) (err error) {
 		c.cliArgs = args
 	}
 
+	// In the dry-run mode, collect all the errors instead of returning.
+	var errs []error
+	failed := func(e error) bool {
+		if e == nil {
+			return false
+		} else if c.dryRun {
+			errs = append(errs, e)
+			return false
+		}
+		err = e
+		return true
+	}
+
 	for _, parser := range c.parsers {
 		c.debug("Initializing the parser '%s'", parser.Name())
-		if err = parser.Pre(c); err != nil {
-			return err
+		if failed(parser.Pre(c)) {
+			return
 		}
 	}
 
+	// Check the conflicts after initializing the parsers,
+	// which may report the conflicts of their own.
+	if failed(c.checkConflicts()) {
+		return
+	}
+
 	c.parsed = true
 	for _, parser := range c.parsers {
 		c.debug("Calling the parser '%s'", parser.Name())
-		if err = parser.Parse(c); err != nil {
-			return fmt.Errorf("The '%s' parser failed: %s", parser.Name(), err)
+		if e := parser.Parse(c); e != nil {
+			if failed(fmt.Errorf("The '%s' parser failed: %s", parser.Name(), e)) {
+				return
+			}
 		}
 	}
 
 	for _, parser := range c.parsers {
 		c.debug("Cleaning the parser '%s'", parser.Name())
-		if err = parser.Post(c); err != nil {
-			return err
+		if failed(parser.Post(c)) {
+			return
 		}
 	}
 
+	// Parse the positional arguments from the rest arguments.
+	if failed(c.parseArgs()) {
+		return
+	}
+
+	// Prompt the user to input the missing required options.
+	if !c.dryRun && failed(c.promptMissingOpts()) {
+		return
+	}
+
+	// Check the options marked by MarkRequired and MarkMutuallyExclusive
+	// before filling the defaults.
+	if failed(c.checkMarkedRequired()) || failed(c.checkMutuallyExclusive()) {
+		return
+	}
+
 	// Check whether all the groups have parsed all the required options.
-	for _, group := range c.groups {
-		if err = group.checkRequiredOption(); err != nil {
-			return err
+	for _, group := range c.AllGroups() {
+		if failed(group.checkRequiredOption()) {
+			return
 		}
 	}
 
 	for _, v := range c.validators {
-		if err = v(); err != nil {
-			return err
+		if failed(v()) {
+			return
 		}
 	}
 
+	if len(errs) > 0 {
+		return ValidateError{Errors: errs}
+	}
+
+	// Freeze the options marked by MarkImmutable.
+	c.frozen = true
+
+	// Print the configuration and exit if the CLI parser asks.
+	if c.pRequested && !c.dryRun {
+		c.PrintConfig(os.Stdout)
+		os.Exit(0)
+	}
+
+	// Watch the config files to reload them automatically.
+	if c.watchFiles && !c.dryRun {
+		c.watchFileParsers()
+	}
+
+	// Start to watch the sources of the parsers implementing WatchParser.
+	if !c.dryRun {
+		c.startWatchParsers()
+	}
+
 	return
 }
 
@@ -301,6 +860,64 @@ func (c *Config) GetVersion() (name, version, help string) {
 	return c.vName, c.vVersion, c.vHelp
 }
 
+// SetOverride sets the information of the repeatable CLI option to override
+// any option by the form "group.option=value", such as
+// "--set db.mysql.conn=root@tcp(127.0.0.1:3306)/db --set port=80".
+//
+// The option value set by it has the highest priority, which covers that
+// of the option itself.
+//
+// It supports:
+//     SetOverride()           // SetOverride("set")
+//     SetOverride(name)       // SetOverride("set")
+//     SetOverride(name, help) // SetOverride("set", "Override the option")
+//
+// Notice: it is for the CLI parser.
+func (c *Config) SetOverride(args ...string) *Config {
+	name := "set"
+	help := "Override the option by 'group.option=value', which can be repeated."
+	if len(args) == 1 {
+		name = args[0]
+	} else if len(args) > 1 {
+		name = args[0]
+		help = args[1]
+	}
+
+	if name == "" || help == "" {
+		panic(fmt.Errorf("The arguments about override must not be empty"))
+	}
+
+	c.oName = name
+	c.oHelp = help
+	return c
+}
+
+// GetOverride returns the information about the override option.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) GetOverride() (name, help string) {
+	return c.oName, c.oHelp
+}
+
+// applyOverrides sets the option values by the form "group.option=value",
+// which is used by the CLI parser for the override option.
+func (c *Config) applyOverrides(overrides []string) error {
+	for _, override := range overrides {
+		index := strings.IndexByte(override, '=')
+		if index < 1 {
+			return fmt.Errorf("invalid override '%s', which should be 'group.option=value'",
+				override)
+		}
+
+		key := strings.TrimSpace(override[:index])
+		group, name := c.splitOptKey(key)
+		if err := c.SetOptValue(0, group, name, override[index+1:]); err != nil {
+			return fmt.Errorf("failed to override '%s': %s", key, err)
+		}
+	}
+	return nil
+}
+
 // CliArgs returns the parsed cil argments.
 func (c *Config) CliArgs() []string {
 	return c.cliArgs
@@ -392,51 +1009,248 @@ func (c *Config) HasParser(name string) bool {
 // If the group name is "", it's regarded as the default group. And the struct
 // must be a pointer to a struct variable, or it will panic.
 //
-// If parsed, it will panic when calling it.
+// If parsed, the options are registered late, see RegisterOpt.
+//
+// The tags of the field are
+//
+//    name:"port"                 // The option name, or "-" to ignore the field
+//    short:"p"                   // The short name
+//    default:"80"                // The default value
+//    help:"the port"             // The help doc
+//    cli:"true"                  // Whether to register it as the CLI option, too
+//    group:"db"                  // The group instead of that of the struct, "" for the default
+//    layout:"2006-01-02|unix"    // The layouts of time.Time or []time.Time, see TimeOptWithLayouts
+//    env:"DATABASE_URL"          // The environment variable, see SetOptEnv
+//    choices:"json,yaml,table"   // The valid values listed in the help output
+//    deprecated:"use db.dsn"     // The deprecated message, see DeprecateOpt
+//    immutable:"true"            // Immutable after parsing, see MarkImmutable
+//    squash:"true"               // Register the nested struct into the parent group
+//    validate:"notempty,url"     // The validators, see below
+//
+// The bool tags, such as "cli", accept "1", "t", "T", "on", "On", "ON", "true",
+// "True" and "TRUE", or "0", "f", "F", "off", "Off", "OFF", "false", "False"
+// and "FALSE".
+//
+// The field of the custom type implementing encoding.TextUnmarshaler is
+// registered by CustomOpt. The field of json.RawMessage or interface{} is
+// the pass-through option keeping the raw value from the source: the former
+// is the JSON encoding of the value, see JSONOpt, and the latter is the value
+// as-is, such as the map decoded from the file.
 //
-// The tag of the field supports "name", "short", "default", "help", which are
-// equal to the name, the short name, the default, the help of the option.
-// If you want to ignore a certain field, just set the tag "name" to "-",
-// such as `name:"-"`. The field also contains the tag "cli", whose value maybe
-// "1", "t", "T", "on", "On", "ON", "true", "True", "TRUE", and which represents
-// the option is also registered into the CLI parser; but you can also use "0",
-// "f", "F", "off", "Off", "OFF", "false", "False" or "FALSE" to override or
-// disable it. Moreover, you can use the tag "group" to reset the group name,
-// that's, the group of the field with the tag "group" is different to the group
-// of the whole struct. If the value of the tag "group" is empty, the default
-// group will be used in preference.
+// If the struct, or the nested struct, has implemented the interface
+// StructDefaulter, it will be called to compute the defaults before registering.
 //
 // If the struct has implemented the interface StructValidator, this validator
 // will be called automatically after having parsed.
 //
 // Notice: If having no the tag "name", the name of the option is the lower-case
-// of the field name.
+// of the field name, which may be changed by SetFieldNameMapper.
 //
-// Notice: The struct supports the nested struct, but not the pointer field.
+// Notice: All the tags may be declared in the single tag "config" instead,
+// such as `config:"port,short=p,default=80"`, see SetStructTagKey.
 //
-// Notice: The struct doesn't support the validator. You maybe choose others,
-// such as github.com/asaskevich/govalidator.
+// Notice: The struct supports the nested struct and the pointer to struct,
+// such as *TLSConfig, which is allocated automatically if it is nil.
+// Like encoding/json, the fields of the anonymous struct are registered into
+// the group of the parent struct, unless the tag "name" or "group" is given.
+// For the named nested struct, the tag "squash" does the same, so the
+// nesting of the Go types is not the hierarchy of the groups.
+//
+// The validators of the tag "validate" are separated by the comma, such as
+// `validate:"range(1,65535)"`, which are one of
+//
+//    notempty           // NewStrNotEmptyValidator()
+//    url                // NewURLValidator()
+//    ip                 // NewIPValidator()
+//    email              // NewEmailValidator()
+//    address            // NewAddressValidator()
+//    port               // NewPortValidator()
+//    len(min,max)       // NewStrLenValidator(min, max)
+//    range(min,max)     // NewIntegerRangeValidator or NewFloatRangeValidator
+//    oneof(a,b,...)     // NewStrArrayValidator([]string{a, b, ...})
+//    regexp(pattern)    // NewRegexpValidator(pattern)
 //
 // NOTICE: ALL THE TAGS ARE OPTIONAL.
 //
-// Notice: For the struct option, you shouldn't call SetOptValue()
-// because of concurrence.
-func (c *Config) RegisterStruct(group string, s interface{}) {
-	c.registerStruct(group, s, false)
+// Notice: For the struct option, you shouldn't call SetOptValue() after
+// parsing because of concurrence, unless the struct locker is set by
+// SetStructLocker.
+//
+// It returns the binding of the struct, which may be used to repoint
+// the options to another struct by Swap, such as the hot reload.
+//
+// If parsed, the options are registered late, see RegisterOpt.
+func (c *Config) RegisterStruct(group string, s interface{}) *StructBinding {
+	b, err := c.registerStruct(group, s, false)
+	if b == nil {
+		panic(err)
+	} else if err != nil {
+		c.Printf("Failed to populate the late options of the struct: %s", err)
+	}
+	return b
+}
+
+// RegisterStructE is the same as RegisterStruct, but returns the error
+// instead of panicking, which is StructError listing all the offending fields,
+// such as the unsupported type, the invalid default or the unknown validator,
+// if the struct is invalid. And neither option nor group is registered
+// if returning an error, but the struct may have been modified, that's,
+// SetDefaults of StructDefaulter has been called and the nil pointers
+// to the structs have been allocated, since they are done while collecting
+// the options from the fields.
+//
+// If parsed but failing to populate the late options, it returns the binding
+// with the error, see RegisterOptE, and the options are still registered.
+func (c *Config) RegisterStructE(group string, s interface{}) (*StructBinding, error) {
+	return c.registerStruct(group, s, false)
+}
+
+// SetStructLocker sets the locker to synchronize the writes of the fields of
+// the registered structs, so the option values can be updated safely into the
+// structs by SetOptValue at runtime, such as the hot reload. For example,
+//
+//    var lock sync.RWMutex
+//    conf.SetStructLocker(&lock)
+//    conf.RegisterStruct("", &opts)
+//
+//    // Read the struct by holding the read lock.
+//    lock.RLock()
+//    addr := opts.Addr
+//    lock.RUnlock()
+//
+// The field is written by holding the locker, and the reader of the struct
+// must hold the same locker, or the read locker of sync.RWMutex.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetStructLocker(locker sync.Locker) *Config {
+	c.panicIsParsed(true)
+	c.structLock = locker
+	return c
+}
+
+// SetFieldNameMapper sets the function to map the name of the struct field
+// to the option name when registering the struct without the tag "name",
+// such as LowerCaseName, SnakeCaseName, KebabCaseName or AsIsName.
+//
+// The default is LowerCaseName, that's, "AccessKeyID" is "accesskeyid".
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetFieldNameMapper(mapper func(fieldName string) string) *Config {
+	c.panicIsParsed(true)
+	c.fieldNamer = mapper
+	return c
+}
+
+// fieldName returns the option name of the struct field.
+func (c *Config) fieldName(name string) string {
+	if c.fieldNamer == nil {
+		return LowerCaseName(name)
+	}
+	return c.fieldNamer(name)
+}
+
+// setStructField sets the value into the field of the registered struct.
+func (c *Config) setStructField(field reflect.Value, value interface{}) {
+	if c.structLock != nil {
+		c.structLock.Lock()
+		defer c.structLock.Unlock()
+	}
+
+	setFieldValue(field, value)
 }
 
 // RegisterCliStruct is the same as RegisterStruct, but it will register
 // the option into the CLI parser by default.
-func (c *Config) RegisterCliStruct(group string, s interface{}) {
-	c.registerStruct(group, s, true)
+func (c *Config) RegisterCliStruct(group string, s interface{}) *StructBinding {
+	b, err := c.registerStruct(group, s, true)
+	if b == nil {
+		panic(err)
+	} else if err != nil {
+		c.Printf("Failed to populate the late options of the struct: %s", err)
+	}
+	return b
 }
 
-func (c *Config) registerStruct(group string, s interface{}, cli bool) {
-	c.panicIsParsed(true)
-	c.getGroupByName(strings.Trim(group, c.groupSep), true).registerStruct(s, cli)
-	if v, ok := s.(StructValidator); ok {
-		c.validators = append(c.validators, v.Validate)
+// RegisterCliStructE is the same as RegisterStructE, but it will register
+// the option into the CLI parser by default.
+func (c *Config) RegisterCliStructE(group string, s interface{}) (*StructBinding, error) {
+	return c.registerStruct(group, s, true)
+}
+
+func (c *Config) registerStruct(group string, s interface{}, cli bool) (*StructBinding, error) {
+	// Create the group only after the struct is valid.
+	gname := strings.TrimPrefix(strings.Trim(group, c.groupSep), c.groupPrefix)
+	if gname == "" {
+		gname = c.groupName
 	}
+
+	opts, err := c.collectStruct(gname, s, cli)
+	if err != nil {
+		return nil, err
+	} else if errs := c.checkStructConflicts(opts); len(errs) > 0 {
+		return nil, StructError{Errors: errs}
+	}
+
+	g := c.getGroupByName(gname, true)
+	b := &StructBinding{conf: c, group: g.name, cli: cli, ptr: s}
+	b.bound = c.registerStructOpts(opts)
+	if _, ok := s.(StructValidator); ok {
+		c.validators = append(c.validators, b.validate)
+	}
+
+	if c.parsed {
+		bound := make([]structOpt, len(b.bound))
+		for i, index := range b.bound {
+			bound[i] = opts[index]
+		}
+		err = c.populateLateOpts(bound)
+	}
+	return b, err
+}
+
+// BindVar binds the variable, which ptr points to, to the registered option
+// in the group, so the option value is kept in sync into the variable on every
+// set, like flag.StringVar, but group-aware, such as
+//
+//    var dsn string
+//    conf.RegisterOpt("db", Str("dsn", "", "the dsn of the database"))
+//    conf.BindVar(&dsn, "db", "dsn")
+//
+// If the option has had a value, it's set into the variable immediately.
+// The binding of the option, such as by RegisterStruct, is replaced.
+//
+// If the group name is "", it's regarded as the default group.
+// It will panic if the option does not exist, or ptr is not a pointer to
+// the variable of the type of the option value.
+func (c *Config) BindVar(ptr interface{}, group, name string) *Config {
+	v := reflect.ValueOf(ptr)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Errorf("the variable of the option '%s' is not a valid pointer", name))
+	}
+
+	g := c.getGroupByName(strings.Trim(group, c.groupSep), false)
+	if g == nil {
+		panic(fmt.Errorf("no option '%s' in the group '%s'", name, c.normalizeGroupName(group)))
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	opt, ok := g.opts[name]
+	if !ok {
+		panic(fmt.Errorf("no option '%s' in the group '%s'", name, c.normalizeGroupName(group)))
+	}
+
+	field := v.Elem()
+	if zero := opt.opt.Zero(); zero != nil && !reflect.TypeOf(zero).AssignableTo(field.Type()) {
+		panic(fmt.Errorf("the variable of the option '%s' is %s, not %T",
+			name, field.Type().String(), zero))
+	}
+
+	g.fields[name] = field
+	if value, ok := g.values[name]; ok {
+		c.setStructField(field, value)
+	}
+	return c
 }
 
 // RegisterCliOpt registers the option into the group.
@@ -446,9 +1260,17 @@ func (c *Config) registerStruct(group string, s interface{}, cli bool) {
 //
 // If the group name is "", it's regarded as the default group.
 //
-// If parsed, it will panic when calling it.
+// If parsed, the option is registered late, see RegisterOpt.
 func (c *Config) RegisterCliOpt(group string, opt Opt) {
-	c.registerOpt(group, true, opt)
+	if err := c.registerOpt(group, true, opt); err != nil {
+		c.Printf("Failed to populate the late option '%s': %s", opt.Name(), err)
+	}
+}
+
+// RegisterCliOptE is the same as RegisterCliOpt, but returns the error
+// to populate the late option, see RegisterOptE.
+func (c *Config) RegisterCliOptE(group string, opt Opt) error {
+	return c.registerOpt(group, true, opt)
 }
 
 // RegisterCliOpts registers the options into the group.
@@ -458,7 +1280,7 @@ func (c *Config) RegisterCliOpt(group string, opt Opt) {
 //
 // If the group name is "", it's regarded as the default group.
 //
-// If parsed, it will panic when calling it.
+// If parsed, the option is registered late, see RegisterOpt.
 func (c *Config) RegisterCliOpts(group string, opts []Opt) {
 	for _, opt := range opts {
 		c.RegisterCliOpt(group, opt)
@@ -471,9 +1293,27 @@ func (c *Config) RegisterCliOpts(group string, opts []Opt) {
 //
 // If the group name is "", it's regarded as the default group.
 //
-// If parsed, it will panic when calling it.
+// If parsed, the option is registered late, such as by the plugin loaded
+// at runtime, and its value is populated immediately by the environment
+// variable parser and the config file parsers, such as the ini parser,
+// then by the default. But it's not populated by the CLI parser. If its value
+// is invalid, or it's required and has no value, the error is only logged
+// by Printf and the option is left unset, see RegisterOptE.
+//
+// Notice: registering the option late may be concurrent with the parsers
+// watching the sources, but not with the other registrations. And the sources
+// are parsed again to populate the late option, since the option is unknown
+// when parsing them at startup.
 func (c *Config) RegisterOpt(group string, opt Opt) {
-	c.registerOpt(group, false, opt)
+	if err := c.registerOpt(group, false, opt); err != nil {
+		c.Printf("Failed to populate the late option '%s': %s", opt.Name(), err)
+	}
+}
+
+// RegisterOptE is the same as RegisterOpt, but returns the error to populate
+// the option registered late, which is still registered, instead of logging it.
+func (c *Config) RegisterOptE(group string, opt Opt) error {
+	return c.registerOpt(group, false, opt)
 }
 
 // RegisterOpts registers the options into the group.
@@ -482,7 +1322,7 @@ func (c *Config) RegisterOpt(group string, opt Opt) {
 //
 // If the group name is "", it's regarded as the default group.
 //
-// If parsed, it will panic when calling it.
+// If parsed, the option is registered late, see RegisterOpt.
 func (c *Config) RegisterOpts(group string, opts []Opt) {
 	for _, opt := range opts {
 		c.RegisterOpt(group, opt)
@@ -496,10 +1336,94 @@ func (c *Config) RegisterOpts(group string, opts []Opt) {
 // The first argument, cli, indicates whether the option is as the CLI option,
 // too.
 //
-// If parsed, it will panic when calling it.
-func (c *Config) registerOpt(group string, cli bool, opt Opt) {
-	c.panicIsParsed(true)
-	c.getGroupByName(group, true).registerOpt(cli, opt)
+// If parsed, the option is registered late, see RegisterOpt.
+func (c *Config) registerOpt(group string, cli bool, opt Opt) error {
+	g := c.getGroupByName(group, true)
+	if !c.parsed {
+		g.registerOpt(cli, opt)
+		return nil
+	}
+
+	g.lock.Lock()
+	ok := g.registerOpt(cli, opt)
+	g.lock.Unlock()
+	if ok {
+		return c.populateLateOpts([]structOpt{{StructOpt: StructOpt{Group: g.name, Opt: opt, Cli: cli}}})
+	}
+	return nil
+}
+
+// UnregisterOpt removes the option named name from the group, including
+// its value, the field of the struct bound to it, the observers added
+// by ObserveOpt for it, and its settings, such as by MarkRequired, AliasOpt
+// or SetOptTTL, such as when the plugin is unloaded at runtime.
+// It reports whether the option has been registered.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// Notice: it should not be concurrent with the accesses to the option.
+func (c *Config) UnregisterOpt(group, name string) bool {
+	g := c.getGroupByName(group, false)
+	if g == nil {
+		return false
+	}
+
+	g.lock.Lock()
+	_, ok := g.opts[name]
+	if ok {
+		g.unregisterOpt(name)
+	}
+	g.lock.Unlock()
+
+	if ok {
+		c.removeObservers(func(o *observer) bool { return o.group == g.name && o.name == name })
+		c.debug("Unregister group=%s, name=%s", g.name, name)
+	}
+	return ok
+}
+
+// RemoveGroup removes the group and all its sub-groups, including all
+// their options like UnregisterOpt, such as when the plugin is unloaded
+// at runtime. It reports whether the group exists.
+//
+// If the group name is "", it's regarded as the default group, which can't
+// be removed and it will panic.
+//
+// Notice: it should not be concurrent with the accesses to the removed groups.
+func (c *Config) RemoveGroup(group string) bool {
+	name := c.normalizeGroupName(strings.Trim(group, c.groupSep))
+	if name == c.groupName {
+		panic(fmt.Errorf("the default group can't be removed"))
+	}
+
+	groups := make([]*OptGroup, 0, 4)
+	prefix := name + c.groupSep
+	c.lock.Lock()
+	for key, g := range c.groups {
+		if g.fname == name || strings.HasPrefix(g.fname, prefix) {
+			delete(c.groups, key)
+			groups = append(groups, g)
+		}
+	}
+	c.lock.Unlock()
+
+	removed := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		g.lock.Lock()
+		for optName := range g.opts {
+			g.unregisterOpt(optName)
+		}
+		g.lock.Unlock()
+
+		removed[g.name] = true
+		c.debug("Remove group '%s'", g.name)
+	}
+
+	if len(removed) == 0 {
+		return false
+	}
+	c.removeObservers(func(o *observer) bool { return removed[o.group] })
+	return true
 }
 
 //////////////////////////////////////////////////////////////////////////////
@@ -508,12 +1432,136 @@ func (c *Config) registerOpt(group string, cli bool, opt Opt) {
 // Observe watches the change of values.
 //
 // When the option value is changed, the function f will be called.
+// It may be called more than once to add more observers, even after parsing,
+// and returns the function to cancel the observer, such as when the component
+// observing the options is torn down.
 //
 // If SetOptValue() is used in the multi-thread, you should promise
 // that the callback function f is thread-safe and reenterable.
-func (c *Config) Observe(f func(groupName string, optName string, optValue interface{})) {
-	c.panicIsParsed(true)
-	c.watch = f
+func (c *Config) Observe(f func(groupName string, optName string, optValue interface{})) (cancel func()) {
+	return c.ObserveChange(func(group, name string, old, new interface{}) {
+		f(group, name, new)
+	})
+}
+
+// ObserveChange is the same as Observe, but the function f is called with
+// both the old and the new value of the option, so it can decide whether
+// the expensive re-initialization is needed, such as
+//
+//    conf.ObserveChange(func(group, name string, old, new interface{}) {
+//        if group == "db" && name == "dsn" && old != new {
+//            // Reconnect the database.
+//        }
+//    })
+//
+// The old value is nil if the option has no value before.
+func (c *Config) ObserveChange(f func(groupName, optName string,
+	oldValue, newValue interface{})) (cancel func()) {
+	return c.ObserveEvent(func(e ChangeEvent) { f(e.Group, e.Name, e.Old, e.New) })
+}
+
+// ChangeEvent is the event of the option value changed.
+type ChangeEvent struct {
+	Group string
+	Name  string
+	Old   interface{} // It's nil if the option has no value before.
+	New   interface{}
+
+	// Source is the name of the parser which has the priority of the change,
+	// "default" for the default value, "expired" for the value reverted
+	// to the default by the TTL, see SetOptTTL, or "" if unknown, such as
+	// set by SetOptValue directly.
+	Source string
+
+	// Generation is the generation of the configuration after the change,
+	// see Config.Generation.
+	Generation uint64
+}
+
+// ObserveEvent is the same as ObserveChange, but the function f is called
+// with the change event, which contains the generation of the configuration,
+// so the consumer can cache it to detect whether the cached state is stale.
+func (c *Config) ObserveEvent(f func(ChangeEvent)) (cancel func()) {
+	return c.addObserver(&observer{f: f})
+}
+
+// Generation returns the generation of the configuration, which is increased
+// monotonically every time the option values are changed, such as
+// by SetOptValue, or by the transaction or Reload, all the changes of which
+// have the same generation. So the consumer can detect cheaply whether
+// the configuration has changed since it was read.
+func (c *Config) Generation() uint64 {
+	return atomic.LoadUint64(&c.generation)
+}
+
+// nextGeneration increases and returns the generation of the configuration.
+func (c *Config) nextGeneration() uint64 {
+	return atomic.AddUint64(&c.generation, 1)
+}
+
+// ObserveOpt is the same as ObserveChange, but only observes the option
+// named name in the group, and the observer is cancelled automatically
+// when the option is removed by UnregisterOpt or RemoveGroup.
+//
+// If the group name is "", it's regarded as the default group.
+func (c *Config) ObserveOpt(group, name string, f func(oldValue, newValue interface{})) (cancel func()) {
+	return c.addObserver(&observer{
+		group: c.normalizeGroupName(strings.Trim(group, c.groupSep)),
+		name:  name,
+		f:     func(e ChangeEvent) { f(e.Old, e.New) },
+	})
+}
+
+func (c *Config) addObserver(o *observer) (cancel func()) {
+	c.obsLock.Lock()
+	c.observers = append(c.observers, o)
+	c.obsLock.Unlock()
+
+	return func() {
+		c.obsLock.Lock()
+		defer c.obsLock.Unlock()
+		for i, _o := range c.observers {
+			if _o == o {
+				observers := make([]*observer, 0, len(c.observers)-1)
+				observers = append(observers, c.observers[:i]...)
+				c.observers = append(observers, c.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+type observer struct {
+	group string // The group of the option observed by ObserveOpt
+	name  string // The name of the option observed by ObserveOpt, or ""
+	f     func(ChangeEvent)
+}
+
+// removeObservers removes the observers matched by the function match.
+func (c *Config) removeObservers(match func(*observer) bool) {
+	c.obsLock.Lock()
+	defer c.obsLock.Unlock()
+
+	observers := make([]*observer, 0, len(c.observers))
+	for _, o := range c.observers {
+		if o.name == "" || !match(o) {
+			observers = append(observers, o)
+		}
+	}
+	c.observers = observers
+}
+
+// notifyObservers calls all the observers for the change of the option value.
+func (c *Config) notifyObservers(e ChangeEvent) {
+	c.obsLock.RLock()
+	observers := c.observers
+	c.obsLock.RUnlock()
+
+	for _, o := range observers {
+		if o.name == "" || (o.group == e.Group && o.name == e.Name) {
+			o.f(e)
+		}
+	}
 }
 
 // SetOptValue sets the value of the option in the group. It's thread-safe.
@@ -523,18 +1571,98 @@ func (c *Config) Observe(f func(groupName string, optName string, optValue inter
 // to update it coercively.
 //
 // Notice: You cannot call SetOptValue() for the struct option, because we have
-// no way to promise that it's thread-safe.
+// no way to promise that it's thread-safe, unless the struct locker is set
+// by SetStructLocker.
 func (c *Config) SetOptValue(priority int, groupName, optName string, optValue interface{}) error {
 	if priority < 0 {
 		return fmt.Errorf("the priority must not be the negative")
 	}
 
+	groupName, optName = c.redirectOpt(groupName, optName)
 	if group := c.getGroupByName(groupName, false); group != nil {
 		return group.setOptValue(priority, optName, optValue)
 	}
 	return fmt.Errorf("no group '%s'", groupName)
 }
 
+// setOptValueIfExist is the same as SetOptValue, but ignores the option
+// which has not been registered.
+//
+// It is used by the parsers whose source may contain other unrelated keys,
+// such as the remote configuration center.
+func (c *Config) setOptValueIfExist(priority int, groupName, optName string, optValue interface{}) error {
+	gname, name := groupName, optName
+	if d, ok := c.getOptRedirect(groupName, optName); ok && d.newName != "" {
+		gname, name = d.newGroup, d.newName
+	}
+
+	group := c.getGroupByName(gname, false)
+	if group == nil || !group.HasOpt(name) {
+		c.debug("Ignore the unregistered option [%s]:[%s]", groupName, optName)
+		return nil
+	}
+	return c.SetOptValue(priority, groupName, optName, optValue)
+}
+
+// setOptValues sets the option values decoded by the Decoder.
+//
+// If ignoreUnknown is true, the unregistered options will be ignored.
+func (c *Config) setOptValues(priority int, values map[string]map[string]string,
+	ignoreUnknown bool) (err error) {
+	for group, opts := range values {
+		for name, value := range opts {
+			if ignoreUnknown {
+				err = c.setOptValueIfExist(priority, group, name, value)
+			} else {
+				err = c.SetOptValue(priority, group, name, value)
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// normalizeGroupName returns the full name of the group, which is the default
+// group if the name is "".
+func (c *Config) normalizeGroupName(group string) string {
+	return c.getGroupName(strings.TrimPrefix(group, c.groupPrefix))
+}
+
+// optKey returns the option name with its group, such as "group1.group2.opt".
+// For the default group, it's the option name.
+func (c *Config) optKey(group, name string) string {
+	if group = c.normalizeGroupName(group); group == c.groupName {
+		return name
+	}
+	return group + c.groupSep + name
+}
+
+// cliOptName returns the name of the CLI option, which is the option name
+// with its group, and the underline is converted to the hyphen if
+// underlineToHyphen is true.
+func (c *Config) cliOptName(group, name string, underlineToHyphen bool) string {
+	name = c.optKey(group, name)
+	if underlineToHyphen {
+		name = strings.Replace(name, "_", "-", -1)
+	}
+	return name
+}
+
+// splitOptKey splits the key like "group1.group2.opt" into the group name
+// "group1.group2" and the option name "opt".
+//
+// If the key does not contain the group separator, the group name is "",
+// that's, the default group.
+func (c *Config) splitOptKey(key string) (group, name string) {
+	if index := strings.LastIndex(key, c.groupSep); index > -1 {
+		return key[:index], key[index+len(c.groupSep):]
+	}
+	return "", key
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 /// Manage Group
 
@@ -583,11 +1711,14 @@ func (c *Config) printMap(parent string, ms map[string]interface{}, indent strin
 // which are the assistant groups.
 func (c *Config) Groups() []*OptGroup {
 	// c.panicIsParsed(false)
-	groups := make([]*OptGroup, 0, len(c.groups))
-	for _, group := range c.groups {
+	all := c.AllGroups()
+	groups := all[:0]
+	for _, group := range all {
+		group.lock.RLock()
 		if len(group.opts) > 0 {
 			groups = append(groups, group)
 		}
+		group.lock.RUnlock()
 	}
 	return groups
 }
@@ -597,10 +1728,12 @@ func (c *Config) Groups() []*OptGroup {
 // Notice: you should not modify the returned slice result.
 func (c *Config) AllGroups() []*OptGroup {
 	// c.panicIsParsed(false)
+	c.lock.RLock()
 	groups := make([]*OptGroup, 0, len(c.groups))
 	for _, group := range c.groups {
 		groups = append(groups, group)
 	}
+	c.lock.RUnlock()
 	return groups
 }
 
@@ -608,7 +1741,7 @@ func (c *Config) mergeGroupName(parent, name string) string {
 	if parent == "" {
 		return name
 	}
-	return strings.TrimPrefix(parent+"."+name, c.groupPrefix)
+	return strings.TrimPrefix(parent+c.groupSep+name, c.groupPrefix)
 }
 
 func (c *Config) getGroupName(name string) string {
@@ -619,10 +1752,15 @@ func (c *Config) getGroupName(name string) string {
 }
 
 func (c *Config) newOptGroup(name, fullName string) *OptGroup {
-	group := c.groups[name]
-	if group == nil {
+	c.lock.Lock()
+	group, ok := c.groups[name]
+	if !ok {
 		group = newOptGroup(name, fullName, c)
 		c.groups[name] = group
+	}
+	c.lock.Unlock()
+
+	if !ok {
 		c.debug("Creating group '%s'", name)
 	}
 	return group
@@ -632,7 +1770,10 @@ func (c *Config) getGroupByName(name string, new bool) *OptGroup {
 	name = strings.TrimPrefix(name, c.groupPrefix)
 
 	if !new {
-		return c.groups[c.getGroupName(name)]
+		c.lock.RLock()
+		group := c.groups[c.getGroupName(name)]
+		c.lock.RUnlock()
+		return group
 	} else if name == "" {
 		return c.newOptGroup(c.groupName, c.groupName)
 	}
@@ -644,12 +1785,16 @@ func (c *Config) getGroupByName(name string, new bool) *OptGroup {
 		c.newOptGroup(gname, fullName)
 	}
 
-	return c.groups[name]
+	c.lock.RLock()
+	group := c.groups[name]
+	c.lock.RUnlock()
+	return group
 }
 
 // NewGroup news and returns a group named group.
+//
+// If parsed, the group is created late, see RegisterOpt.
 func (c *Config) NewGroup(group string) *OptGroup {
-	c.panicIsParsed(true)
 	return c.getGroupByName(group, true)
 }
 
@@ -1049,3 +2194,143 @@ func (c *Config) TimesD(name string, _default []time.Time) []time.Time {
 func (c *Config) Times(name string) []time.Time {
 	return c.Group("").Times(name)
 }
+
+// CIDRE is equal to c.Group("").CIDRE(name).
+func (c *Config) CIDRE(name string) (*net.IPNet, error) {
+	return c.Group("").CIDRE(name)
+}
+
+// CIDRD is equal to c.Group("").CIDRD(name, _default).
+func (c *Config) CIDRD(name string, _default *net.IPNet) *net.IPNet {
+	return c.Group("").CIDRD(name, _default)
+}
+
+// CIDR is equal to c.Group("").CIDR(name).
+func (c *Config) CIDR(name string) *net.IPNet {
+	return c.Group("").CIDR(name)
+}
+
+// CIDRsE is equal to c.Group("").CIDRsE(name).
+func (c *Config) CIDRsE(name string) ([]*net.IPNet, error) {
+	return c.Group("").CIDRsE(name)
+}
+
+// CIDRsD is equal to c.Group("").CIDRsD(name, _default).
+func (c *Config) CIDRsD(name string, _default []*net.IPNet) []*net.IPNet {
+	return c.Group("").CIDRsD(name, _default)
+}
+
+// CIDRs is equal to c.Group("").CIDRs(name).
+func (c *Config) CIDRs(name string) []*net.IPNet {
+	return c.Group("").CIDRs(name)
+}
+
+// SizeE is equal to c.Group("").SizeE(name).
+func (c *Config) SizeE(name string) (int64, error) {
+	return c.Group("").SizeE(name)
+}
+
+// SizeD is equal to c.Group("").SizeD(name, _default).
+func (c *Config) SizeD(name string, _default int64) int64 {
+	return c.Group("").SizeD(name, _default)
+}
+
+// Size is equal to c.Group("").Size(name).
+func (c *Config) Size(name string) int64 {
+	return c.Group("").Size(name)
+}
+
+// LogLevelE is equal to c.Group("").LogLevelE(name).
+func (c *Config) LogLevelE(name string) (Level, error) {
+	return c.Group("").LogLevelE(name)
+}
+
+// LogLevelD is equal to c.Group("").LogLevelD(name, _default).
+func (c *Config) LogLevelD(name string, _default Level) Level {
+	return c.Group("").LogLevelD(name, _default)
+}
+
+// LogLevel is equal to c.Group("").LogLevel(name).
+func (c *Config) LogLevel(name string) Level {
+	return c.Group("").LogLevel(name)
+}
+
+// SecretE is equal to c.Group("").SecretE(name).
+func (c *Config) SecretE(name string) (Secret, error) {
+	return c.Group("").SecretE(name)
+}
+
+// SecretD is equal to c.Group("").SecretD(name, _default).
+func (c *Config) SecretD(name string, _default Secret) Secret {
+	return c.Group("").SecretD(name, _default)
+}
+
+// Secret is equal to c.Group("").Secret(name).
+func (c *Config) Secret(name string) Secret {
+	return c.Group("").Secret(name)
+}
+
+// BigIntE is equal to c.Group("").BigIntE(name).
+func (c *Config) BigIntE(name string) (*big.Int, error) {
+	return c.Group("").BigIntE(name)
+}
+
+// BigIntD is equal to c.Group("").BigIntD(name, _default).
+func (c *Config) BigIntD(name string, _default *big.Int) *big.Int {
+	return c.Group("").BigIntD(name, _default)
+}
+
+// BigInt is equal to c.Group("").BigInt(name).
+func (c *Config) BigInt(name string) *big.Int {
+	return c.Group("").BigInt(name)
+}
+
+// DecimalE is equal to c.Group("").DecimalE(name).
+func (c *Config) DecimalE(name string) (Decimal, error) {
+	return c.Group("").DecimalE(name)
+}
+
+// DecimalD is equal to c.Group("").DecimalD(name, _default).
+func (c *Config) DecimalD(name string, _default Decimal) Decimal {
+	return c.Group("").DecimalD(name, _default)
+}
+
+// Decimal is equal to c.Group("").Decimal(name).
+func (c *Config) Decimal(name string) Decimal {
+	return c.Group("").Decimal(name)
+}
+
+// JSONE is equal to c.Group("").JSONE(name).
+func (c *Config) JSONE(name string) (json.RawMessage, error) {
+	return c.Group("").JSONE(name)
+}
+
+// JSOND is equal to c.Group("").JSOND(name, _default).
+func (c *Config) JSOND(name string, _default json.RawMessage) json.RawMessage {
+	return c.Group("").JSOND(name, _default)
+}
+
+// JSON is equal to c.Group("").JSON(name).
+func (c *Config) JSON(name string) json.RawMessage {
+	return c.Group("").JSON(name)
+}
+
+// DecodeJSON is equal to c.Group("").DecodeJSON(name, v).
+func (c *Config) DecodeJSON(name string, v interface{}) error {
+	return c.Group("").DecodeJSON(name, v)
+}
+
+// CertificateE is equal to c.Group("").CertificateE(name).
+func (c *Config) CertificateE(name string) (tls.Certificate, error) {
+	return c.Group("").CertificateE(name)
+}
+
+// CertificateD is equal to c.Group("").CertificateD(name, _default).
+func (c *Config) CertificateD(name string, _default tls.Certificate) tls.Certificate {
+	return c.Group("").CertificateD(name, _default)
+}
+
+// Certificate is equal to c.Group("").Certificate(name).
+func (c *Config) Certificate(name string) tls.Certificate {
+	return c.Group("").Certificate(name)
+}
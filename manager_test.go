@@ -17,8 +17,13 @@ limitations under the License.
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 )
 
 func ExampleConfig_Observe() {
@@ -38,6 +43,39 @@ func ExampleConfig_Observe() {
 	// group=test, name=watchval, value=123
 }
 
+func ExampleConfig_ObserveChange() {
+	conf := NewConfig()
+	conf.RegisterCliOpt("test", Str("watchval", "abc", "test watch value"))
+	conf.ObserveChange(func(gname, name string, old, new interface{}) {
+		fmt.Printf("group=%s, name=%s, old=%v, new=%v\n", gname, name, old, new)
+	})
+
+	conf.Parse() // Start the config
+
+	// Set the option value while the program is running.
+	conf.SetOptValue(0, "test", "watchval", "123")
+
+	// Output:
+	// group=test, name=watchval, old=<nil>, new=abc
+	// group=test, name=watchval, old=abc, new=123
+}
+
+func ExampleConfig_Validate() {
+	conf := NewConfig().MarkRequired("", "name").MarkMutuallyExclusive("", "json", "yaml")
+	conf.RegisterOpt("", Str("name", "", "the name"))
+	conf.RegisterOpt("", Bool("json", false, "output JSON"))
+	conf.RegisterOpt("", Bool("yaml", false, "output YAML"))
+	conf.SetOptValue(0, "", "json", true)
+	conf.SetOptValue(0, "", "yaml", true)
+
+	fmt.Println(conf.Validate())
+
+	// Output:
+	// the config is invalid:
+	//   - missing the required options: name
+	//   - the options json, yaml are mutually exclusive, but set: json, yaml
+}
+
 func ExampleNewEnvVarParser() {
 	// Simulate the environment variable.
 	os.Setenv("TEST_VAR1", "abc")
@@ -291,3 +329,447 @@ func ExampleConfig_RegisterStruct() {
 	// |   |   |--> conn
 	// |   |   |--> maxconn
 }
+
+func TestObserveCancel(t *testing.T) {
+	var calls1, calls2 int
+	conf := NewConfig()
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	cancel1 := conf.Observe(func(gname, name string, value interface{}) { calls1++ })
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add the observer after parsing.
+	cancel2 := conf.Observe(func(gname, name string, value interface{}) { calls2++ })
+	conf.SetOptValue(0, "", "name", "xyz")
+	if calls1 != 2 || calls2 != 1 {
+		t.Errorf("expect calls 2 and 1, but got %d and %d", calls1, calls2)
+	}
+
+	cancel1()
+	cancel1() // Cancel it again
+	conf.SetOptValue(0, "", "name", "123")
+	if calls1 != 2 || calls2 != 2 {
+		t.Errorf("expect calls 2 and 2, but got %d and %d", calls1, calls2)
+	}
+
+	cancel2()
+	conf.SetOptValue(0, "", "name", "456")
+	if calls1 != 2 || calls2 != 2 {
+		t.Errorf("expect calls 2 and 2, but got %d and %d", calls1, calls2)
+	}
+}
+
+type testPoolOpts struct {
+	Min int `default:"1"`
+	Max int `default:"10"`
+}
+
+func (o *testPoolOpts) Validate() error {
+	if o.Min > o.Max {
+		return fmt.Errorf("min '%d' is greater than max '%d'", o.Min, o.Max)
+	}
+	return nil
+}
+
+func TestTxn(t *testing.T) {
+	var opts testPoolOpts
+	var changes int
+	conf := NewConfig()
+	conf.RegisterStruct("pool", &opts)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	conf.Observe(func(group, name string, value interface{}) { changes++ })
+
+	// Setting min alone is invalid, but it's valid together with max.
+	err := conf.Begin().SetOptValue(0, "pool", "min", 20).SetOptValue(0, "pool", "max", 100).Commit()
+	if err != nil {
+		t.Fatal(err)
+	} else if opts.Min != 20 || opts.Max != 100 || conf.Group("pool").Int("min") != 20 {
+		t.Errorf("unexpected options %+v", opts)
+	} else if changes != 2 {
+		t.Errorf("expect 2 changes, but got %d", changes)
+	}
+
+	// Roll back all the changes if the validation fails.
+	txn := conf.Begin().SetOptValue(0, "pool", "max", 50).SetOptValue(0, "pool", "min", 60)
+	if err = txn.Commit(); err == nil {
+		t.Error("expect an error for min > max")
+	} else if opts.Min != 20 || opts.Max != 100 || conf.Group("pool").Int("max") != 100 {
+		t.Errorf("unexpected options %+v", opts)
+	} else if changes != 2 {
+		t.Errorf("expect 2 changes, but got %d", changes)
+	}
+
+	// Fail to parse the value.
+	if err = conf.Begin().SetOptValue(0, "pool", "min", "abc").Commit(); err == nil {
+		t.Error("expect an error for the invalid integer")
+	}
+
+	// The unchanged value is not notified.
+	gen := conf.Generation()
+	if err = conf.Begin().SetOptValue(0, "pool", "min", 20).SetOptValue(0, "pool", "max", 200).Commit(); err != nil {
+		t.Error(err)
+	} else if changes != 3 {
+		t.Errorf("expect 3 changes, but got %d", changes)
+	} else if g := conf.Generation(); g != gen+1 {
+		t.Errorf("expect the generation %d, but got %d", gen+1, g)
+	}
+	if err = conf.Begin().SetOptValue(0, "pool", "min", 20).Commit(); err != nil {
+		t.Error(err)
+	} else if changes != 3 {
+		t.Errorf("expect 3 changes, but got %d", changes)
+	} else if g := conf.Generation(); g != gen+1 {
+		t.Errorf("expect the generation %d, but got %d", gen+1, g)
+	}
+
+	// The option is unregistered before committing.
+	txn = conf.Begin().SetOptValue(0, "pool", "min", 30).SetOptValue(0, "pool", "max", 300)
+	conf.UnregisterOpt("pool", "max")
+	if err = txn.Commit(); err == nil {
+		t.Error("expect an error for the unregistered option")
+	} else if v := conf.Group("pool").Int("min"); v != 20 {
+		t.Errorf("expect min=20, but got %d", v)
+	}
+}
+
+func TestTxnCountOpt(t *testing.T) {
+	var opts testPoolOpts
+	conf := NewConfig()
+	conf.RegisterOpt("", Count("verbose", ""))
+	conf.RegisterStruct("pool", &opts)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SetOptValue(0, "", "verbose", 2)
+	if err := conf.Begin().SetOptValue(10, "", "verbose", 1).Commit(); err != nil {
+		t.Fatal(err)
+	} else if v := conf.Int("verbose"); v != 3 {
+		t.Errorf("expect verbose=3, but got %d", v)
+	}
+
+	// The counts are rolled back, too.
+	if err := conf.Begin().SetOptValue(20, "", "verbose", 5).SetOptValue(0, "pool", "min", 20).Commit(); err == nil {
+		t.Error("expect an error for min > max")
+	}
+	if err := conf.Begin().SetOptValue(10, "", "verbose", 2).Commit(); err != nil {
+		t.Fatal(err)
+	} else if v := conf.Int("verbose"); v != 4 {
+		t.Errorf("expect verbose=4, but got %d", v)
+	}
+}
+
+func TestUnregisterOpt(t *testing.T) {
+	var plugin struct {
+		Addr    string `default:"127.0.0.1"`
+		Timeout int    `default:"3"`
+	}
+
+	var calls int
+	conf := NewConfig()
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	conf.RegisterStruct("plugin.http", &plugin)
+	conf.ObserveOpt("plugin.http", "timeout", func(old, new interface{}) { calls++ })
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !conf.UnregisterOpt("plugin.http", "timeout") {
+		t.Error("expect the option 'timeout' to be unregistered")
+	} else if conf.UnregisterOpt("plugin.http", "timeout") {
+		t.Error("expect the option 'timeout' to have been unregistered")
+	} else if conf.Group("plugin.http").HasOpt("timeout") {
+		t.Error("expect no option 'timeout'")
+	}
+
+	// Register it again, and the field is not bound any more.
+	conf.RegisterOpt("plugin.http", Int("timeout", 5, ""))
+	if calls != 1 {
+		t.Errorf("expect the observer to be called once, but got %d", calls)
+	} else if v := conf.Group("plugin.http").Int("timeout"); v != 5 {
+		t.Errorf("expect the timeout 5, but got %d", v)
+	} else if plugin.Timeout != 3 {
+		t.Errorf("expect the field timeout 3, but got %d", plugin.Timeout)
+	}
+
+	if !conf.RemoveGroup("plugin") {
+		t.Error("expect the group 'plugin' to be removed")
+	} else if conf.HasGroup("plugin") || conf.HasGroup("plugin.http") {
+		t.Error("expect no group 'plugin' and 'plugin.http'")
+	} else if conf.RemoveGroup("plugin") {
+		t.Error("expect the group 'plugin' to have been removed")
+	} else if v := conf.String("name"); v != "abc" {
+		t.Errorf("expect the name 'abc', but got '%s'", v)
+	}
+}
+
+func TestRegisterOptLate(t *testing.T) {
+	os.Setenv("TEST_LATE_PORT", "abc")
+	defer os.Unsetenv("TEST_LATE_PORT")
+
+	conf := NewConfig().AddParser(NewEnvVarParser("test"))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The invalid value does not panic, but the option is still registered.
+	if err := conf.RegisterOptE("late", Int("port", 80, "")); err == nil {
+		t.Error("expect the error of the invalid port")
+	} else if !conf.Group("late").HasOpt("port") {
+		t.Error("expect the option 'port' to be registered")
+	}
+	os.Unsetenv("TEST_LATE_PORT")
+
+	timeout := Int("timeout", 0, "").SetValidators(NewIntegerRangeValidator(1, 10))
+	if err := conf.RegisterOptE("late", timeout); err == nil {
+		t.Error("expect the error of the invalid default timeout")
+	}
+	conf.RegisterOpt("late", Int("retry", -1, "").SetValidators(NewIntegerRangeValidator(0, 3)))
+	if !conf.Group("late").HasOpt("retry") {
+		t.Error("expect the option 'retry' to be registered")
+	}
+
+	var opts struct {
+		Port int `default:"0" validate:"range(1,65535)"`
+	}
+	if b, err := conf.RegisterStructE("late.struct", &opts); err == nil {
+		t.Error("expect the error of the invalid default port")
+	} else if b == nil {
+		t.Error("expect the struct binding")
+	}
+}
+
+func TestRegisterOptConflictsConcurrently(t *testing.T) {
+	conf := NewConfig().SetConflictPolicy(ConflictError)
+	conf.RegisterOpt("", Str("name", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conf.RegisterOpt("", Str("name", "", ""))
+			conf.checkConflicts()
+		}()
+	}
+	wg.Wait()
+
+	if err := conf.checkConflicts(); err == nil {
+		t.Error("expect the conflicts of the option 'name'")
+	}
+}
+
+func TestUnregisterOptPurge(t *testing.T) {
+	conf := NewConfig()
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	conf.RegisterOpt("", Bool("json", false, ""))
+	conf.RegisterOpt("", Bool("yaml", false, ""))
+	conf.RegisterOpt("plugin", Str("addr", "", ""))
+	conf.MarkRequired("", "name").MarkImmutable("", "name").SetOptEnv("", "name", "APP_NAME")
+	conf.MarkMutuallyExclusive("", "name", "json", "yaml")
+	conf.MarkMutuallyExclusive("", "name", "json")
+	conf.SetOptTTL("", "name", time.Millisecond)
+	conf.AliasOpt("", "name", "title")
+	conf.DeprecateOpt("", "label", "name", "")
+	conf.AliasOpt("plugin", "addr", "address")
+	conf.SetOptValue(0, "", "name", "xyz")
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove then re-register the option.
+	conf.UnregisterOpt("", "name")
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	if conf.isOptRequired("", "name") {
+		t.Error("expect the option 'name' not to be required")
+	}
+	if conf.isOptImmutable("", "name") {
+		t.Error("expect the option 'name' not to be immutable")
+	}
+	if _, ok := conf.optEnv("", "name"); ok {
+		t.Error("expect the option 'name' to have no environment variable")
+	}
+	if ttl := conf.optTTL("", "name", 0); ttl != 0 {
+		t.Errorf("expect the option 'name' to have no TTL, but got %s", ttl)
+	}
+	for _, old := range []string{"title", "label"} {
+		if _, ok := conf.getOptRedirect("", old); ok {
+			t.Errorf("expect no redirection from '%s'", old)
+		}
+	}
+	if len(conf.exclusives) != 1 || strings.Join(conf.exclusives[0], ",") != "json,yaml" {
+		t.Errorf("expect the exclusive options [json yaml], but got %v", conf.exclusives)
+	}
+
+	if err := conf.SetOptValue(0, "", "name", "xyz"); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	if v := conf.String("name"); v != "xyz" {
+		t.Errorf("expect the name 'xyz', but got '%s'", v)
+	}
+
+	// Remove then re-register the group.
+	conf.RemoveGroup("plugin")
+	if _, ok := conf.getOptRedirect("plugin", "address"); ok {
+		t.Error("expect no redirection from 'plugin.address'")
+	}
+	conf.RegisterOpt("plugin", Str("addr", ":80", ""))
+	if v := conf.Group("plugin").String("addr"); v != ":80" {
+		t.Errorf("expect the addr ':80', but got '%s'", v)
+	}
+}
+
+func TestMarkImmutable(t *testing.T) {
+	var opts struct {
+		DataDir string `default:"/data" immutable:"true"`
+		Timeout int    `default:"3"`
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &opts)
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	conf.MarkImmutable("", "name")
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conf.SetOptValue(0, "", "datadir", "/tmp"); err == nil {
+		t.Error("expect an error for the immutable option 'datadir'")
+	} else if opts.DataDir != "/data" {
+		t.Errorf("expect the datadir '/data', but got '%s'", opts.DataDir)
+	}
+
+	if err := conf.Begin().SetOptValue(0, "", "name", "xyz").Commit(); err == nil {
+		t.Error("expect an error for the immutable option 'name'")
+	} else if v := conf.String("name"); v != "abc" {
+		t.Errorf("expect the name 'abc', but got '%s'", v)
+	}
+
+	if err := conf.SetOptValue(0, "", "datadir", "/data"); err != nil {
+		t.Errorf("unexpected error for the same value: %s", err)
+	} else if err = conf.SetOptValue(0, "", "timeout", 5); err != nil {
+		t.Error(err)
+	} else if opts.Timeout != 5 {
+		t.Errorf("expect the timeout 5, but got %d", opts.Timeout)
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	var events []ChangeEvent
+	conf := NewConfig()
+	conf.RegisterOpts("", []Opt{Int("min", 1, ""), Int("max", 10, "")})
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	conf.ObserveEvent(func(e ChangeEvent) { events = append(events, e) })
+
+	gen := conf.Generation()
+	if gen == 0 {
+		t.Error("expect the generation to be increased by parsing")
+	}
+
+	conf.SetOptValue(0, "", "min", 2)
+	if g := conf.Generation(); g != gen+1 {
+		t.Errorf("expect the generation %d, but got %d", gen+1, g)
+	} else if len(events) != 1 || events[0].Generation != g || events[0].Old != 1 {
+		t.Errorf("unexpected events: %+v", events)
+	}
+
+	// All the changes of the transaction have the same generation.
+	events = nil
+	if err := conf.Begin().SetOptValue(0, "", "min", 3).SetOptValue(0, "", "max", 30).Commit(); err != nil {
+		t.Fatal(err)
+	} else if g := conf.Generation(); g != gen+2 {
+		t.Errorf("expect the generation %d, but got %d", gen+2, g)
+	} else if len(events) != 2 || events[0].Generation != g || events[1].Generation != g {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	os.Setenv("AUDIT_NAME", "env")
+	defer os.Unsetenv("AUDIT_NAME")
+
+	conf := NewConfig().EnableAudit(2).AddParser(NewEnvVarParser("audit"))
+	conf.RegisterOpt("", Str("name", "abc", ""))
+	conf.RegisterOpt("", SecretStr("password", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SetOptValue(0, "", "name", "xyz")
+	conf.SetOptValue(0, "", "password", "123456")
+
+	// The oldest record of the env parser has been overwritten.
+	records := conf.AuditTrail()
+	if len(records) != 2 {
+		t.Fatalf("expect 2 records, but got %d", len(records))
+	} else if r := records[0]; r.Name != "name" || r.Old != "env" || r.New != "xyz" ||
+		r.Source != "" || r.Time.IsZero() || r.Generation == 0 {
+		t.Errorf("unexpected record: %+v", r)
+	} else if r := records[1]; r.Name != "password" || r.New != SecretMask ||
+		r.Generation != records[0].Generation+1 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	conf.DumpAudit(buf)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Errorf("expect 2 lines, but got '%s'", buf.String())
+	} else if !strings.HasSuffix(lines[0], " [-] name: env -> xyz") {
+		t.Errorf("unexpected line: '%s'", lines[0])
+	}
+}
+
+func TestOptTTL(t *testing.T) {
+	os.Setenv("TTL_FLAG", "true")
+	defer os.Unsetenv("TTL_FLAG")
+
+	events := make(chan ChangeEvent, 4)
+	conf := NewConfig().AddParser(ExpireAfter(NewEnvVarParser("ttl"), time.Millisecond*200))
+	conf.RegisterOpt("", Bool("flag", false, ""))
+	conf.RegisterOpt("", Int("limit", 10, ""))
+	conf.SetOptTTL("", "limit", time.Millisecond*200)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	conf.ObserveEvent(func(e ChangeEvent) { events <- e })
+
+	// Refresh the unchanged value by reloading.
+	conf.SetOptValue(0, "", "limit", 20)
+	<-events
+	time.Sleep(time.Millisecond * 100)
+	if err := conf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if !conf.Bool("flag") {
+		t.Error("expect the flag not to expire")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-events:
+			if e.Source != "expired" {
+				t.Errorf("unexpected event: %+v", e)
+			} else if e.Name == "flag" && e.New != false {
+				t.Errorf("expect the flag to revert to false, but got %v", e.New)
+			} else if e.Name == "limit" && e.New != 10 {
+				t.Errorf("expect the limit to revert to 10, but got %v", e.New)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout to wait for the expiry")
+		}
+	}
+
+	if conf.Bool("flag") || conf.Int("limit") != 10 {
+		t.Errorf("expect the flag false and the limit 10, but got %v and %d",
+			conf.Bool("flag"), conf.Int("limit"))
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+)
+
+// MongoCollection is the interface of the MongoDB collection used by
+// the MongoDB parser.
+//
+// It is a small subset of the collection API, so any MongoDB driver,
+// such as go.mongodb.org/mongo-driver, can be adapted to it easily.
+type MongoCollection interface {
+	// FindOne returns the document whose "_id" is id.
+	//
+	// The values of the document should be the basic Go types, []interface{}
+	// or map[string]interface{}. If the document does not exist, it should
+	// return nil, not an error.
+	FindOne(id string) (map[string]interface{}, error)
+
+	// Watch opens a change stream on the collection, and returns a channel
+	// to receive the "_id" of the inserted, updated or replaced documents.
+	Watch() (<-chan string, error)
+}
+
+type mongoParser struct {
+	prio       int
+	collection MongoCollection
+	watch      bool
+}
+
+// NewMongoParser returns a new parser to read the options from the documents
+// of a MongoDB collection.
+//
+// Each group is stored in a document, the "_id" of which is the full name of
+// the group, such as "DEFAULT" and "db.mysql". The other fields of the document
+// are the option names. The array values are joined by the comma, and the
+// document values are encoded into JSON.
+//
+// If watch is true, it will tail the change stream of the collection, and
+// update the option values by SetOptValue when a group document is changed.
+//
+// Notice: the fields that have not been registered as the options are ignored.
+func NewMongoParser(priority int, collection MongoCollection, watch bool) Parser {
+	if collection == nil {
+		panic(fmt.Errorf("the MongoDB collection must not be nil"))
+	}
+	return mongoParser{prio: priority, collection: collection, watch: watch}
+}
+
+func (p mongoParser) Name() string {
+	return "mongodb"
+}
+
+func (p mongoParser) Priority() int {
+	return p.prio
+}
+
+func (p mongoParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p mongoParser) Post(c *Config) error {
+	return nil
+}
+
+func (p mongoParser) Parse(c *Config) (err error) {
+	for _, group := range c.Groups() {
+		if err = p.loadGroup(c, group.FullName()); err != nil {
+			return
+		}
+	}
+
+	if p.watch && !c.staging {
+		var ids <-chan string
+		if ids, err = p.collection.Watch(); err != nil {
+			return
+		}
+		c.goWatch(func(ctx context.Context) { p.watchGroups(ctx, c, ids) })
+	}
+
+	return
+}
+
+func (p mongoParser) loadGroup(c *Config, group string) error {
+	c.Printf("[%s] Parsing the document '%s'", p.Name(), group)
+	doc, err := p.collection.FindOne(group)
+	if err != nil {
+		return fmt.Errorf("failed to find the document '%s': %s", group, err)
+	}
+
+	for name, value := range doc {
+		if name == "_id" {
+			continue
+		}
+
+		err = c.setOptValueIfExist(p.prio, group, name, jsonValueToString(value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p mongoParser) watchGroups(ctx context.Context, c *Config, ids <-chan string) {
+	for {
+		var id string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case id, ok = <-ids:
+			if !ok {
+				return
+			}
+		}
+
+		if !c.HasGroup(id) {
+			continue
+		}
+
+		if err := p.loadGroup(c, id); err != nil {
+			c.Printf("[%s] Failed to reload the group '%s': %s", p.Name(), id, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+type testMongoCollection struct {
+	lock sync.Mutex
+	docs map[string]map[string]interface{}
+	ids  chan string
+}
+
+func (c *testMongoCollection) FindOne(id string) (map[string]interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.docs[id], nil
+}
+
+func (c *testMongoCollection) Watch() (<-chan string, error) {
+	return c.ids, nil
+}
+
+func (c *testMongoCollection) update(id, name string, value interface{}) {
+	c.lock.Lock()
+	c.docs[id][name] = value
+	c.lock.Unlock()
+	c.ids <- id
+}
+
+func TestMongoParser(t *testing.T) {
+	coll := &testMongoCollection{
+		docs: map[string]map[string]interface{}{
+			"DEFAULT": {"_id": "DEFAULT", "port": int32(80), "unused": "xxx"},
+			"db.mysql": {
+				"_id":   "db.mysql",
+				"hosts": []interface{}{"a", "b"},
+				"attrs": map[string]interface{}{"charset": "utf8"},
+			},
+		},
+		ids: make(chan string),
+	}
+
+	conf := NewConfig().AddParser(NewMongoParser(50, coll, true))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpts("db.mysql", []Opt{Strings("hosts", nil, ""), Str("attrs", "", "")})
+	conf.RegisterOpt("db.redis", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	mysql := conf.Group("db.mysql")
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := mysql.Strings("hosts"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("hosts: expected [a b], got %v", v)
+	}
+	if v := mysql.String("attrs"); v != `{"charset":"utf8"}` {
+		t.Errorf(`attrs: expected '{"charset":"utf8"}', got '%s'`, v)
+	}
+
+	coll.update("DEFAULT", "port", int32(8080))
+	coll.ids <- "unknown"
+	conf.Close()
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testNacosServer struct {
+	lock    sync.Mutex
+	content string
+	notify  chan struct{}
+}
+
+func (s *testNacosServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		if r.PostFormValue("username") != "user" || r.PostFormValue("password") != "pass" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"accessToken": "token", "tokenTtl": 18000}`)
+		return
+	} else if r.URL.Query().Get("accessToken") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/nacos/v1/cs/configs":
+		if q := r.URL.Query(); q.Get("dataId") != "myapp" || q.Get("group") != "DEFAULT_GROUP" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		s.lock.Lock()
+		fmt.Fprint(w, s.content)
+		s.lock.Unlock()
+
+	case "/nacos/v1/cs/configs/listener":
+		if !strings.HasPrefix(r.PostFormValue("Listening-Configs"), "myapp\x02DEFAULT_GROUP\x02") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case <-s.notify:
+			fmt.Fprint(w, "myapp%02DEFAULT_GROUP%01")
+		case <-r.Context().Done():
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testNacosServer) publish(content string) {
+	s.lock.Lock()
+	s.content = content
+	s.lock.Unlock()
+	s.notify <- struct{}{}
+}
+
+func TestNacosParser(t *testing.T) {
+	nacos := &testNacosServer{
+		content: "port = 80\ndb.mysql.conn = root@tcp\nunused = xxx\n",
+		notify:  make(chan struct{}),
+	}
+	server := httptest.NewServer(nacos)
+	defer server.Close()
+
+	updated := make(chan interface{}, 1)
+	addr := strings.Replace(server.URL, "://", "://user:pass@", 1)
+	conf := NewConfig().AddParser(NewNacosParser(50, addr, "myapp", "", "", true))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	conf.Observe(func(group, name string, value interface{}) { updated <- value })
+	nacos.publish("port = 8080\ndb.mysql.conn = root@tcp\n")
+	select {
+	case v := <-updated:
+		if v != 8080 {
+			t.Errorf("port: expected 8080, got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout to wait for the update")
+	}
+
+	// Close cancels the long polling request.
+	done := make(chan struct{})
+	go func() { conf.Close(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("timeout to close the long polling")
+	}
+}
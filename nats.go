@@ -0,0 +1,160 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NatsKVEntry is the entry of the NATS KV bucket sent by the watcher.
+type NatsKVEntry struct {
+	Key     string
+	Value   []byte
+	Deleted bool
+}
+
+// NatsKVClient is the client interface of a NATS KV bucket used by
+// the NATS KV parser.
+//
+// It is a small subset of the KeyValue API of JetStream, so the client,
+// such as github.com/nats-io/nats.go, can be adapted to it easily.
+type NatsKVClient interface {
+	// Keys returns all the keys in the bucket.
+	//
+	// If the bucket has no keys, it should return an empty slice, not an error.
+	Keys() ([]string, error)
+
+	// Get returns the value of the key.
+	Get(key string) ([]byte, error)
+
+	// Watch watches the keys matching the pattern, such as "myapp.>",
+	// and returns a channel to receive the updated entries.
+	//
+	// It should only send the updates, not the initial values.
+	Watch(pattern string) (<-chan NatsKVEntry, error)
+}
+
+type natsKVParser struct {
+	prio   int
+	prefix string
+	client NatsKVClient
+	watch  bool
+}
+
+// NewNatsKVParser returns a new parser to read the options from a NATS KV
+// bucket of JetStream.
+//
+// The key is keyPrefix plus the full name of the option, that's, the group
+// name and the option name joined by the group separator, such as
+// "myapp.db.mysql.conn" for the option "conn" in the group "db.mysql" if
+// keyPrefix is "myapp.". The options in the default group may omit the group
+// name, such as "myapp.opt". keyPrefix is appended with "." if it does not end
+// with it, because the NATS subject tokens are separated by ".".
+//
+// If watch is true, it will watch the keys with keyPrefix, and update
+// the option values by SetOptValue when the keys are put. The deleted keys
+// are ignored.
+//
+// Notice: the keys that have not been registered as the options are ignored.
+func NewNatsKVParser(priority int, client NatsKVClient, keyPrefix string, watch bool) Parser {
+	if client == nil {
+		panic(fmt.Errorf("the NATS KV client must not be nil"))
+	}
+	if keyPrefix != "" && !strings.HasSuffix(keyPrefix, ".") {
+		keyPrefix += "."
+	}
+	return natsKVParser{prio: priority, client: client, prefix: keyPrefix, watch: watch}
+}
+
+func (p natsKVParser) Name() string {
+	return "nats-kv"
+}
+
+func (p natsKVParser) Priority() int {
+	return p.prio
+}
+
+func (p natsKVParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p natsKVParser) Post(c *Config) error {
+	return nil
+}
+
+func (p natsKVParser) Parse(c *Config) error {
+	keys, err := p.client.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, p.prefix) {
+			continue
+		}
+
+		value, err := p.client.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get the key '%s': %s", key, err)
+		}
+
+		if err = p.setValue(c, key, value); err != nil {
+			return err
+		}
+	}
+
+	if p.watch && !c.staging {
+		entries, err := p.client.Watch(p.prefix + ">")
+		if err != nil {
+			return err
+		}
+		c.goWatch(func(ctx context.Context) { p.watchKeys(ctx, c, entries) })
+	}
+
+	return nil
+}
+
+func (p natsKVParser) setValue(c *Config, key string, value []byte) error {
+	c.Printf("[%s] Parsing the key '%s'", p.Name(), key)
+	group, name := c.splitOptKey(strings.TrimPrefix(key, p.prefix))
+	return c.setOptValueIfExist(p.prio, group, name, string(value))
+}
+
+func (p natsKVParser) watchKeys(ctx context.Context, c *Config, entries <-chan NatsKVEntry) {
+	for {
+		var entry NatsKVEntry
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok = <-entries:
+			if !ok {
+				return
+			}
+		}
+
+		if entry.Deleted || !strings.HasPrefix(entry.Key, p.prefix) {
+			continue
+		}
+
+		if err := p.setValue(c, entry.Key, entry.Value); err != nil {
+			c.Printf("[%s] Failed to update the key '%s': %s", p.Name(), entry.Key, err)
+		}
+	}
+}
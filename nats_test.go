@@ -0,0 +1,94 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testNatsKVClient struct {
+	kvs      map[string]string
+	entries  chan NatsKVEntry
+	patterns chan string
+}
+
+func (c testNatsKVClient) Keys() ([]string, error) {
+	keys := make([]string, 0, len(c.kvs))
+	for key := range c.kvs {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (c testNatsKVClient) Get(key string) ([]byte, error) {
+	if value, ok := c.kvs[key]; ok {
+		return []byte(value), nil
+	}
+	return nil, fmt.Errorf("no key '%s'", key)
+}
+
+func (c testNatsKVClient) Watch(pattern string) (<-chan NatsKVEntry, error) {
+	c.patterns <- pattern
+	return c.entries, nil
+}
+
+func TestNatsKVParser(t *testing.T) {
+	for _, prefix := range []string{"myapp", "myapp."} {
+		client := testNatsKVClient{
+			kvs: map[string]string{
+				"myapp.port":          "80",
+				"myapp.db.mysql.conn": "root@tcp",
+				"myappx.port":         "90",
+				"other.port":          "90",
+			},
+			entries:  make(chan NatsKVEntry),
+			patterns: make(chan string, 1),
+		}
+
+		conf := NewConfig().AddParser(NewNatsKVParser(50, client, prefix, true))
+		conf.RegisterOpt("", Int("port", 0, ""))
+		conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+		if err := conf.Parse(); err != nil {
+			t.Fatal(err)
+		}
+
+		if pattern := <-client.patterns; pattern != "myapp.>" {
+			t.Errorf("%s: expect the pattern 'myapp.>', but got '%s'", prefix, pattern)
+		}
+		if v := conf.Int("port"); v != 80 {
+			t.Errorf("%s: port: expected 80, got %d", prefix, v)
+		}
+		if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+			t.Errorf("%s: conn: expected 'root@tcp', got '%s'", prefix, v)
+		}
+
+		client.entries <- NatsKVEntry{Key: "myapp.port", Value: []byte("8080")}
+		client.entries <- NatsKVEntry{Key: "myapp.port", Deleted: true}
+		conf.Close()
+		if v := conf.Int("port"); v != 8080 {
+			t.Errorf("%s: port: expected 8080, got %d", prefix, v)
+		}
+	}
+
+	if p := NewNatsKVParser(50, testNatsKVClient{}, "", false); p.(natsKVParser).prefix != "" {
+		t.Error("expect the empty prefix to be kept")
+	} else if strings.HasSuffix(NewNatsKVParser(50, testNatsKVClient{}, "a.", false).(natsKVParser).prefix, "..") {
+		t.Error("expect the prefix with the trailing dot to be kept")
+	}
+}
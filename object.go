@@ -0,0 +1,174 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ObjectStore is the interface of the object storage, such as AWS S3
+// or Google Cloud Storage, to download the config object.
+//
+// GetObject returns the content and the ETag of the object in the bucket.
+// If etag is not empty and equal to the ETag of the object, the content
+// should be nil to indicate that the object is not modified, such as
+// the conditional request with the header "If-None-Match".
+//
+// For example, it may be implemented by the client of AWS S3 like this,
+//
+//    func (s s3Store) GetObject(bucket, key, etag string) ([]byte, string, error) {
+//        input := &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+//        if etag != "" {
+//            input.IfNoneMatch = aws.String(etag)
+//        }
+//
+//        output, err := s.client.GetObject(input)
+//        if err != nil {
+//            if e, ok := err.(awserr.RequestFailure); ok && e.StatusCode() == 304 {
+//                return nil, etag, nil
+//            }
+//            return nil, "", err
+//        }
+//        defer output.Body.Close()
+//
+//        data, err := ioutil.ReadAll(output.Body)
+//        return data, aws.StringValue(output.ETag), err
+//    }
+type ObjectStore interface {
+	GetObject(bucket, key, etag string) (data []byte, newEtag string, err error)
+}
+
+type objectParser struct {
+	prio     int
+	store    ObjectStore
+	bucket   string
+	key      string
+	decode   Decoder
+	interval time.Duration
+}
+
+// NewObjectParser returns a new parser to download the config object from
+// the object storage, such as AWS S3 or Google Cloud Storage, and decode it
+// by decoder, such as DecodeIni or DecodeProperty.
+//
+// If interval is greater than 0, it will re-fetch the object periodically,
+// and update the option values by SetOptValue when the object is modified,
+// which is detected by the ETag of the object.
+//
+// Notice: the keys that have not been registered as the options are ignored.
+func NewObjectParser(priority int, store ObjectStore, bucket, key string,
+	decoder Decoder, interval time.Duration) Parser {
+	if store == nil || decoder == nil {
+		panic(fmt.Errorf("the object store and the decoder must not be nil"))
+	} else if bucket == "" || key == "" {
+		panic(fmt.Errorf("the bucket and the key must not be empty"))
+	}
+
+	return objectParser{
+		prio:     priority,
+		store:    store,
+		bucket:   bucket,
+		key:      key,
+		decode:   decoder,
+		interval: interval,
+	}
+}
+
+func (p objectParser) Name() string {
+	return "object"
+}
+
+func (p objectParser) Priority() int {
+	return p.prio
+}
+
+func (p objectParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p objectParser) Post(c *Config) error {
+	return nil
+}
+
+func (p objectParser) Parse(c *Config) error {
+	r := &objectRefresher{
+		objectParser: p,
+		conf:         c,
+		values:       make(map[string]map[string]string, 8),
+	}
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	if p.interval > 0 {
+		c.goWatch(r.run)
+	}
+	return nil
+}
+
+type objectRefresher struct {
+	objectParser
+
+	conf   *Config
+	etag   string
+	values map[string]map[string]string
+}
+
+func (r *objectRefresher) load() error {
+	data, etag, err := r.store.GetObject(r.bucket, r.key, r.etag)
+	if err != nil {
+		return fmt.Errorf("failed to get the object '%s/%s': %s", r.bucket, r.key, err)
+	} else if data == nil && r.etag != "" && etag == r.etag {
+		return nil
+	}
+
+	values, err := r.decode(data, r.conf.GetGroupSeparator())
+	if err != nil {
+		return fmt.Errorf("failed to decode the object '%s/%s': %s", r.bucket, r.key, err)
+	}
+
+	r.conf.Printf("[%s] Loading the object '%s/%s', etag=%s", r.Name(), r.bucket, r.key, etag)
+
+	changes := changedValues(r.values, values)
+	if err = r.conf.setOptValues(r.prio, changes, true); err != nil {
+		return err
+	}
+
+	r.etag = etag
+	r.values = values
+	return nil
+}
+
+func (r *objectRefresher) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.load(); err != nil {
+			r.conf.Printf("[%s] Failed to reload: %s", r.Name(), err)
+		}
+	}
+}
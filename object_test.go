@@ -0,0 +1,94 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testObjectStore struct {
+	lock     sync.Mutex
+	data     string
+	version  int
+	modified int // The number of the downloads of the modified object.
+}
+
+func (s *testObjectStore) GetObject(bucket, key, etag string) ([]byte, string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if bucket != "mybucket" || key != "app.ini" {
+		return nil, "", fmt.Errorf("no object '%s/%s'", bucket, key)
+	}
+
+	newEtag := fmt.Sprintf(`"%d"`, s.version)
+	if etag == newEtag {
+		return nil, etag, nil
+	}
+
+	s.modified++
+	return []byte(s.data), newEtag, nil
+}
+
+func (s *testObjectStore) put(data string) {
+	s.lock.Lock()
+	s.data = data
+	s.version++
+	s.lock.Unlock()
+}
+
+func (s *testObjectStore) downloads() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.modified
+}
+
+func TestObjectParser(t *testing.T) {
+	store := &testObjectStore{data: "[DEFAULT]\nport = 80\n[db.mysql]\nconn = root@tcp\n"}
+	conf := NewConfig().AddParser(NewObjectParser(50, store, "mybucket", "app.ini",
+		DecodeIni, time.Millisecond*10))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	// The unmodified object is not downloaded again.
+	time.Sleep(time.Millisecond * 50)
+	if n := store.downloads(); n != 1 {
+		t.Errorf("expect the object to be downloaded once, but got %d", n)
+	}
+
+	store.put("[DEFAULT]\nport = 8080\n[db.mysql]\nconn = root@tcp\n")
+	for i := 0; i < 100 && conf.Int("port") != 8080; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
 	"time"
 )
@@ -74,6 +77,16 @@ const (
 	float64Type
 	durationType
 	timeType
+	countType
+	cidrType
+	sizeType
+	levelType
+	secretType
+	bigIntType
+	decimalType
+	jsonType
+	certType
+	anyType
 
 	stringsType
 	intsType
@@ -83,6 +96,7 @@ const (
 	float64sType
 	durationsType
 	timesType
+	cidrsType
 )
 
 var optTypeMap = map[optType]string{
@@ -103,6 +117,16 @@ var optTypeMap = map[optType]string{
 	float64Type:  "float64",
 	durationType: "time.Duration",
 	timeType:     "time.Time",
+	countType:    "count",
+	cidrType:     "*net.IPNet",
+	sizeType:     "size",
+	levelType:    "level",
+	secretType:   "secret",
+	bigIntType:   "*big.Int",
+	decimalType:  "decimal",
+	jsonType:     "json",
+	certType:     "certificate",
+	anyType:      "interface{}",
 
 	stringsType:   "[]string",
 	intsType:      "[]int",
@@ -112,6 +136,7 @@ var optTypeMap = map[optType]string{
 	float64sType:  "[]float64",
 	durationsType: "[]time.Duration",
 	timesType:     "[]time.Time",
+	cidrsType:     "[]*net.IPNet",
 }
 
 var kind2optType = map[reflect.Kind]optType{
@@ -132,33 +157,56 @@ var kind2optType = map[reflect.Kind]optType{
 }
 
 func getOptType(v reflect.Value) optType {
+	t, ok := lookupOptType(v)
+	if !ok {
+		panic(fmt.Errorf("doesn't support the type %s", v.Type().Name()))
+	}
+	return t
+}
+
+// lookupOptType returns the builtin option type of the value.
+func lookupOptType(v reflect.Value) (optType, bool) {
 	if t, ok := kind2optType[v.Kind()]; ok {
-		return t
+		return t, true
+	} else if v.Kind() == reflect.Interface && v.Type().NumMethod() == 0 {
+		return anyType, true // interface{}
 	}
 
 	switch v.Interface().(type) {
 	case time.Duration:
-		return durationType
+		return durationType, true
 	case time.Time:
-		return timeType
+		return timeType, true
+	case *net.IPNet:
+		return cidrType, true
+	case Secret:
+		return secretType, true
+	case *big.Int:
+		return bigIntType, true
+	case Decimal:
+		return decimalType, true
+	case json.RawMessage:
+		return jsonType, true
 	case []string:
-		return stringsType
+		return stringsType, true
 	case []int:
-		return intsType
+		return intsType, true
 	case []int64:
-		return int64sType
+		return int64sType, true
 	case []uint:
-		return uintsType
+		return uintsType, true
 	case []uint64:
-		return uint64sType
+		return uint64sType, true
 	case []float64:
-		return float64sType
+		return float64sType, true
 	case []time.Duration:
-		return durationsType
+		return durationsType, true
 	case []time.Time:
-		return timesType
+		return timesType, true
+	case []*net.IPNet:
+		return cidrsType, true
 	default:
-		panic(fmt.Errorf("doesn't support the type %s", v.Type().Name()))
+		return noneType, false
 	}
 }
 
@@ -169,6 +217,7 @@ type baseOpt struct {
 	_default interface{}
 
 	_type      optType
+	layouts    []string // The layouts of the time options.
 	validators []Validator
 }
 
@@ -237,7 +286,7 @@ func (o baseOpt) Default() interface{} {
 		return o._default.(bool)
 	case stringType:
 		return o._default.(string)
-	case intType:
+	case intType, countType:
 		return o._default.(int)
 	case int8Type:
 		return o._default.(int8)
@@ -245,7 +294,7 @@ func (o baseOpt) Default() interface{} {
 		return o._default.(int16)
 	case int32Type:
 		return o._default.(int32)
-	case int64Type:
+	case int64Type, sizeType:
 		return o._default.(int64)
 	case uintType:
 		return o._default.(uint)
@@ -265,6 +314,20 @@ func (o baseOpt) Default() interface{} {
 		return o._default.(time.Duration)
 	case timeType:
 		return o._default.(time.Time)
+	case cidrType:
+		return o._default.(*net.IPNet)
+	case levelType:
+		return o._default.(Level)
+	case secretType:
+		return o._default.(Secret)
+	case bigIntType:
+		return new(big.Int).Set(o._default.(*big.Int))
+	case decimalType:
+		return o._default.(Decimal)
+	case jsonType:
+		return o._default.(json.RawMessage)
+	case anyType:
+		return o._default
 	case durationsType:
 		return o._default.([]time.Duration)
 	case timesType:
@@ -281,6 +344,8 @@ func (o baseOpt) Default() interface{} {
 		return o._default.([]uint64)
 	case float64sType:
 		return o._default.([]float64)
+	case cidrsType:
+		return o._default.([]*net.IPNet)
 	default:
 		panic(fmt.Errorf("don't support the type %s", o._type))
 	}
@@ -293,7 +358,7 @@ func (o baseOpt) Zero() interface{} {
 		return false
 	case stringType:
 		return ""
-	case intType:
+	case intType, countType:
 		return int(0)
 	case int8Type:
 		return int8(0)
@@ -301,7 +366,7 @@ func (o baseOpt) Zero() interface{} {
 		return int16(0)
 	case int32Type:
 		return int32(0)
-	case int64Type:
+	case int64Type, sizeType:
 		return int64(0)
 	case uintType:
 		return uint(0)
@@ -321,6 +386,20 @@ func (o baseOpt) Zero() interface{} {
 		return time.Duration(0)
 	case timeType:
 		return time.Time{}
+	case cidrType:
+		return (*net.IPNet)(nil)
+	case levelType:
+		return LevelDebug
+	case secretType:
+		return Secret{}
+	case bigIntType:
+		return new(big.Int)
+	case decimalType:
+		return Decimal{}
+	case jsonType:
+		return json.RawMessage("null")
+	case anyType:
+		return nil
 	case stringsType:
 		return []string{}
 	case intsType:
@@ -337,6 +416,8 @@ func (o baseOpt) Zero() interface{} {
 		return []time.Duration{}
 	case timesType:
 		return []time.Time{}
+	case cidrsType:
+		return []*net.IPNet{}
 	default:
 		panic(fmt.Errorf("don't support the type %s", o._type))
 	}
@@ -344,6 +425,14 @@ func (o baseOpt) Zero() interface{} {
 
 // Parse parses the value of the option to a certain type.
 func (o baseOpt) Parse(data interface{}) (v interface{}, err error) {
+	if len(o.layouts) > 0 {
+		switch o._type {
+		case timeType:
+			return ToTimeWithLayouts(data, o.layouts...)
+		case timesType:
+			return ToTimesWithLayouts(data, o.layouts...)
+		}
+	}
 	return parseOpt(data, o._type)
 }
 
@@ -353,7 +442,7 @@ func parseOpt(data interface{}, _type optType) (v interface{}, err error) {
 		return ToBool(data)
 	case stringType:
 		return ToString(data)
-	case intType, int8Type, int16Type, int32Type, int64Type:
+	case intType, int8Type, int16Type, int32Type, int64Type, countType:
 		v, err = ToInt64(data)
 	case uintType, uint8Type, uint16Type, uint32Type, uint64Type:
 		v, err = ToUint64(data)
@@ -413,6 +502,24 @@ func parseOpt(data interface{}, _type optType) (v interface{}, err error) {
 		return ToDurations(data)
 	case timesType:
 		return ToTimes(time.RFC3339Nano, data)
+	case cidrType:
+		return ToCIDR(data)
+	case sizeType:
+		return ToSize(data)
+	case levelType:
+		return ToLevel(data)
+	case secretType:
+		return ToSecret(data)
+	case bigIntType:
+		return ToBigInt(data)
+	case decimalType:
+		return ToDecimal(data)
+	case jsonType:
+		return ToJSON(data)
+	case anyType:
+		return data, nil // Pass through the raw value.
+	case cidrsType:
+		return ToCIDRs(data)
 	default:
 		err = fmt.Errorf("don't support the type '%s'", _type)
 	}
@@ -425,7 +532,7 @@ func parseOpt(data interface{}, _type optType) (v interface{}, err error) {
 	// case uint64Type:
 	// case int64Type:
 	// case float64Type:
-	case intType:
+	case intType, countType:
 		v = int(v.(int64))
 	case int8Type:
 		v = int8(v.(int64))
@@ -531,6 +638,16 @@ func TimeOpt(short, name string, _default time.Time, help string) ValidatorChain
 	return newBaseOpt(short, name, _default, help, timeType)
 }
 
+// TimeOptWithLayouts is the same as TimeOpt, but the string value is parsed
+// by the layouts in turn, such as "2006-01-02" or UnixLayout for the Unix
+// epoch seconds. The layout time.RFC3339Nano is always tried at last.
+func TimeOptWithLayouts(short, name string, _default time.Time, help string,
+	layouts ...string) ValidatorChainOpt {
+	o := newBaseOpt(short, name, _default, help, timeType)
+	o.layouts = layouts
+	return o
+}
+
 // DurationsOpt return a new []time.Duration option.
 //
 // For the string value, it will use time.ParseDuration to parse it.
@@ -545,9 +662,18 @@ func TimesOpt(short, name string, _default []time.Time, help string) ValidatorCh
 	return newBaseOpt(short, name, _default, help, timesType)
 }
 
+// TimesOptWithLayouts is the same as TimesOpt, but the each string value
+// is parsed by the layouts, see TimeOptWithLayouts.
+func TimesOptWithLayouts(short, name string, _default []time.Time, help string,
+	layouts ...string) ValidatorChainOpt {
+	o := newBaseOpt(short, name, _default, help, timesType)
+	o.layouts = layouts
+	return o
+}
+
 // StringsOpt return a new []string option.
 func StringsOpt(short, name string, _default []string, help string) ValidatorChainOpt {
-	return newBaseOpt(short, name, _default, help, float64sType)
+	return newBaseOpt(short, name, _default, help, stringsType)
 }
 
 // IntsOpt return a new []int option.
@@ -575,6 +701,95 @@ func Float64sOpt(short, name string, _default []float64, help string) ValidatorC
 	return newBaseOpt(short, name, _default, help, float64sType)
 }
 
+// CIDROpt return a new *net.IPNet option, such as "192.168.0.0/16".
+//
+// For the string value, it will use net.ParseCIDR to parse it.
+func CIDROpt(short, name string, _default *net.IPNet, help string) ValidatorChainOpt {
+	if _default == nil {
+		return newBaseOpt(short, name, nil, help, cidrType)
+	}
+	return newBaseOpt(short, name, _default, help, cidrType)
+}
+
+// CIDRsOpt return a new []*net.IPNet option, which is usually used as
+// the allowlist or the blocklist.
+//
+// For the string value, they are separated by the comma and the each value
+// is parsed by net.ParseCIDR.
+func CIDRsOpt(short, name string, _default []*net.IPNet, help string) ValidatorChainOpt {
+	return newBaseOpt(short, name, _default, help, cidrsType)
+}
+
+// BigIntOpt return a new *big.Int option for the integer exceeding int64,
+// such as the chain ID or the quota.
+//
+// For the string value, it is the decimal integer, or the integer with
+// the prefix "0x", "0o" or "0b".
+func BigIntOpt(short, name string, _default *big.Int, help string) ValidatorChainOpt {
+	if _default == nil {
+		return newBaseOpt(short, name, nil, help, bigIntType)
+	}
+	return newBaseOpt(short, name, new(big.Int).Set(_default), help, bigIntType)
+}
+
+// JSONOpt return a new json.RawMessage option, which is used to pass the
+// complex configuration blob, such as the driver-specific options, through
+// one option. Use DecodeJSON of the group to decode it.
+//
+// For the string value, it must be the valid JSON, which is compacted.
+// For other values, such as the map decoded from the config file,
+// they are encoded by json.Marshal.
+func JSONOpt(short, name string, _default json.RawMessage, help string) ValidatorChainOpt {
+	if _default == nil {
+		return newBaseOpt(short, name, nil, help, jsonType)
+	}
+
+	v, err := ToJSON(_default)
+	if err != nil {
+		panic(fmt.Errorf("the default of the option '%s' is invalid: %s", name, err))
+	}
+	return newBaseOpt(short, name, v, help, jsonType)
+}
+
+// SizeOpt return a new size option, the value of which is the int64 byte count.
+//
+// For the string value, it is the human-readable size, such as "512KiB",
+// "2GB" or "1.5M", see ToSize.
+func SizeOpt(short, name string, _default int64, help string) ValidatorChainOpt {
+	return newBaseOpt(short, name, _default, help, sizeType)
+}
+
+// CountOpt return a new count option, the value of which is an int.
+//
+// For the CLI parser, the value increases by one with each occurrence of
+// the option, such as "-v -v -v" for 3, which is usually used as the level
+// of the verbosity. For other parsers, the value is the count set by them.
+// The value of the option is the sum of the counts set by all the sources,
+// such as "-v -v" and the environment variable "VERBOSE=1" for 3, and the
+// source setting it again replaces its last count.
+func CountOpt(short, name string, help string) ValidatorChainOpt {
+	return newBaseOpt(short, name, 0, help, countType)
+}
+
+// isSizeOpt reports whether the option is the size option.
+func isSizeOpt(opt Opt) bool {
+	o, ok := opt.(baseOpt)
+	return ok && o._type == sizeType
+}
+
+// isAnyOpt reports whether the option is the pass-through interface{} option,
+// which is optional since its zero value is nil.
+func isAnyOpt(opt Opt) bool {
+	o, ok := opt.(baseOpt)
+	return ok && o._type == anyType
+}
+
+// isCountOpt reports whether the option is the count option.
+func isCountOpt(opt Opt) bool {
+	o, ok := opt.(baseOpt)
+	return ok && o._type == countType
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 // Bool is equal to BoolOpt("", name, _default, help).
@@ -653,17 +868,38 @@ func Duration(name string, _default time.Duration, help string) ValidatorChainOp
 }
 
 // Time is equal to TimeOpt("", name, _default, help).
+//
+// Deprecated: the argument short is ignored, use NamedTime instead.
 func Time(short, name string, _default time.Time, help string) ValidatorChainOpt {
-	return newBaseOpt("", name, _default, help, timeType)
+	return NamedTime(name, _default, help)
 }
 
 // Durations is equal to DurationsOpt("", name, _default, help).
+//
+// Deprecated: the argument short is ignored, use NamedDurations instead.
 func Durations(short, name string, _default []time.Duration, help string) ValidatorChainOpt {
-	return newBaseOpt("", name, _default, help, durationsType)
+	return NamedDurations(name, _default, help)
 }
 
 // Times is equal to TimesOpt("", name, _default, help).
+//
+// Deprecated: the argument short is ignored, use NamedTimes instead.
 func Times(short, name string, _default []time.Time, help string) ValidatorChainOpt {
+	return NamedTimes(name, _default, help)
+}
+
+// NamedTime is equal to TimeOpt("", name, _default, help).
+func NamedTime(name string, _default time.Time, help string) ValidatorChainOpt {
+	return newBaseOpt("", name, _default, help, timeType)
+}
+
+// NamedDurations is equal to DurationsOpt("", name, _default, help).
+func NamedDurations(name string, _default []time.Duration, help string) ValidatorChainOpt {
+	return newBaseOpt("", name, _default, help, durationsType)
+}
+
+// NamedTimes is equal to TimesOpt("", name, _default, help).
+func NamedTimes(name string, _default []time.Time, help string) ValidatorChainOpt {
 	return newBaseOpt("", name, _default, help, timesType)
 }
 
@@ -696,3 +932,33 @@ func Uint64s(name string, _default []uint64, help string) ValidatorChainOpt {
 func Float64s(name string, _default []float64, help string) ValidatorChainOpt {
 	return newBaseOpt("", name, _default, help, float64sType)
 }
+
+// Count is equal to CountOpt("", name, help).
+func Count(name string, help string) ValidatorChainOpt {
+	return newBaseOpt("", name, 0, help, countType)
+}
+
+// BigInt is equal to BigIntOpt("", name, _default, help).
+func BigInt(name string, _default *big.Int, help string) ValidatorChainOpt {
+	return BigIntOpt("", name, _default, help)
+}
+
+// JSON is equal to JSONOpt("", name, _default, help).
+func JSON(name string, _default json.RawMessage, help string) ValidatorChainOpt {
+	return JSONOpt("", name, _default, help)
+}
+
+// Size is equal to SizeOpt("", name, _default, help).
+func Size(name string, _default int64, help string) ValidatorChainOpt {
+	return newBaseOpt("", name, _default, help, sizeType)
+}
+
+// CIDR is equal to CIDROpt("", name, _default, help).
+func CIDR(name string, _default *net.IPNet, help string) ValidatorChainOpt {
+	return CIDROpt("", name, _default, help)
+}
+
+// CIDRs is equal to CIDRsOpt("", name, _default, help).
+func CIDRs(name string, _default []*net.IPNet, help string) ValidatorChainOpt {
+	return CIDRsOpt("", name, _default, help)
+}
@@ -1,6 +1,24 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -48,4 +66,1211 @@ func TestOpt(t *testing.T) {
 	if len(newBaseOpt("", "float64s", nil, "", float64sType).Zero().([]float64)) != 0 {
 		t.Fail()
 	}
+	if len(newBaseOpt("", "cidrs", nil, "", cidrsType).Zero().([]*net.IPNet)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestOptConstructors(t *testing.T) {
+	if _, ok := StringsOpt("s", "strings", nil, "").Zero().([]string); !ok {
+		t.Error("StringsOpt is not the []string option")
+	}
+
+	opts := []Opt{
+		DurationOpt("d", "duration", 0, ""),
+		TimeOpt("t", "time", time.Time{}, ""),
+		DurationsOpt("D", "durations", nil, ""),
+		TimesOpt("T", "times", nil, ""),
+	}
+	for _, opt := range opts {
+		if opt.Short() == "" {
+			t.Errorf("the option '%s' has no short name", opt.Name())
+		}
+	}
+
+	if v, err := NamedDurations("durations", nil, "").Parse("1s,2m"); err != nil {
+		t.Error(err)
+	} else if ds := v.([]time.Duration); len(ds) != 2 || ds[1] != 2*time.Minute {
+		t.Errorf("unexpected durations %v", ds)
+	}
+
+	// The deprecated constructors keep the short argument, which is ignored.
+	opts = []Opt{
+		Time("t", "time", time.Time{}, ""),
+		Durations("D", "durations", nil, ""),
+		Times("T", "times", nil, ""),
+		NamedTime("time", time.Time{}, ""),
+		NamedTimes("times", nil, ""),
+	}
+	for _, opt := range opts {
+		if opt.Short() != "" {
+			t.Errorf("the option '%s' has the short name '%s'", opt.Name(), opt.Short())
+		}
+	}
+}
+
+func TestDurationsAndTimesOpt(t *testing.T) {
+	durations := DurationsOpt("", "backoff", nil, "")
+	for _, data := range []interface{}{"1s, 2m", []string{"1s", "2m"}, []interface{}{"1s", 120000000000}} {
+		if v, err := durations.Parse(data); err != nil {
+			t.Error(err)
+		} else if ds := v.([]time.Duration); len(ds) != 2 || ds[0] != time.Second || ds[1] != 2*time.Minute {
+			t.Errorf("unexpected durations %v", ds)
+		}
+	}
+
+	times := TimesOpt("", "windows", nil, "")
+	for _, data := range []interface{}{"2020-01-01T00:00:00Z,2020-01-02T00:00:00Z",
+		[]interface{}{"2020-01-01T00:00:00Z", "2020-01-02T00:00:00Z"}} {
+		if v, err := times.Parse(data); err != nil {
+			t.Error(err)
+		} else if ts := v.([]time.Time); len(ts) != 2 || ts[1].Day() != 2 {
+			t.Errorf("unexpected times %v", ts)
+		}
+	}
+}
+
+func TestStructValidateTag(t *testing.T) {
+	var s struct {
+		Port  int     `validate:"range(1,65535)" default:"80"`
+		Ratio float64 `validate:"range(0,1)" default:"0.5"`
+		Mode  string  `validate:"notempty,oneof(debug,release)" default:"debug"`
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &s)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{"port": "0", "ratio": "1.5", "mode": ""} {
+		if err := conf.SetOptValue(0, "", name, value); err == nil {
+			t.Errorf("expect an error for the option '%s'", name)
+		}
+	}
+	if err := conf.SetOptValue(0, "", "mode", "release"); err != nil || s.Mode != "release" {
+		t.Errorf("unexpected mode '%s': %v", s.Mode, err)
+	}
+}
+
+func TestStructTimeLayoutTag(t *testing.T) {
+	var s struct {
+		Start time.Time   `layout:"2006-01-02|unix" default:"2020-01-02"`
+		Dates []time.Time `layout:"2006-01-02"`
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &s)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if s.Start.Year() != 2020 || s.Start.Day() != 2 {
+		t.Errorf("unexpected start time %s", s.Start)
+	}
+
+	if err := conf.SetOptValue(0, "", "start", "1577836800"); err != nil {
+		t.Error(err)
+	} else if !s.Start.Equal(time.Unix(1577836800, 0)) {
+		t.Errorf("unexpected start time %s", s.Start)
+	}
+
+	if err := conf.SetOptValue(0, "", "dates", "2020-01-01,2020-02-01"); err != nil {
+		t.Error(err)
+	} else if len(s.Dates) != 2 || s.Dates[1].Month() != time.February {
+		t.Errorf("unexpected dates %v", s.Dates)
+	}
+
+	if err := conf.SetOptValue(0, "", "start", "01/02/2020"); err == nil {
+		t.Error("expect an error for the unmatched layout")
+	}
+}
+
+type testColor string
+
+func (c *testColor) UnmarshalText(text []byte) error {
+	switch s := strings.ToLower(string(text)); s {
+	case "red", "green", "blue":
+		*c = testColor(s)
+		return nil
+	default:
+		return fmt.Errorf("unknown color '%s'", text)
+	}
+}
+
+type testPoint struct{ x, y int }
+
+func (p *testPoint) UnmarshalText(text []byte) (err error) {
+	_, err = fmt.Sscanf(string(text), "%d,%d", &p.x, &p.y)
+	return
+}
+
+func TestStructTextUnmarshalerField(t *testing.T) {
+	var s struct {
+		Color testColor `default:"Red"`
+		Point testPoint `default:"1,2"`
+		Addr  net.IP    `default:"127.0.0.1"`
+		Peer  *testPoint
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &s)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Color != "red" || s.Point != (testPoint{1, 2}) || !s.Addr.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("unexpected struct %+v", s)
+	}
+
+	if err := conf.SetOptValue(0, "", "peer", "3,4"); err != nil {
+		t.Error(err)
+	} else if s.Peer == nil || *s.Peer != (testPoint{3, 4}) {
+		t.Errorf("unexpected peer %v", s.Peer)
+	}
+
+	if err := conf.SetOptValue(0, "", "color", "black"); err == nil {
+		t.Error("expect an error for the unknown color")
+	}
+}
+
+func TestStructConsolidatedTag(t *testing.T) {
+	var s struct {
+		Port   int    `config:"port,short=p,default=80,help=The port, such as 80"`
+		Format string `config:",default=json,choices=json,yaml,group=log"`
+		Addr   string `config:"addr,default=0.0.0.0" default:"127.0.0.1"`
+		Ignore string `config:"-"`
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &s)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opt := range conf.Group("").AllOpts() {
+		if opt.Name() == "port" && (opt.Short() != "p" || opt.Help() != "The port, such as 80") {
+			t.Errorf("unexpected short '%s' and help '%s'", opt.Short(), opt.Help())
+		}
+	}
+
+	if s.Port != 80 || s.Format != "json" || s.Addr != "127.0.0.1" {
+		t.Errorf("unexpected struct: %+v", s)
+	} else if conf.Group("").HasOpt("ignore") {
+		t.Error("expect the field Ignore to be ignored")
+	} else if err := conf.SetOptValue(0, "log", "format", "xml"); err == nil {
+		t.Error("expect an error for the invalid choice")
+	}
+}
+
+func TestConfigUnmarshal(t *testing.T) {
+	conf := NewConfig()
+	conf.RegisterOpts("", []Opt{Str("addr", "127.0.0.1", ""), Int("max_conns", 10, "")})
+	conf.RegisterOpts("log", []Opt{Str("level", "info", ""), Strings("outputs", []string{"stdout"}, "")})
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	type Common struct {
+		Addr string
+	}
+
+	var opts struct {
+		Common
+		MaxConns int64
+		Timeout  int // No option
+		Log      *struct {
+			Level   string
+			Outputs []string
+		}
+	}
+
+	if err := conf.Unmarshal(&opts); err != nil {
+		t.Fatal(err)
+	} else if opts.Addr != "127.0.0.1" || opts.MaxConns != 10 || opts.Timeout != 0 {
+		t.Errorf("unexpected struct: %+v", opts)
+	} else if opts.Log.Level != "info" || len(opts.Log.Outputs) != 1 || opts.Log.Outputs[0] != "stdout" {
+		t.Errorf("unexpected log struct: %+v", *opts.Log)
+	}
+
+	var invalid struct {
+		Addr int
+	}
+	if err := conf.Unmarshal(&invalid); err == nil {
+		t.Error("expect an error for the string to int")
+	}
+}
+
+func TestRegisterStructE(t *testing.T) {
+	var s struct {
+		Port    int    `default:"abc"`
+		Addr    string `validate:"unknown"`
+		Chan    chan int
+		Enabled bool `cli:"maybe"`
+		Sub     struct {
+			Level int `default:"x"`
+		}
+		Valid string
+	}
+
+	conf := NewConfig()
+	_, err := conf.RegisterStructE("", &s)
+	if err == nil {
+		t.Fatal("expect an error for the invalid struct")
+	}
+
+	se, ok := err.(StructError)
+	if !ok {
+		t.Fatalf("expect StructError, but got %T", err)
+	} else if len(se.Errors) != 5 {
+		t.Fatalf("expect 5 errors, but got %d: %s", len(se.Errors), err)
+	}
+
+	for _, field := range []string{"Port", "Addr", "Chan", "Enabled", "Sub.Level"} {
+		if !strings.Contains(err.Error(), "the field "+field+":") {
+			t.Errorf("the error does not contain the field '%s': %s", field, err)
+		}
+	}
+
+	if conf.HasGroup("") && conf.Group("").HasOpt("valid") {
+		t.Error("no option should be registered for the invalid struct")
+	}
+	if _, err = conf.RegisterStructE("bad.group", &s); err == nil {
+		t.Error("expect an error for the invalid struct")
+	} else if conf.HasGroup("bad") || conf.HasGroup("bad.group") {
+		t.Error("no group should be created for the invalid struct")
+	}
+
+	var ok1, ok2 struct{ Name string }
+	if _, err := conf.RegisterStructE("", &ok1); err != nil {
+		t.Error(err)
+	} else if _, err := conf.RegisterStructE("", &ok2); err == nil {
+		t.Error("expect an error for the duplicate option")
+	}
+}
+
+func TestOptsFromStruct(t *testing.T) {
+	var s struct {
+		Addr string `default:":80" cli:"true"`
+		DB   struct {
+			URL string
+		}
+	}
+
+	opts, err := OptsFromStruct(&s)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(opts) != 2 {
+		t.Fatalf("expect 2 options, but got %d", len(opts))
+	}
+
+	if o := opts[0]; o.Group != "" || o.Opt.Name() != "addr" || !o.Cli || o.Opt.Default() != ":80" {
+		t.Errorf("unexpected option %+v", o)
+	}
+	if o := opts[1]; o.Group != "db" || o.Opt.Name() != "url" || o.Cli {
+		t.Errorf("unexpected option %+v", o)
+	}
+
+	for _, conf := range []*Config{NewConfig(), NewConfig()} {
+		for _, o := range opts {
+			conf.RegisterOpt(o.Group, o.Opt)
+		}
+		if err := conf.Parse(); err != nil {
+			t.Error(err)
+		} else if v := conf.String("addr"); v != ":80" {
+			t.Errorf("expect ':80', but got '%s'", v)
+		}
+	}
+}
+
+func TestStructPassThroughField(t *testing.T) {
+	var s struct {
+		Plugin json.RawMessage
+		Extra  interface{}
+	}
+
+	conf := NewConfig()
+	conf.RegisterStruct("", &s)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	section := map[string]interface{}{"name": "auth", "retries": 3}
+	if err := conf.SetOptValue(0, "", "plugin", section); err != nil {
+		t.Fatal(err)
+	} else if string(s.Plugin) != `{"name":"auth","retries":3}` {
+		t.Errorf("unexpected plugin '%s'", s.Plugin)
+	}
+
+	if err := conf.SetOptValue(0, "", "extra", section); err != nil {
+		t.Fatal(err)
+	} else if v, ok := s.Extra.(map[string]interface{}); !ok || v["name"] != "auth" {
+		t.Errorf("unexpected extra %v", s.Extra)
+	}
+
+	if err := conf.SetOptValue(0, "", "extra", nil); err != nil {
+		t.Fatal(err)
+	} else if s.Extra != nil {
+		t.Errorf("expect nil, but got %v", s.Extra)
+	}
+}
+
+func TestStructBindingSwap(t *testing.T) {
+	type Opts struct {
+		Addr string `default:":80"`
+		DB   struct {
+			URL string
+		}
+	}
+
+	var lock sync.RWMutex
+	var old Opts
+	conf := NewConfig().SetStructLocker(&lock)
+	binding := conf.RegisterStruct("", &old)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if err := conf.SetOptValue(0, "db", "url", "mysql://db1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var _new Opts
+	if v, err := binding.Swap(&_new); err != nil {
+		t.Fatal(err)
+	} else if v != &old || binding.Struct() != &_new {
+		t.Error("unexpected swapped struct")
+	} else if _new.Addr != ":80" || _new.DB.URL != "mysql://db1" {
+		t.Errorf("unexpected new struct %+v", _new)
+	}
+
+	if err := conf.SetOptValue(0, "db", "url", "mysql://db2"); err != nil {
+		t.Fatal(err)
+	} else if _new.DB.URL != "mysql://db2" || old.DB.URL != "mysql://db1" {
+		t.Errorf("unexpected struct: new=%+v, old=%+v", _new, old)
+	}
+
+	if _, err := binding.Swap(new(struct{ Addr string })); err == nil {
+		t.Error("expect an error for the different struct type")
+	}
+}
+
+func TestBindVar(t *testing.T) {
+	var dsn string
+	var timeout time.Duration
+	conf := NewConfig()
+	conf.RegisterOpt("db", Str("dsn", "mysql://localhost", ""))
+	conf.RegisterOpt("db", Duration("timeout", time.Second, ""))
+	conf.BindVar(&dsn, "db", "dsn").BindVar(&timeout, "db", "timeout")
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if dsn != "mysql://localhost" || timeout != time.Second {
+		t.Errorf("unexpected dsn '%s' and timeout '%s'", dsn, timeout)
+	}
+
+	if err := conf.SetOptValue(0, "db", "timeout", "3s"); err != nil {
+		t.Fatal(err)
+	} else if timeout != time.Second*3 {
+		t.Errorf("expect '3s', but got '%s'", timeout)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the mismatched type")
+			}
+		}()
+		var port int
+		conf.BindVar(&port, "db", "dsn")
+	}()
+
+	// Bind the variable concurrently with the option registered late.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			conf.RegisterOpt("db", Int(fmt.Sprintf("port%d", i), 3306, ""))
+		}
+	}()
+	var dsn2 string
+	for i := 0; i < 10; i++ {
+		conf.BindVar(&dsn2, "db", "dsn")
+	}
+	<-done
+}
+
+type testRangeOpts struct {
+	Min int `default:"1"`
+	Max int `default:"10"`
+}
+
+func (o *testRangeOpts) Validate() error {
+	if o.Min > o.Max {
+		return fmt.Errorf("min '%d' is greater than max '%d'", o.Min, o.Max)
+	}
+	return nil
+}
+
+func TestStructValidator(t *testing.T) {
+	var opts testRangeOpts
+	conf := NewConfig()
+	conf.AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+	conf.RegisterCliStruct("", &opts)
+	if err := conf.Parse("--min", "20"); err == nil {
+		t.Error("expect an error for min > max")
+	} else if !strings.Contains(err.Error(), "min '20' is greater than max '10'") {
+		t.Error(err)
+	}
+
+	opts = testRangeOpts{}
+	conf = NewConfig()
+	conf.AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+	conf.RegisterCliStruct("", &opts)
+	if err := conf.Parse("--min", "5"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSizeOpt(t *testing.T) {
+	opt := SizeOpt("s", "size", 1024, "")
+	if v := opt.Default(); v != int64(1024) {
+		t.Errorf("expected the default 1024, got %v", v)
+	} else if v := opt.Zero(); v != int64(0) {
+		t.Errorf("expected the zero 0, got %v", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect int64
+		err    bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"1K", 1000, false},
+		{"1kb", 1000, false},
+		{"2GB", 2000000000, false},
+		{"1.5M", 1500000, false},
+		{"512KiB", 512 << 10, false},
+		{"1ki", 1 << 10, false},
+		{"1.5GiB", 3 << 29, false},
+		{" 2 MiB ", 2 << 20, false},
+		{"1PiB", 1 << 50, false},
+		{4096, 4096, false},
+		{int64(1) << 40, 1 << 40, false},
+		{"", 0, true},
+		{"KiB", 0, true},
+		{"1XB", 0, true},
+		{"1.2.3K", 0, true},
+		{"-1K", 0, true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if v != c.expect {
+			t.Errorf("%#v: expected %d, got %v", c.input, c.expect, v)
+		}
+	}
+
+	conf := NewConfig()
+	conf.RegisterOpt("", opt)
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if v := conf.Size("size"); v != 1024 {
+		t.Errorf("expected the size 1024, got %d", v)
+	} else if err = conf.SetOptValue(0, "", "size", "4KiB"); err != nil {
+		t.Error(err)
+	} else if v := conf.Size("size"); v != 4096 {
+		t.Errorf("expected the size 4096, got %d", v)
+	}
+}
+
+func TestPathOpt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "a.conf")
+	if err = ioutil.WriteFile(file, []byte("a=1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.conf")
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", home)
+
+	if opt := File("file", "/etc/app.conf", "", PathExist); opt.Default() != "/etc/app.conf" {
+		t.Errorf("expected the default '/etc/app.conf', got %v", opt.Default())
+	} else if opt.Zero() != "" {
+		t.Errorf("expected the zero '', got %v", opt.Zero())
+	}
+
+	cases := []struct {
+		name   string
+		opt    Opt
+		input  string
+		expect string
+		err    string
+	}{
+		{"no check", File("file", "", "", 0), "~/x", "~/x", ""},
+		{"empty not checked", File("file", "", "", PathExist), "", "", ""},
+		{"expand home", File("file", "", "", PathExpand), "~/a.conf", file, ""},
+		{"expand home only", File("file", "", "", PathExpand), "~", dir, ""},
+		{"existing file", File("file", "", "", PathExist|PathReadable|PathWritable), file, file, ""},
+		{"missing file", File("file", "", "", PathExist), missing, "", "does not exist"},
+		{"missing readable file", File("file", "", "", PathReadable), missing, "", "does not exist"},
+		{"missing writable file", File("file", "", "", PathWritable), missing, missing, ""},
+		{"missing parent", File("file", "", "", PathWritable), filepath.Join(missing, "x"), "", "is not writable"},
+		{"file not directory", Dir("dir", "", "", PathExist), file, "", "is not a directory"},
+		{"directory not file", File("file", "", "", PathExist), dir, "", "is not a file"},
+		{"writable directory", Dir("dir", "", "", PathExist|PathWritable), dir, dir, ""},
+	}
+
+	for _, c := range cases {
+		v, err := c.opt.Parse(c.input)
+		if c.err != "" {
+			if err == nil {
+				t.Errorf("%s: expected the error '%s', got %v", c.name, c.err, v)
+			} else if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected the error '%s', got '%s'", c.name, c.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+		} else if v != c.expect {
+			t.Errorf("%s: expected '%s', got '%v'", c.name, c.expect, v)
+		}
+	}
+}
+
+func TestEnumOpt(t *testing.T) {
+	choices := []string{"json", "yaml", "TOML"}
+	if opt := Enum("format", choices, "Yaml", ""); opt.Default() != "yaml" {
+		t.Errorf("expected the default 'yaml', got %v", opt.Default())
+	} else if opt := Enum("format", choices, "", ""); opt.Default() != nil {
+		t.Errorf("expected no default, got %v", opt.Default())
+	} else if opt.Zero() != "" {
+		t.Errorf("expected the zero '', got %v", opt.Zero())
+	}
+
+	opt := EnumOpt("f", "format", choices, "", "")
+	cases := []struct {
+		input  interface{}
+		expect string
+		err    bool
+	}{
+		{"json", "json", false},
+		{"JSON", "json", false},
+		{" yaml ", "yaml", false},
+		{"toml", "TOML", false},
+		{[]byte("Json"), "json", false},
+		{"", "", true},
+		{"xml", "", true},
+		{"js", "", true},
+		{1, "", true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if v != c.expect {
+			t.Errorf("%#v: expected '%s', got '%v'", c.input, c.expect, v)
+		}
+	}
+
+	for _, f := range []func(){
+		func() { Enum("format", nil, "", "") },
+		func() { Enum("format", choices, "xml", "") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expect a panic for the invalid enum option")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestLogLevelOpt(t *testing.T) {
+	opt := LogLevelOpt("l", "level", LevelWarn, "")
+	if v := opt.Default(); v != LevelWarn {
+		t.Errorf("expected the default warn, got %v", v)
+	} else if v := opt.Zero(); v != LevelDebug {
+		t.Errorf("expected the zero debug, got %v", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect Level
+		err    bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{" warn ", LevelWarn, false},
+		{"Warning", LevelWarn, false},
+		{"error", LevelError, false},
+		{"fatal", LevelFatal, false},
+		{"0", LevelDebug, false},
+		{"4", LevelFatal, false},
+		{[]byte("info"), LevelInfo, false},
+		{LevelError, LevelError, false},
+		{3, LevelError, false},
+		{"", 0, true},
+		{"trace", 0, true},
+		{"5", 0, true},
+		{-1, 0, true},
+		{5, 0, true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if v != c.expect {
+			t.Errorf("%#v: expected %s, got %v", c.input, c.expect, v)
+		}
+	}
+
+	if s := Level(5).String(); s != "Level(5)" {
+		t.Errorf("expected 'Level(5)', got '%s'", s)
+	}
+}
+
+func TestSecretOpt(t *testing.T) {
+	opt := SecretOpt("p", "password", "default", "")
+	if v := opt.Default(); v != NewSecret("default") {
+		t.Errorf("expected the default secret, got %#v", v)
+	} else if v := opt.Zero(); v != NewSecret("") {
+		t.Errorf("expected the empty secret, got %#v", v)
+	}
+
+	secret := NewSecret("abc")
+	cases := []struct {
+		input  interface{}
+		expect string
+		err    bool
+	}{
+		{"abc", "abc", false},
+		{"", "", false},
+		{[]byte("abc"), "abc", false},
+		{secret, "abc", false},
+		{&secret, "abc", false},
+		{123, "123", false},
+		{nil, "", true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%T: expected an error, got %#v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%T: %s", c.input, err)
+		} else if s, ok := v.(Secret); !ok || s.Unmask() != c.expect {
+			t.Errorf("%T: expected the secret '%s', got %#v", c.input, c.expect, v)
+		}
+	}
+
+	// The value is masked when printed or marshaled.
+	data, _ := json.Marshal(map[string]Secret{"pass": secret, "empty": {}})
+	masks := []struct {
+		result string
+		expect string
+	}{
+		{fmt.Sprintf("%v", secret), SecretMask},
+		{fmt.Sprintf("%s", NewSecret("")), ""},
+		{fmt.Sprintf("%#v", secret), `config.Secret("*****")`},
+		{string(data), `{"empty":"","pass":"*****"}`},
+	}
+	for _, m := range masks {
+		if m.result != m.expect {
+			t.Errorf("expected the masked '%s', got '%s'", m.expect, m.result)
+		}
+	}
+}
+
+func TestBigIntOpt(t *testing.T) {
+	_default, _ := new(big.Int).SetString("18446744073709551616", 10) // 1<<64
+	opt := BigIntOpt("b", "bigint", _default, "")
+	if v := opt.Default().(*big.Int); v.Cmp(_default) != 0 {
+		t.Errorf("expected the default %s, got %s", _default, v)
+	} else if v.SetInt64(1); opt.Default().(*big.Int).Cmp(_default) != 0 {
+		t.Error("the default is modified by the returned value")
+	} else if v := opt.Zero().(*big.Int); v.Sign() != 0 {
+		t.Errorf("expected the zero 0, got %s", v)
+	} else if v := BigInt("bigint", nil, "").Default(); v != nil {
+		t.Errorf("expected no default, got %v", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect string
+		err    bool
+	}{
+		{"123", "123", false},
+		{" -123 ", "-123", false},
+		{"18446744073709551616", "18446744073709551616", false},
+		{"1_000_000", "1000000", false},
+		{"0x10", "16", false},
+		{"0o10", "8", false},
+		{"0b10", "2", false},
+		{[]byte("42"), "42", false},
+		{_default, "18446744073709551616", false},
+		{*big.NewInt(7), "7", false},
+		{uint64(1) << 63, "9223372036854775808", false},
+		{int64(-5), "-5", false},
+		{float64(1e20), "100000000000000000000", false},
+		{"", "", true},
+		{"12a", "", true},
+		{"1.5", "", true},
+		{1.5, "", true},
+		{(*big.Int)(nil), "", true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if s := v.(*big.Int).String(); s != c.expect {
+			t.Errorf("%#v: expected %s, got %s", c.input, c.expect, s)
+		}
+	}
+}
+
+func TestDecimalOpt(t *testing.T) {
+	opt := DecimalOpt("d", "price", NewDecimal(1999, 2), "")
+	if v := opt.Default().(Decimal); v.String() != "19.99" {
+		t.Errorf("expected the default 19.99, got %s", v)
+	} else if v := opt.Zero().(Decimal); v.String() != "0" {
+		t.Errorf("expected the zero 0, got %s", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect string
+		scale  int
+		err    bool
+	}{
+		{"123.45", "123.45", 2, false},
+		{" -0.5 ", "-0.5", 1, false},
+		{"+1.50", "1.50", 2, false},
+		{"-.05", "-0.05", 2, false},
+		{"100", "100", 0, false},
+		{"1.2e3", "1200", 0, false},
+		{"1.2345E2", "123.45", 2, false},
+		{"12e-3", "0.012", 3, false},
+		{"0.1000000000000000000000000001", "0.1000000000000000000000000001", 28, false},
+		{[]byte("9.9"), "9.9", 1, false},
+		{NewDecimal(-5, -2), "-500", 0, false},
+		{float64(0.1), "0.1", 1, false},
+		{float32(2.5), "2.5", 1, false},
+		{uint64(1) << 63, "9223372036854775808", 0, false},
+		{int64(-7), "-7", 0, false},
+		{"", "", 0, true},
+		{"-", "", 0, true},
+		{"1.2.3", "", 0, true},
+		{"1-2", "", 0, true},
+		{"1e", "", 0, true},
+		{"1e1.5", "", 0, true},
+		{"abc", "", 0, true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if d := v.(Decimal); d.String() != c.expect || d.Scale() != c.scale {
+			t.Errorf("%#v: expected %s with the scale %d, got %s with the scale %d",
+				c.input, c.expect, c.scale, d, d.Scale())
+		}
+	}
+
+	if NewDecimal(150, 2).Cmp(NewDecimal(15, 1)) != 0 {
+		t.Error("expected 1.50 == 1.5")
+	}
+}
+
+func TestCustomOpt(t *testing.T) {
+	opt := CustomOpt("c", "color", new(testColor), "Blue", "")
+	if v := opt.Default(); v != testColor("blue") {
+		t.Errorf("expected the default blue, got %v", v)
+	} else if v := opt.Zero(); v != testColor("") {
+		t.Errorf("expected the zero '', got %v", v)
+	} else if v := Custom("color", new(testColor), "", "").Default(); v != nil {
+		t.Errorf("expected no default, got %v", v)
+	}
+
+	cases := []struct {
+		opt    Opt
+		input  interface{}
+		expect interface{}
+		err    bool
+	}{
+		{opt, "red", testColor("red"), false},
+		{opt, "GREEN", testColor("green"), false},
+		{opt, []byte("Blue"), testColor("blue"), false},
+		{opt, testColor("any"), testColor("any"), false},
+		{opt, "black", nil, true},
+		{opt, "", nil, true},
+		{opt, nil, nil, true},
+		{Custom("point", new(testPoint), "", ""), "1,2", testPoint{1, 2}, false},
+		{Custom("point", new(testPoint), "", ""), testPoint{3, 4}, testPoint{3, 4}, false},
+		{Custom("point", new(testPoint), "", ""), "1;2", nil, true},
+		{Custom("ip", new(net.IP), "", ""), "127.0.0.1", net.IPv4(127, 0, 0, 1), false},
+		{Custom("ip", new(net.IP), "", ""), "localhost", nil, true},
+	}
+
+	for _, c := range cases {
+		v, err := c.opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s %#v: expected an error, got %v", c.opt.Name(), c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%s %#v: %s", c.opt.Name(), c.input, err)
+		} else if fmt.Sprint(v) != fmt.Sprint(c.expect) {
+			t.Errorf("%s %#v: expected %v, got %v", c.opt.Name(), c.input, c.expect, v)
+		}
+	}
+
+	for _, f := range []func(){
+		func() { Custom("color", nil, "", "") },
+		func() { Custom("color", new(testColor), "black", "") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expect a panic for the invalid custom option")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+type testFlagList []string
+
+func (l *testFlagList) String() string { return strings.Join(*l, ",") }
+func (l *testFlagList) Set(s string) error {
+	if s == "" {
+		return fmt.Errorf("empty value")
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+func TestFlagValueOpt(t *testing.T) {
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Int("num", 3, "")
+	fset.Bool("bool", false, "")
+	num := fset.Lookup("num").Value
+
+	opt := FlagValueOpt("n", "num", num, "")
+	if v := opt.Default(); v != 3 {
+		t.Errorf("expected the default 3, got %#v", v)
+	} else if v := opt.Zero(); v != 0 {
+		t.Errorf("expected the zero 0, got %#v", v)
+	}
+
+	list := new(testFlagList)
+	cases := []struct {
+		opt    Opt
+		input  interface{}
+		expect interface{}
+		err    bool
+	}{
+		{opt, "abc", nil, true},
+		{opt, "", nil, true},
+		{opt, "10", 10, false},
+		{opt, 20, 20, false},
+		{opt, []byte("30"), 30, false},
+		{FlagValue("bool", fset.Lookup("bool").Value, ""), "true", true, false},
+		{FlagValue("bool", fset.Lookup("bool").Value, ""), "yes", nil, true},
+		{FlagValue("list", list, ""), "a", "a", false},
+		{FlagValue("list", list, ""), "b", "a,b", false},
+		{FlagValue("list", list, ""), "", nil, true},
+	}
+
+	for _, c := range cases {
+		v, err := c.opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s %#v: expected an error, got %v", c.opt.Name(), c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%s %#v: %s", c.opt.Name(), c.input, err)
+		} else if fmt.Sprint(v) != fmt.Sprint(c.expect) {
+			t.Errorf("%s %#v: expected %v, got %v", c.opt.Name(), c.input, c.expect, v)
+		}
+	}
+
+	if v := num.String(); v != "30" {
+		t.Errorf("expected the flag value 30, got %s", v)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the nil flag value")
+			}
+		}()
+		FlagValue("nil", nil, "")
+	}()
+}
+
+func testCertificatePEM(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return
+}
+
+func TestCertificateOpt(t *testing.T) {
+	certPEM, keyPEM := testCertificatePEM(t)
+	bundle := string(certPEM) + string(keyPEM)
+
+	dir, err := ioutil.TempDir("", "go-config-cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	bundleFile := filepath.Join(dir, "bundle.pem")
+	for file, data := range map[string]string{
+		certFile:   string(certPEM),
+		keyFile:    string(keyPEM),
+		bundleFile: bundle,
+	} {
+		if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opt := CertificateOpt("c", "cert", bundleFile, "")
+	if v := opt.Default(); v != bundleFile {
+		t.Errorf("expected the default '%s', got %v", bundleFile, v)
+	} else if v, ok := opt.Zero().(tls.Certificate); !ok || len(v.Certificate) != 0 {
+		t.Errorf("expected the empty certificate, got %v", opt.Zero())
+	} else if v := Certificate("cert", "", "").Default(); v != nil {
+		t.Errorf("expected no default, got %v", v)
+	}
+
+	loaded, err := ToCertificate(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		input interface{}
+		err   bool
+	}{
+		{certFile + "," + keyFile, false},
+		{certFile + " , " + keyFile, false},
+		{bundleFile, false},
+		{bundle, false},
+		{[]byte(bundle), false},
+		{loaded, false},
+		{&loaded, false},
+		{"", true},
+		{"   ", true},
+		{nil, true},
+		{certFile, true},
+		{keyFile + "," + certFile, true},
+		{certFile + "," + filepath.Join(dir, "missing.pem"), true},
+		{filepath.Join(dir, "missing.pem"), true},
+		{string(certPEM), true},
+		{"-----BEGIN CERTIFICATE-----\ninvalid\n-----END CERTIFICATE-----", true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if cert, ok := v.(tls.Certificate); !ok || cert.Leaf == nil {
+			t.Errorf("%#v: expected the certificate with the leaf, got %v", c.input, v)
+		} else if cert.Leaf.Subject.CommonName != "test" {
+			t.Errorf("%#v: expected the subject 'test', got '%s'", c.input, cert.Leaf.Subject.CommonName)
+		}
+	}
+
+	if s := certificateSummary(loaded); !strings.Contains(s, "CN=test") || strings.Contains(s, "PRIVATE") {
+		t.Errorf("unexpected certificate summary: %s", s)
+	} else if s := certificateSummary(tls.Certificate{}); s != "" {
+		t.Errorf("expected the empty summary, got %s", s)
+	}
+}
+
+func TestLocaleOpt(t *testing.T) {
+	opt := LocaleOpt("l", "locale", "EN_us", "")
+	if v := opt.Default(); v != "en-US" {
+		t.Errorf("expected the default en-US, got %v", v)
+	} else if v := opt.Zero(); v != "" {
+		t.Errorf("expected the zero '', got %v", v)
+	} else if v := Locale("locale", "", "").Default(); v != nil {
+		t.Errorf("expected no default, got %v", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect string
+		err    bool
+	}{
+		{"en", "en", false},
+		{"EN-us", "en-US", false},
+		{" en_us ", "en-US", false},
+		{[]byte("zh-hans-cn"), "zh-Hans-CN", false},
+		{"es-419", "es-419", false},
+		{"zh-cmn-Hans-CN", "zh-cmn-Hans-CN", false},
+		{"de-DE-1996", "de-DE-1996", false},
+		{"sl-rozaj", "sl-rozaj", false},
+		{"en-US-u-ca-gregory", "en-US-u-ca-gregory", false},
+		{"en-x-private", "en-x-private", false},
+		{"x-whatever", "x-whatever", false},
+		{"", "", true},
+		{nil, "", true},
+		{"e", "", true},
+		{"12", "", true},
+		{"en--US", "", true},
+		{"en-US-", "", true},
+		{"en.US", "", true},
+		{"toolonglang", "", true},
+		{"en-US-ab", "", true},
+		{"en-u", "", true},
+		{"en-x", "", true},
+		{"i-klingon", "", true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if v != c.expect {
+			t.Errorf("%#v: expected %s, got %v", c.input, c.expect, v)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the invalid default locale")
+			}
+		}()
+		Locale("locale", "en-", "")
+	}()
+}
+
+func TestPercentOpt(t *testing.T) {
+	opt := PercentOpt("p", "percent", 50, "")
+	if v := opt.Default(); v != 0.5 {
+		t.Errorf("expected the default 0.5, got %v", v)
+	} else if v := opt.Zero(); v != float64(0) {
+		t.Errorf("expected the zero 0, got %v", v)
+	} else if v := Percent("percent", 0.25, "").Default(); v != 0.25 {
+		t.Errorf("expected the default 0.25, got %v", v)
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect float64
+		err    bool
+	}{
+		{"0.75", 0.75, false},
+		{"75", 0.75, false},
+		{"75%", 0.75, false},
+		{" 1 % ", 0.01, false},
+		{[]byte("20%"), 0.2, false},
+		{"0", 0, false},
+		{"0%", 0, false},
+		{"1", 1, false},
+		{"100%", 1, false},
+		{0.5, 0.5, false},
+		{float32(0.5), 0.5, false},
+		{80, 0.8, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"%", 0, true},
+		{"NaN", 0, true},
+		{"-0.1", 0, true},
+		{"-1%", 0, true},
+		{"101", 0, true},
+		{"100.5%", 0, true},
+		{-1, 0, true},
+		{nil, 0, true},
+	}
+
+	for _, c := range cases {
+		v, err := opt.Parse(c.input)
+		if c.err {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.input, v)
+			}
+		} else if err != nil {
+			t.Errorf("%#v: %s", c.input, err)
+		} else if f, ok := v.(float64); !ok || math.Abs(f-c.expect) > 1e-9 {
+			t.Errorf("%#v: expected %v, got %v", c.input, c.expect, v)
+		}
+	}
+
+	for _, d := range []float64{-1, 101, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: expect a panic for the invalid default percentage", d)
+				}
+			}()
+			Percent("percent", d, "")
+		}()
+	}
 }
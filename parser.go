@@ -20,11 +20,12 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
-	"unicode"
 )
 
 // Parser is an parser interface.
@@ -76,8 +77,9 @@ type Parser interface {
 }
 
 type flagParser struct {
-	utoh bool
-	fset *flag.FlagSet
+	utoh  bool
+	slash bool
+	fset  *flag.FlagSet
 }
 
 // NewDefaultFlagCliParser returns a new CLI parser based on flag,
@@ -98,6 +100,21 @@ func NewDefaultFlagCliParser(underlineToHyphen ...bool) Parser {
 //
 // If underlineToHyphen is true, it will convert the underline to the hyphen.
 //
+// For the slice options, such as []string and []int, the values can be given
+// by the comma-separated form, or by repeating the flag, such as
+// "-tag a,b -tag c".
+//
+// If the option has the short name, it will be registered as the alias
+// of the option, such as "-c config.ini" for "-config-file config.ini".
+//
+// The single-character boolean flags can be combined, such as "-abc" for
+// "-a -b -c", and the last one may take the value, such as "-vofile" for
+// "-v -o file".
+//
+// The value can be given by "-flag=value" or "-flag value" for all the types,
+// including the negative number, such as "-offset -5", and the bool flag,
+// such as "-debug false". The flag name can also start with "--".
+//
 // Notice: when other libraries use the default global flag.FlagSet, that's
 // flag.CommandLine, such as github.com/golang/glog, please use flag.CommandLine
 // as flag.FlagSet.
@@ -112,15 +129,61 @@ func NewFlagCliParser(flagSet *flag.FlagSet, underlineToHyphen bool) Parser {
 	}
 }
 
+// NewWindowsFlagCliParser is the same as NewFlagCliParser, but also accepts
+// the Windows-style flags, "/name" and "/name:value", such as "/verbose" for
+// "-verbose" and "/config-file:C:\app.ini" for "-config-file=C:\app.ini",
+// and "/?" for "-help". The name is matched case-insensitively.
+//
+// Notice: the argument starting with "/" is regarded as the flag only if its
+// name has been registered, so the absolute path is still the non-flag argument.
+func NewWindowsFlagCliParser(flagSet *flag.FlagSet, underlineToHyphen bool) Parser {
+	p := NewFlagCliParser(flagSet, underlineToHyphen).(flagParser)
+	p.slash = true
+	return p
+}
+
 func (f flagParser) Name() string {
 	return "flag"
 }
 
+func (f flagParser) isCliParser() {}
+
 func (f flagParser) Priority() int {
 	return 0
 }
 
+// Pre reports the conflicts of the short names of the CLI options with the
+// names of the flags, because they share the same namespace in flag.FlagSet.
 func (f flagParser) Pre(c *Config) error {
+	vname, _, _ := c.GetVersion()
+	pname, _ := c.GetPrintConfig()
+	gname, _ := c.GetGenerateConfig()
+	oname, _ := c.GetOverride()
+
+	flags := make(map[string]string, 16)
+	for _, name := range []string{vname, c.GetCompletion(), pname, gname, oname} {
+		if name != "" {
+			flags[name] = name
+		}
+	}
+	for _, group := range c.Groups() {
+		for _, opt := range group.CliOpts() {
+			key := c.optKey(group.FullName(), opt.Name())
+			flags[c.cliOptName(group.FullName(), opt.Name(), f.utoh)] = key
+		}
+	}
+
+	for _, group := range c.Groups() {
+		for _, opt := range group.CliOpts() {
+			short := opt.Short()
+			if other, ok := flags[short]; ok && short != "" {
+				c.addConflict(fmt.Errorf(
+					"the short name '%s' of the option '%s' has been used by the flag '%s'",
+					short, c.optKey(group.FullName(), opt.Name()), other))
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -147,62 +210,116 @@ func (f flagParser) Parse(c *Config) (err error) {
 			name2group[name] = gname
 			name2opt[name] = opt.Name()
 
-			switch opt.Zero().(type) {
+			usage := c.cliOptUsage(gname, opt)
+			zero := opt.Zero()
+			if isCountOpt(opt) {
+				zero = countValue(0)
+			} else if isSizeOpt(opt) {
+				zero = "" // The size accepts the human-readable string, such as "2GB".
+			} else if _, ok := opt.(percentOpt); ok {
+				zero = "" // The percentage accepts the string with "%", such as "75%".
+			}
+
+			switch zero.(type) {
+			case countValue:
+				f.fset.Var(new(countValue), name, usage)
 			case bool:
 				var _default bool
 				if v := opt.Default(); v != nil {
 					_default = v.(bool)
 				}
-				f.fset.Bool(name, _default, opt.Help())
+				f.fset.Bool(name, _default, usage)
 			case int, int8, int16, int32, int64:
 				var _default int64
 				if v := opt.Default(); v != nil {
 					_default, _ = ToInt64(v)
 				}
-				f.fset.Int64(name, _default, opt.Help())
+				f.fset.Int64(name, _default, usage)
 			case uint, uint8, uint16, uint32, uint64:
 				var _default uint64
 				if v := opt.Default(); v != nil {
 					_default, _ = ToUint64(v)
 				}
-				f.fset.Uint64(name, _default, opt.Help())
+				f.fset.Uint64(name, _default, usage)
 			case float32, float64:
 				var _default float64
 				if v := opt.Default(); v != nil {
 					_default, _ = ToFloat64(v)
 				}
-				f.fset.Float64(name, _default, opt.Help())
+				f.fset.Float64(name, _default, usage)
 			case time.Duration:
 				var _default time.Duration
 				if v := opt.Default(); v != nil {
 					_default = v.(time.Duration)
 				}
-				f.fset.Duration(name, _default, opt.Help())
+				f.fset.Duration(name, _default, usage)
+			case []string, []int, []int64, []uint, []uint64, []float64,
+				[]time.Duration, []time.Time, []*net.IPNet:
+				var _default []string
+				if v := opt.Default(); v != nil {
+					_default = toStringSlice(v)
+				}
+				f.fset.Var(&sliceValue{values: _default}, name, usage)
 			default:
 				var _default string
 				if v := opt.Default(); v != nil {
 					_default = fmt.Sprintf("%v", v)
 				}
-				f.fset.String(name, _default, opt.Help())
+				f.fset.String(name, _default, usage)
+			}
+
+			// Register the short name as the alias of the option.
+			// The conflicts of the short names have been reported by Pre.
+			if short := opt.Short(); short != "" && f.fset.Lookup(short) == nil {
+				f.fset.Var(f.fset.Lookup(name).Value, short, fmt.Sprintf("The short name of -%s.", name))
+				name2group[short] = gname
+				name2opt[short] = opt.Name()
 			}
 		}
 	}
 
-	// Register the version option.
-	var _version *bool
-	name, version, help := c.GetVersion()
-	if name != "" {
-		_version = f.fset.Bool(name, false, help)
+	// Register the aliases and the old names of the deprecated options.
+	for _, d := range c.optRedirects() {
+		if d.newName == "" {
+			continue
+		}
+
+		old := c.cliOptName(d.group, d.name, f.utoh)
+		target := f.fset.Lookup(c.cliOptName(d.newGroup, d.newName, f.utoh))
+		if target != nil && f.fset.Lookup(old) == nil {
+			f.fset.Var(target.Value, old, d.usage(c, f.utoh))
+			name2group[old] = d.group
+			name2opt[old] = d.name
+		}
 	}
 
+	// Register the built-in flags, such as the version.
+	builtins := c.registerBuiltinFlags(f.fset, func(name, usage string) *[]string {
+		overrides := new([]string)
+		f.fset.Var((*stringsValue)(overrides), name, usage)
+		return overrides
+	})
+
+	// Render the help output by the help template.
+	f.fset.Usage = func() { c.PrintHelp(f.fset.Output(), f.fset.Name(), f.utoh) }
+
 	// Parse the CLI arguments.
-	if err = f.fset.Parse(c.CliArgs()); err != nil {
+	args := c.CliArgs()
+	if f.slash {
+		args = convertSlashFlags(f.fset, args)
+	}
+	if err = f.fset.Parse(normalizeFlagArgs(f.fset, args)); err != nil {
+		const unknown = "flag provided but not defined: -"
+		if msg := err.Error(); strings.HasPrefix(msg, unknown) {
+			var names []string
+			f.fset.VisitAll(func(fg *flag.Flag) { names = append(names, fg.Name) })
+			err = c.didYouMean(err, msg[len(unknown):], "-", names)
+		}
 		return
 	}
 
-	if _version != nil && *_version {
-		fmt.Println(version)
-		os.Exit(0)
+	if err = builtins.run(c, f.fset.Name(), f.utoh); err != nil {
+		return
 	}
 
 	// Acquire the result.
@@ -211,16 +328,337 @@ func (f flagParser) Parse(c *Config) (err error) {
 		c.Printf("[%s] Parsing flag '%s'", f.Name(), fg.Name)
 		gname := name2group[fg.Name]
 		optname := name2opt[fg.Name]
-		if gname != "" && optname != "" && fg.Name != name {
-			c.SetOptValue(0, gname, optname, fg.Value.String())
+		if gname == "" || optname == "" || fg.Name == builtins.version {
+			return
+		}
+
+		if e := c.SetOptValue(0, gname, optname, fg.Value.String()); e != nil && err == nil {
+			err = e
 		}
 	})
 
+	if err == nil {
+		err = builtins.override(c)
+	}
+	return
+}
+
+// builtinFlags is the built-in flags of the CLI parsers, which are not
+// the options, such as the version and the completion.
+type builtinFlags struct {
+	version string
+
+	printVersion *bool
+	completion   *string
+	printConfig  *bool
+	generate     *string
+	overrides    *[]string
+}
+
+// flagSet is the common interface of flag.FlagSet and pflag.FlagSet.
+type flagSet interface {
+	Bool(name string, value bool, usage string) *bool
+	String(name string, value string, usage string) *string
+}
+
+// registerBuiltinFlags registers the built-in flags into fset, and the override
+// flag by overrides, which returns the values of the repeated flag.
+func (c *Config) registerBuiltinFlags(fset flagSet,
+	overrides func(name, usage string) *[]string) (b builtinFlags) {
+	// Register the version option.
+	name, _, help := c.GetVersion()
+	if name != "" {
+		b.version = name
+		b.printVersion = fset.Bool(name, false, help)
+	}
+
+	// Register the completion option.
+	if cname := c.GetCompletion(); cname != "" {
+		b.completion = fset.String(cname, "", "Print the completion script of the shell, bash, zsh or fish.")
+	}
+
+	// Register the print config option.
+	if pname, phelp := c.GetPrintConfig(); pname != "" {
+		b.printConfig = fset.Bool(pname, false, phelp)
+	}
+
+	// Register the generate config option.
+	if gname, ghelp := c.GetGenerateConfig(); gname != "" {
+		b.generate = fset.String(gname, "", ghelp)
+	}
+
+	// Register the override option.
+	if oname, ohelp := c.GetOverride(); oname != "" {
+		b.overrides = overrides(oname, ohelp)
+	}
+
 	return
 }
 
+// run prints the version, the completion script of the program or the config
+// file, then exits, if the corresponding flag is given. Or, it requests to
+// print the config after parsing if given.
+func (b builtinFlags) run(c *Config, prog string, underlineToHyphen bool) error {
+	if b.printVersion != nil && *b.printVersion && !c.IsDryRun() {
+		c.PrintVersion(os.Stdout)
+		os.Exit(0)
+	}
+
+	if b.completion != nil && *b.completion != "" && !c.IsDryRun() {
+		if err := c.GenerateCompletion(os.Stdout, *b.completion, prog, underlineToHyphen); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+
+	if b.generate != nil && *b.generate != "" && !c.IsDryRun() {
+		if err := c.generateConfigFile(*b.generate); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+
+	if b.printConfig != nil && *b.printConfig {
+		c.requestPrintConfig()
+	}
+
+	return nil
+}
+
+// override overrides the options by the override flags, which should be
+// called at last.
+func (b builtinFlags) override(c *Config) error {
+	if b.overrides != nil {
+		return c.applyOverrides(*b.overrides)
+	}
+	return nil
+}
+
+// stringsValue is a flag.Value to collect the values of the repeated flag.
+type stringsValue []string
+
+func (s *stringsValue) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, " ")
+}
+
+func (s *stringsValue) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// sliceValue is a flag.Value to accumulate the values of the repeated flag.
+type sliceValue struct {
+	set    bool
+	values []string
+}
+
+func (s *sliceValue) String() string {
+	return strings.Join(s.values, ",")
+}
+
+func (s *sliceValue) Set(value string) error {
+	// The first one overrides the default values.
+	if !s.set {
+		s.set = true
+		s.values = nil
+	}
+
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			s.values = append(s.values, v)
+		}
+	}
+	return nil
+}
+
+// countValue is a flag.Value to count the occurrences of the flag.
+type countValue int
+
+func (c *countValue) IsBoolFlag() bool {
+	return true
+}
+
+func (c *countValue) String() string {
+	return strconv.Itoa(int(*c))
+}
+
+func (c *countValue) Set(value string) error {
+	switch value {
+	case "true":
+		*c++
+	case "false":
+		*c = 0
+	default:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*c = countValue(v)
+	}
+	return nil
+}
+
+// convertSlashFlags converts the Windows-style flags, "/name" and
+// "/name:value", to "-name" and "-name=value".
+func convertSlashFlags(fset *flag.FlagSet, args []string) []string {
+	results := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			copy(results[i:], args[i:])
+			break
+		} else if arg == "/?" {
+			results[i] = "-help"
+			continue
+		} else if len(arg) < 2 || arg[0] != '/' {
+			results[i] = arg
+			continue
+		}
+
+		name, value := arg[1:], ""
+		index := strings.IndexByte(name, ':')
+		if index > -1 {
+			name, value = name[:index], name[index+1:]
+		}
+
+		fg := fset.Lookup(name)
+		if fg == nil {
+			fset.VisitAll(func(f *flag.Flag) {
+				if fg == nil && strings.EqualFold(f.Name, name) {
+					fg = f
+				}
+			})
+		}
+
+		switch {
+		case fg == nil:
+			results[i] = arg
+		case index > -1:
+			results[i] = fmt.Sprintf("-%s=%s", fg.Name, value)
+		default:
+			results[i] = "-" + fg.Name
+		}
+	}
+	return results
+}
+
+func isBoolFlag(fg *flag.Flag) bool {
+	b, ok := fg.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// isNegativeNumber reports whether the argument is a negative number,
+// such as "-5" or "-1.5".
+func isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// isBoolValue reports whether the argument is the value of the bool flag
+// given by the form "-flag value".
+func isBoolValue(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "true", "false":
+		return true
+	}
+	return false
+}
+
+// normalizeFlagArgs normalizes the CLI arguments for the flag package:
+//
+//   1. Expand the combined single-character flags, such as "-abc" to
+//      "-a -b -c" and "-vofile" to "-v -o file".
+//   2. Convert "-flag true" and "-flag false" to "-flag=true" and
+//      "-flag=false" for the bool flag.
+//   3. Stop parsing the flags at the negative number which is not the value
+//      of the flag, such as "-5", so it is the non-flag argument.
+func normalizeFlagArgs(fset *flag.FlagSet, args []string) []string {
+	results := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			// The flag package stops parsing at the first non-flag argument.
+			return append(results, args[i:]...)
+		} else if isNegativeNumber(arg) && fset.Lookup(arg[1:]) == nil {
+			return append(append(results, "--"), args[i:]...)
+		}
+
+		name := arg[1:]
+		if name[0] == '-' {
+			name = name[1:]
+		}
+
+		if strings.IndexByte(name, '=') > -1 {
+			results = append(results, arg)
+			continue
+		}
+
+		// The registered flag, and handle its value.
+		if fg := fset.Lookup(name); fg != nil {
+			results = append(results, arg)
+			if i+1 < len(args) {
+				if !isBoolFlag(fg) {
+					i++
+					results = append(results, args[i])
+				} else if isBoolValue(args[i+1]) {
+					i++
+					results[len(results)-1] = fmt.Sprintf("%s=%s", arg, args[i])
+				}
+			}
+			continue
+		}
+
+		if arg[1] == '-' {
+			results = append(results, arg)
+			continue
+		}
+
+		expanded, ok := expandShortFlag(fset, arg[1:])
+		if !ok {
+			results = append(results, arg)
+			continue
+		}
+		results = append(results, expanded...)
+
+		// The last flag takes the value from the next argument.
+		if last := fset.Lookup(expanded[len(expanded)-1][1:]); last != nil &&
+			!isBoolFlag(last) && i+1 < len(args) {
+			i++
+			results = append(results, args[i])
+		}
+	}
+	return results
+}
+
+func expandShortFlag(fset *flag.FlagSet, flags string) (results []string, ok bool) {
+	for j := 0; j < len(flags); j++ {
+		fg := fset.Lookup(flags[j : j+1])
+		if fg == nil {
+			return nil, false
+		}
+
+		results = append(results, "-"+fg.Name)
+		if !isBoolFlag(fg) {
+			if j+1 < len(flags) {
+				results = append(results, flags[j+1:])
+			}
+			break
+		}
+	}
+	return results, true
+}
+
+// cliParser is implemented by the CLI parsers, which are not re-run by Reload.
+type cliParser interface {
+	isCliParser()
+}
+
 type iniParser struct {
-	sep  string
 	opt  string
 	prio int
 	init func(*Config) error
@@ -255,7 +693,7 @@ func NewSimpleIniParser(optName string) Parser {
 // Notice: the options that have not been assigned to a certain group will be
 // divided into the default group.
 func NewIniParser(priority int, optName string, init func(*Config) error) Parser {
-	return iniParser{prio: priority, opt: optName, sep: "=", init: init}
+	return iniParser{prio: priority, opt: optName, init: init}
 }
 
 func (p iniParser) Name() string {
@@ -277,9 +715,13 @@ func (p iniParser) Post(c *Config) error {
 	return nil
 }
 
+func (p iniParser) fileName(c *Config) string {
+	return c.StringD(p.opt, "")
+}
+
 func (p iniParser) Parse(c *Config) error {
 	// Read the content of the config file.
-	filename := c.StringD(p.opt, "")
+	filename := p.fileName(c)
 	if filename == "" {
 		return nil
 	}
@@ -289,68 +731,11 @@ func (p iniParser) Parse(c *Config) error {
 	}
 
 	// Parse the config file.
-	gname := c.GetDefaultGroupName()
-	lines := strings.Split(string(data), "\n")
-	for index, maxIndex := 0, len(lines); index < maxIndex; {
-		line := strings.TrimSpace(lines[index])
-		index++
-
-		c.Printf("[%s] Parsing %dth line: '%s'", p.Name(), index, line)
-
-		// Ignore the empty line.
-		if len(line) == 0 {
-			continue
-		}
-
-		// Ignore the line comments starting with "#", ";" or "//".
-		if (line[0] == '#') || (line[0] == ';') ||
-			(len(line) > 1 && line[0] == '/' && line[1] == '/') {
-			continue
-		}
-
-		// Start a new group
-		if line[0] == '[' && line[len(line)-1] == ']' {
-			gname = strings.TrimSpace(line[1 : len(line)-1])
-			if gname == "" {
-				return fmt.Errorf("the group is empty")
-			}
-			continue
-		}
-
-		n := strings.Index(line, p.sep)
-		if n == -1 {
-			return fmt.Errorf("the %dth line misses the separator '%s'", index, p.sep)
-		}
-
-		key := strings.TrimSpace(line[0:n])
-		for _, r := range key {
-			if r != '_' && r != '-' && !unicode.IsNumber(r) && !unicode.IsLetter(r) {
-				return fmt.Errorf("invalid identifier key '%s'", key)
-			}
-		}
-		value := strings.TrimSpace(line[n+len(p.sep) : len(line)])
-
-		// The continuation line
-		if value != "" && value[len(value)-1] == '\\' {
-			vs := []string{strings.TrimSpace(strings.TrimRight(value, "\\"))}
-			for index < maxIndex {
-				value = strings.TrimSpace(lines[index])
-				vs = append(vs, strings.TrimSpace(strings.TrimRight(value, "\\")))
-				index++
-				c.Printf("[%s] Parsing %dth line: '%s'", p.Name(), index, value)
-				if value == "" || value[len(value)-1] != '\\' {
-					break
-				}
-			}
-			value = strings.TrimSpace(strings.Join(vs, "\n"))
-		}
-
-		if err = c.SetOptValue(p.prio, gname, key, value); err != nil {
-			return err
-		}
+	values, err := DecodeIni(data, c.GetGroupSeparator())
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return c.setOptValues(p.prio, values, c.ignoreUnknown)
 }
 
 type envVarParser struct {
@@ -366,6 +751,9 @@ type envVarParser struct {
 //
 // Notice: the prefix, the group name and the option name will be converted to
 // the upper, and the group separator will be converted to "_".
+//
+// The option may have the exact name of the environment variable, which is
+// set by SetOptEnv or the struct tag "env", regardless of the prefix.
 func NewEnvVarParser(prefix string) Parser {
 	return envVarParser{prefix: prefix}
 }
@@ -386,23 +774,40 @@ func (e envVarParser) Post(c *Config) error {
 	return nil
 }
 
-func (e envVarParser) Parse(c *Config) (err error) {
-	// Initialize the prefix
+// varName returns the name of the environment variable of the option
+// in the group, the name of which is the full name.
+func (e envVarParser) varName(c *Config, group, opt string) string {
+	if env, ok := c.optEnv(group, opt); ok {
+		return env
+	}
+
 	prefix := e.prefix
 	if prefix != "" {
 		prefix += "_"
 	}
 
+	gname := ""
+	if group != "" && group != c.GetDefaultGroupName() {
+		gname = strings.Replace(group, c.GetGroupSeparator(), "_", -1) + "_"
+	}
+
+	return strings.ToUpper(fmt.Sprintf("%s%s%s", prefix, gname, opt))
+}
+
+func (e envVarParser) Parse(c *Config) (err error) {
 	// Convert the option to the variable name
 	env2opts := make(map[string][]string, len(c.Groups())*8)
 	for _, group := range c.Groups() {
-		gname := ""
-		if group.Name() != c.GetDefaultGroupName() {
-			gname = strings.Replace(group.FullName(), c.GetGroupSeparator(), "_", -1) + "_"
-		}
 		for _, opt := range group.AllOpts() {
-			e := fmt.Sprintf("%s%s%s", prefix, gname, opt.Name())
-			env2opts[strings.ToUpper(e)] = []string{group.Name(), opt.Name()}
+			env := e.varName(c, group.FullName(), opt.Name())
+			env2opts[env] = []string{group.Name(), opt.Name()}
+		}
+	}
+
+	// The aliases and the old names of the deprecated options.
+	for _, d := range c.optRedirects() {
+		if name := e.varName(c, d.group, d.name); d.newName != "" && env2opts[name] == nil {
+			env2opts[name] = []string{d.group, d.name}
 		}
 	}
 
@@ -424,7 +829,6 @@ func (e envVarParser) Parse(c *Config) (err error) {
 }
 
 type propertyParser struct {
-	sep  string
 	opt  string
 	prio int
 	init func(*Config) error
@@ -459,7 +863,7 @@ func NewSimplePropertyParser(optName string) Parser {
 // Notice: the options that have not been assigned to a certain group will be
 // divided into the default group.
 func NewPropertyParser(priority int, optName string, init func(*Config) error) Parser {
-	return propertyParser{prio: priority, opt: optName, sep: "=", init: init}
+	return propertyParser{prio: priority, opt: optName, init: init}
 }
 
 func (p propertyParser) Name() string {
@@ -481,9 +885,13 @@ func (p propertyParser) Post(c *Config) error {
 	return nil
 }
 
+func (p propertyParser) fileName(c *Config) string {
+	return c.StringD(p.opt, "")
+}
+
 func (p propertyParser) Parse(c *Config) error {
 	// Read the content of the config file.
-	filename := c.StringD(p.opt, "")
+	filename := p.fileName(c)
 	if filename == "" {
 		return nil
 	}
@@ -493,51 +901,9 @@ func (p propertyParser) Parse(c *Config) error {
 	}
 
 	// Parse the config file.
-	lines := strings.Split(string(data), "\n")
-	for index, maxIndex := 0, len(lines); index < maxIndex; {
-		line := strings.TrimSpace(lines[index])
-		index++
-
-		c.Printf("[%s] Parsing %dth line: '%s'", p.Name(), index, line)
-
-		// Ignore the empty line.
-		if len(line) == 0 {
-			continue
-		}
-
-		// Ignore the line comments starting with "#", ";" or "//".
-		if (line[0] == '#') || (line[0] == ';') ||
-			(len(line) > 1 && line[0] == '/' && line[1] == '/') {
-			continue
-		}
-
-		ss := strings.SplitN(line, p.sep, 2)
-		if len(ss) != 2 {
-			return fmt.Errorf("the %dth line misses the separator '%s'", index, p.sep)
-		}
-
-		key := strings.TrimSpace(ss[0])
-		value := strings.TrimSpace(ss[1])
-		if value != "" {
-			for index < maxIndex && value[len(value)-1] == '\\' {
-				value = strings.TrimRight(value, "\\") + strings.TrimSpace(lines[index])
-				index++
-				c.Printf("[%s] Parsing %dth line: '%s'", p.Name(), index, lines[index])
-			}
-		}
-
-		ss = strings.Split(key, c.GetGroupSeparator())
-		switch _len := len(ss) - 1; _len {
-		case 0:
-			err = c.SetOptValue(p.prio, "", key, value)
-		default:
-			err = c.SetOptValue(p.prio, strings.Join(ss[:_len], c.GetGroupSeparator()), ss[_len], value)
-		}
-
-		if err != nil {
-			return err
-		}
+	values, err := DecodeProperty(data, c.GetGroupSeparator())
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return c.setOptValues(p.prio, values, c.ignoreUnknown)
 }
@@ -0,0 +1,1142 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCliParserValueShapes(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+	}
+
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"--offset=-5"}, "offset=-5"},
+		{[]string{"--offset", "-5"}, "offset=-5"},
+		{[]string{"-o", "-5"}, "offset=-5"},
+		{[]string{"--ratio=-1.5"}, "ratio=-1.5"},
+		{[]string{"--ratio", "-1.5"}, "ratio=-1.5"},
+		{[]string{"--debug"}, "debug=true"},
+		{[]string{"--debug=true"}, "debug=true"},
+		{[]string{"--debug", "true"}, "debug=true"},
+		{[]string{"--name", "-x"}, "name=-x"},
+		{[]string{"--name=a=b"}, "name=a=b"},
+		{[]string{"--timeout", "-1s"}, "timeout=-1s"},
+		{[]string{"--timeout=2s"}, "timeout=2s"},
+		{[]string{"--tags", "a", "--tags=b,c"}, "tags=a,b,c"},
+		{[]string{"--offset", "1", "-5", "x"}, "offset=1 args=-5,x"},
+	}
+
+	for pname, newParser := range parsers {
+		for _, c := range cases {
+			conf := NewConfig().AddParser(newParser())
+			conf.RegisterCliOpt("", IntOpt("o", "offset", 0, ""))
+			conf.RegisterCliOpt("", Float64("ratio", 0, ""))
+			conf.RegisterCliOpt("", Bool("debug", false, ""))
+			conf.RegisterCliOpt("", Str("name", "", ""))
+			conf.RegisterCliOpt("", Duration("timeout", 0, ""))
+			conf.RegisterCliOpt("", Strings("tags", nil, ""))
+
+			if err := conf.Parse(c.args...); err != nil {
+				t.Errorf("%s %v: %s", pname, c.args, err)
+				continue
+			}
+
+			var results []string
+			for _, name := range []string{"offset", "ratio", "debug", "name", "timeout", "tags"} {
+				if conf.Group("").Priority(name) == 0 {
+					results = append(results, fmt.Sprintf("%s=%s", name, formatOptValue(conf.Value(name))))
+				}
+			}
+			if args := conf.Args(); len(args) > 0 {
+				results = append(results, "args="+strings.Join(args, ","))
+			}
+
+			if result := strings.Join(results, " "); result != c.expect {
+				t.Errorf("%s %v: expected '%s', got '%s'", pname, c.args, c.expect, result)
+			}
+		}
+	}
+}
+
+func TestCliParserCountOpt(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"windows": func() Parser {
+			return NewWindowsFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+		"getopt": func() Parser {
+			return NewGetoptCliParser("vq", true)
+		},
+	}
+
+	for pname, newParser := range parsers {
+		conf := NewConfig().AddParser(newParser())
+		conf.RegisterCliOpt("", CountOpt("v", "verbose", ""))
+		conf.RegisterCliOpt("", CountOpt("q", "quiet", ""))
+
+		if err := conf.Parse("-vv", "-q", "--verbose", "-v"); err != nil {
+			t.Errorf("%s: %s", pname, err)
+		} else if v, q := conf.Int("verbose"), conf.Int("quiet"); v != 4 || q != 1 {
+			t.Errorf("%s: expect verbose=4 quiet=1, but got verbose=%d quiet=%d", pname, v, q)
+		}
+	}
+}
+
+func TestCountOptAcrossSources(t *testing.T) {
+	defer os.Unsetenv("TEST_COUNT_VERBOSE")
+	os.Setenv("TEST_COUNT_VERBOSE", "2")
+
+	conf := NewConfig()
+	conf.AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+	conf.AddParser(NewEnvVarParser("test_count"))
+	conf.RegisterCliOpt("", CountOpt("v", "verbose", ""))
+	conf.RegisterCliOpt("", CountOpt("q", "quiet", ""))
+
+	if err := conf.Parse("-v", "-v", "-v"); err != nil {
+		t.Fatal(err)
+	} else if v, q := conf.Int("verbose"), conf.Int("quiet"); v != 5 || q != 0 {
+		t.Errorf("expect verbose=5 quiet=0, but got verbose=%d quiet=%d", v, q)
+	}
+
+	// The source setting the count again replaces its last count.
+	if err := conf.SetOptValue(10, "", "verbose", 1); err != nil {
+		t.Error(err)
+	} else if v := conf.Int("verbose"); v != 4 {
+		t.Errorf("expect verbose=4, but got %d", v)
+	}
+	if p := conf.Group("").Priority("verbose"); p != 0 {
+		t.Errorf("expect the priority 0, but got %d", p)
+	}
+}
+
+func TestCliParserSetOptValueError(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+	}
+
+	for pname, newParser := range parsers {
+		conf := NewConfig().AddParser(newParser())
+		conf.SetOverride()
+		conf.RegisterCliOpt("", IntOpt("", "port", 80, "").SetValidators(NewIntegerRangeValidator(1, 1024)))
+		conf.RegisterCliOpt("", Str("name", "", ""))
+
+		err := conf.Parse("--port", "8080", "--set", "name=abc")
+		if err == nil {
+			t.Errorf("%s: expect a validation error, but got nil", pname)
+		} else if !strings.Contains(err.Error(), "port") {
+			t.Errorf("%s: expect the error of the option 'port', got '%s'", pname, err)
+		}
+	}
+}
+
+func TestCliShortNameCollision(t *testing.T) {
+	conf := NewConfig().AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+	conf.RegisterCliOpt("", StrOpt("v", "version", "", ""))
+	conf.RegisterCliOpt("log", StrOpt("v", "verbose", "", ""))
+	conf.RegisterCliOpt("db", StrOpt("v", "vendor", "", ""))
+	conf.RegisterOpt("db", StrOpt("v", "volume", "", "")) // Not the CLI option
+
+	err := conf.Parse()
+	if err == nil {
+		t.Fatal("expect an error for the short name collisions")
+	}
+	for _, key := range []string{"log.verbose", "db.vendor"} {
+		if !strings.Contains(err.Error(), "the option '"+key+"'") {
+			t.Errorf("the error does not contain the option '%s': %s", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "db.volume") {
+		t.Errorf("unexpected the non-CLI option: %s", err)
+	}
+
+	var s struct {
+		Host string `short:"h" cli:"true"`
+		Port int    `short:"h" cli:"true"`
+	}
+	if _, err := NewConfig().RegisterStructE("", &s); err == nil {
+		t.Error("expect an error for the short name collision in the struct")
+	}
+}
+
+func TestFlagParserShortNameConflicts(t *testing.T) {
+	newConfig := func() *Config {
+		return NewConfig().AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+	}
+
+	// Two options share the same short name.
+	conf := newConfig().SetConflictPolicy(ConflictMergeHelp)
+	conf.RegisterCliOpt("", StrOpt("p", "port", "", ""))
+	conf.RegisterOpt("", StrOpt("p", "path", "", ""))
+	conf.RegisterCliOpt("", StrOpt("p", "path", "", "")) // Merge it into the CLI option
+	if err := conf.Parse(); err == nil {
+		t.Error("expect an error for the options sharing the short name")
+	} else if !strings.Contains(err.Error(), "the short name 'p' of the option 'path'") {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// The short name is used by the name of another option.
+	conf = newConfig()
+	conf.RegisterCliOpt("", StrOpt("", "d", "", ""))
+	conf.RegisterCliOpt("", StrOpt("d", "debug", "", ""))
+	if err := conf.Parse(); err == nil {
+		t.Error("expect an error for the short name used by the option name")
+	} else if !strings.Contains(err.Error(), "the short name 'd' of the option 'debug' has been used by the flag 'd'") {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// The short name is used by the built-in flag.
+	conf = newConfig().SetVersion("1.0.0", "V")
+	conf.RegisterCliOpt("", BoolOpt("V", "verbose", false, ""))
+	if err := conf.Parse(); err == nil {
+		t.Error("expect an error for the short name used by the built-in flag")
+	} else if !strings.Contains(err.Error(), "the short name 'V' of the option 'verbose' has been used by the flag 'V'") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestPFlagParserConflicts(t *testing.T) {
+	fset := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fset.StringP("addr", "a", "", "")
+	conf := NewConfig().AddParser(NewPFlagCliParser(fset, true))
+	conf.RegisterCliOpt("", StrOpt("", "addr", "", ""))
+	conf.RegisterCliOpt("", StrOpt("a", "admin", "", ""))
+	conf.RegisterCliOpt("", StrOpt("", "log_file", "", ""))
+	conf.RegisterCliOpt("", StrOpt("", "log-file", "", ""))
+
+	err := conf.Parse()
+	if err == nil {
+		t.Fatal("expect an error for the conflicted flags")
+	}
+	for _, msg := range []string{
+		"the flag 'addr' of the option 'addr' has been defined",
+		"the short name 'a' of the option 'admin' has been used by the flag 'addr'",
+		"the flag 'log-file' of the option 'log",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expect the error '%s', but got '%s'", msg, err)
+		}
+	}
+}
+
+func TestPFlagParserUsageOutput(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	fset := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fset.SetOutput(buf)
+	conf := NewConfig().AddParser(NewPFlagCliParser(fset, true))
+	conf.RegisterCliOpt("", StrOpt("", "addr", "", "the address"))
+	if err := conf.Parse("--help"); err == nil {
+		t.Error("expect an error for the help flag")
+	} else if !strings.Contains(buf.String(), "the address") {
+		t.Errorf("expect the help in the output of the flag set, but got '%s'", buf.String())
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.ini")
+	if err = ioutil.WriteFile(filename, []byte("[DEFAULT]\naddr = :80\nport = 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan interface{}, 4)
+	conf := NewConfig().AddParser(NewIniParser(100, "config-file", nil)).WatchFiles(true)
+	conf.RegisterOpt("", Str("config-file", "", ""))
+	conf.RegisterOpt("", Str("addr", "", ""))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.SetOptValue(0, "", "config-file", filename)
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	// The port unchanged in the file keeps the value set at runtime.
+	conf.SetOptValue(100, "", "port", 8000)
+	conf.Observe(func(group, name string, value interface{}) {
+		changed <- fmt.Sprintf("%s=%v", name, value)
+	})
+
+	// Replace the file by renaming like the editors.
+	tmpfile := filename + ".tmp"
+	if err = ioutil.WriteFile(tmpfile, []byte("[DEFAULT]\naddr = :8080\nport = 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.Rename(tmpfile, filename); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case v := <-changed:
+		if v != "addr=:8080" {
+			t.Errorf("expect 'addr=:8080', but got '%v'", v)
+		}
+	case <-time.After(FileWatchInterval * 3):
+		t.Error("the config file is not reloaded")
+	}
+
+	// The unchanged port is not notified.
+	select {
+	case v := <-changed:
+		t.Errorf("unexpected change '%v'", v)
+	case <-time.After(fileWatchDelay * 2):
+	}
+	if port := conf.Int("port"); port != 8000 {
+		t.Errorf("expect the port '8000', but got '%d'", port)
+	}
+}
+
+type testRefreshParser struct {
+	lock   *sync.Mutex
+	values map[string]string
+}
+
+func (p testRefreshParser) Name() string         { return "test" }
+func (p testRefreshParser) Priority() int        { return 10 }
+func (p testRefreshParser) Pre(c *Config) error  { return nil }
+func (p testRefreshParser) Post(c *Config) error { return nil }
+func (p testRefreshParser) Parse(c *Config) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for name, value := range p.values {
+		if err := c.SetOptValue(p.Priority(), "", name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRefreshEvery(t *testing.T) {
+	p := testRefreshParser{lock: new(sync.Mutex), values: map[string]string{"a": "1", "b": "2"}}
+	changed := make(chan string, 8)
+	conf := NewConfig().AddParser(RefreshEvery(p, time.Millisecond*10))
+	conf.RegisterOpt("", Str("a", "", ""))
+	conf.RegisterOpt("", Str("b", "", ""))
+	conf.Observe(func(group, name string, value interface{}) {
+		changed <- fmt.Sprintf("%s=%v", name, value)
+	})
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	for i := 0; i < 2; i++ {
+		if v := <-changed; v != "a=1" && v != "b=2" {
+			t.Errorf("unexpected change '%s'", v)
+		}
+	}
+
+	p.lock.Lock()
+	p.values["b"] = "3"
+	p.lock.Unlock()
+
+	select {
+	case v := <-changed:
+		if v != "b=3" {
+			t.Errorf("expect the change 'b=3', but got '%s'", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the parser is not refreshed")
+	}
+
+	select {
+	case v := <-changed:
+		t.Errorf("unexpected change '%s'", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestReloadNoWatchLeak(t *testing.T) {
+	p := testRefreshParser{lock: new(sync.Mutex), values: map[string]string{"a": "1"}}
+	conf := NewConfig().AddParser(RefreshEvery(p, time.Second))
+	conf.RegisterOpt("", Str("a", "", ""))
+
+	before := runtime.NumGoroutine()
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := conf.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := runtime.NumGoroutine(); n > before+1 {
+		t.Errorf("expect at most %d goroutines after reloading, but got %d", before+1, n)
+	}
+
+	// The goroutine may not exit yet after Close returns.
+	conf.Close()
+	n := runtime.NumGoroutine()
+	for i := 0; i < 100 && n > before; i++ {
+		time.Sleep(time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	if n > before {
+		t.Errorf("expect at most %d goroutines after closing, but got %d", before, n)
+	}
+}
+
+func TestReloadParserStaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.ini")
+	if err = ioutil.WriteFile(filename, []byte("addr = :80\nport = 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewIniParser(100, "config-file", nil)
+	conf := NewConfig().AddParser(parser)
+	conf.RegisterOpt("", Str("config-file", "", ""))
+	conf.RegisterOpt("", Str("addr", "", ""))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.SetOptValue(0, "", "config-file", filename)
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The invalid port makes the whole file not applied.
+	if err = ioutil.WriteFile(filename, []byte("addr = :8080\nport = abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err = conf.reloadParser(parser.(fileParser), nil); err == nil {
+		t.Error("expect an error for the invalid port")
+	} else if addr := conf.String("addr"); addr != ":80" {
+		t.Errorf("expect ':80', but got '%s'", addr)
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("addr = :8080\nport = 8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err = conf.reloadParser(parser.(fileParser), nil); err != nil {
+		t.Error(err)
+	} else if addr, port := conf.String("addr"), conf.Int("port"); addr != ":8080" || port != 8080 {
+		t.Errorf("expect ':8080' and 8080, but got '%s' and %d", addr, port)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.ini")
+	if err = ioutil.WriteFile(filename, []byte("addr = :80\nport = 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig().AddParser(
+		NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true),
+		NewSimpleIniParser("config-file"))
+	conf.RegisterCliOpt("", Str("addr", "", ""))
+	conf.RegisterCliOpt("", Int("port", 0, ""))
+	if err = conf.Reload(); err == nil {
+		t.Error("expect an error before parsing")
+	}
+	if err = conf.Parse("--config-file", filename, "--port", "8000"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("addr = :8080\nport = 8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = conf.Reload(); err != nil {
+		t.Fatal(err)
+	} else if addr, port := conf.String("addr"), conf.Int("port"); addr != ":8080" || port != 8000 {
+		t.Errorf("expect ':8080' and 8000, but got '%s' and %d", addr, port)
+	}
+}
+
+func TestRegisterOptAfterParse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.ini")
+	data := []byte("[plugin]\naddr = :80\n[cache]\nsize = 100\n")
+	if err = ioutil.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("TEST_PLUGIN_TOKEN", "secret")
+	defer os.Unsetenv("TEST_PLUGIN_TOKEN")
+
+	conf := NewConfig().AddParser(NewEnvVarParser("test"), NewIniParser(100, "config-file", nil))
+	conf.IgnoreUnknown(true).RegisterOpt("", Str("config-file", "", ""))
+	conf.SetOptValue(0, "", "config-file", filename)
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Register the options of the plugin loaded at runtime.
+	conf.RegisterOpt("plugin", Str("addr", "", ""))
+	conf.RegisterOpt("plugin", Str("token", "", ""))
+	conf.RegisterOpt("plugin", Int("timeout", 3, ""))
+	g := conf.Group("plugin")
+	if v := g.String("addr"); v != ":80" {
+		t.Errorf("expect ':80', but got '%s'", v)
+	}
+	if v := g.String("token"); v != "secret" {
+		t.Errorf("expect 'secret', but got '%s'", v)
+	}
+	if v := g.Int("timeout"); v != 3 {
+		t.Errorf("expect 3, but got %d", v)
+	}
+
+	var cache struct{ Size int }
+	conf.RegisterStruct("cache", &cache)
+	if cache.Size != 100 {
+		t.Errorf("expect 100, but got %d", cache.Size)
+	}
+}
+
+func TestRegisterOptWhileWatching(t *testing.T) {
+	p := testWatchParser{
+		testRefreshParser: testRefreshParser{lock: new(sync.Mutex), values: map[string]string{"a": "1"}},
+		changes:           make(chan map[string]map[string]interface{}),
+		errs:              make(chan error),
+	}
+
+	conf := NewConfig().AddParser(p)
+	conf.RegisterOpt("", Str("a", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	const count = 50
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < count; i++ {
+			group := fmt.Sprintf("plugin%d", i)
+			p.changes <- map[string]map[string]interface{}{"": {"a": fmt.Sprint(i)}, group: {"addr": ":80"}}
+			if err := <-p.errs; err != nil {
+				t.Error(err)
+				return
+			}
+			if err := conf.setOptValueIfExist(0, group, "port", 80); err != nil {
+				t.Error(err)
+				return
+			}
+			conf.Groups()
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		var plugin struct {
+			Addr string `env:"TEST_PLUGIN_ADDR"`
+			Host string `deprecated:"use addr"`
+			Data string `immutable:"true"`
+		}
+		group := fmt.Sprintf("plugin%d", i)
+		conf.RegisterStruct(group, &plugin)
+		conf.RegisterOpt(group, Int("port", 0, ""))
+	}
+	<-done
+
+	if a := conf.String("a"); a != fmt.Sprint(count-1) {
+		t.Errorf("expect a=%d, but got a=%s", count-1, a)
+	}
+	if port := conf.Group("plugin0").Int("port"); port != 0 && port != 80 {
+		t.Errorf("unexpected port %d", port)
+	}
+}
+
+type testWatchParser struct {
+	testRefreshParser
+	changes chan map[string]map[string]interface{}
+	errs    chan error
+}
+
+func (p testWatchParser) Watch(ctx context.Context,
+	apply func(map[string]map[string]interface{}) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case values := <-p.changes:
+			p.errs <- apply(values)
+		}
+	}
+}
+
+func TestWatchParser(t *testing.T) {
+	p := testWatchParser{
+		testRefreshParser: testRefreshParser{lock: new(sync.Mutex), values: map[string]string{"a": "1"}},
+		changes:           make(chan map[string]map[string]interface{}),
+		errs:              make(chan error),
+	}
+
+	conf := NewConfig().AddParser(p)
+	conf.RegisterOpt("", Str("a", "", ""))
+	conf.RegisterOpt("", Int("b", 0, ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	p.changes <- map[string]map[string]interface{}{"": {"a": "2", "b": 3, "c": 4}}
+	if err := <-p.errs; err != nil {
+		t.Error(err)
+	} else if a, b := conf.String("a"), conf.Int("b"); a != "2" || b != 3 {
+		t.Errorf("expect a=2 and b=3, but got a=%s and b=%d", a, b)
+	}
+
+	// The values are applied all or nothing.
+	p.changes <- map[string]map[string]interface{}{"": {"a": "4", "b": "x"}}
+	if err := <-p.errs; err == nil {
+		t.Error("expect an error for the invalid integer")
+	} else if a := conf.String("a"); a != "2" {
+		t.Errorf("expect a=2, but got a=%s", a)
+	}
+
+	conf.Close()
+	select {
+	case p.changes <- nil:
+		t.Error("expect the watcher to be stopped")
+	case <-time.After(time.Millisecond * 10):
+	}
+}
+
+func TestCloseWatchingParsers(t *testing.T) {
+	store := testKeyValueStore{kvs: map[string]string{"/a": "1"}, events: make(chan KeyValue)}
+	conf := NewConfig().AddParser(NewKeyValueStoreParser("test", 50, store, "/", true))
+	conf.RegisterOpt("", Int("a", 0, ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.events <- KeyValue{Key: "/a", Value: "2"}
+	conf.Close()
+	if v := conf.Int("a"); v != 2 {
+		t.Errorf("expect a=2, but got %d", v)
+	}
+
+	select {
+	case store.events <- KeyValue{Key: "/a", Value: "3"}:
+		t.Error("expect the watcher to be stopped")
+	case <-time.After(time.Millisecond * 10):
+	}
+}
+
+func TestPrintHelp(t *testing.T) {
+	newConfig := func() *Config {
+		conf := NewConfig().AddParser(NewEnvVarParser("app")).SetDescription("The test app.").
+			SetVersion("1.0.0").MarkRequired("db", "dsn").
+			SetExamples(HelpExample{Desc: "Listen on 8080", Cmd: "app --port 8080"})
+		conf.RegisterCliOpt("", StrOpt("p", "port", "80", "the port"))
+		conf.RegisterCliOpt("", Bool("debug", false, "the debug mode"))
+		conf.RegisterCliOpt("db", Str("max_conns", "", "the max connections"))
+		conf.RegisterCliOpt("db", Str("dsn", "", "the dsn"))
+		return conf
+	}
+
+	const expect = `The test app.
+
+Usage: app [OPTIONS] [ARGS...]
+
+Options:
+  --debug
+        the debug mode [env: APP_DEBUG]
+  -p, --port string
+        the port (default: 80) [env: APP_PORT]
+  --version
+        Print the version and exit.
+
+Options of the group 'db':
+  --db.dsn string
+        the dsn [env: APP_DB_DSN] [required]
+  --db.max-conns string
+        the max connections [env: APP_DB_MAX_CONNS]
+
+Examples:
+  # Listen on 8080
+  $ app --port 8080
+
+`
+
+	buf := bytes.NewBuffer(nil)
+	if err := newConfig().PrintHelp(buf, "app", true); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != expect {
+		t.Errorf("expect the help:\n%s\nbut got:\n%s", expect, buf.String())
+	}
+
+	// The custom template
+	buf.Reset()
+	conf := newConfig().SetHelpTemplate(`{{.Prog}}:{{range .Groups}} [{{.Name}}]{{range .Opts}} {{.Name}}{{end}}{{end}}`)
+	if err := conf.PrintHelp(buf, "app", false); err != nil {
+		t.Fatal(err)
+	} else if s := buf.String(); s != "app: [] debug port version [db] db.dsn db.max_conns" {
+		t.Errorf("unexpected help '%s'", s)
+	}
+}
+
+func TestCliParserDeprecatedOpt(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+		"getopt": func() Parser { return NewGetoptCliParser("", true) },
+	}
+
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"--listen-addr=:80"}, "addr=:80 debug=false"},
+		{[]string{"--listen-addr", ":80"}, "addr=:80 debug=false"},
+		{[]string{"--http.port", ":81"}, "addr=:81 debug=false"},
+		{[]string{"--addr", ":82", "--debug"}, "addr=:82 debug=true"},
+	}
+
+	for pname, newParser := range parsers {
+		for _, c := range cases {
+			conf := NewConfig().AddParser(newParser())
+			conf.DeprecateOpt("", "listen_addr", "addr", "removed in v2")
+			conf.DeprecateOpt("http", "port", "addr", "")
+			conf.DeprecateOpt("", "debug", "", "")
+			conf.RegisterCliOpt("", Str("addr", "", ""))
+			conf.RegisterCliOpt("", Bool("debug", false, ""))
+			conf.SetDebug(true) // Print the warnings by Printf, not to os.Stderr.
+			if err := conf.Parse(c.args...); err != nil {
+				t.Errorf("%s %v: %s", pname, c.args, err)
+				continue
+			}
+
+			result := fmt.Sprintf("addr=%s debug=%v", conf.String("addr"), conf.Bool("debug"))
+			if result != c.expect {
+				t.Errorf("%s %v: expected '%s', got '%s'", pname, c.args, c.expect, result)
+			}
+		}
+	}
+
+	conf := NewConfig()
+	conf.DeprecateOpt("", "listen_addr", "addr", "removed in v2")
+	conf.DeprecateOpt("http", "port", "addr", "")
+	conf.DeprecateOpt("", "debug", "", "")
+	warnings := []struct {
+		group  string
+		name   string
+		expect string
+	}{
+		{"", "listen_addr", "the option 'listen_addr' is deprecated, please use 'addr' instead: removed in v2"},
+		{"http", "port", "the option 'http.port' is deprecated, please use 'addr' instead"},
+		{"", "debug", "the option 'debug' is deprecated"},
+	}
+	for _, w := range warnings {
+		if d, ok := conf.getOptRedirect(w.group, w.name); !ok {
+			t.Errorf("no redirection of the option '%s'", w.name)
+		} else if msg := d.warning(conf); msg != w.expect {
+			t.Errorf("expected the warning '%s', got '%s'", w.expect, msg)
+		}
+	}
+}
+
+func TestCliParserAliasOpt(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+		"getopt": func() Parser { return NewGetoptCliParser("", true) },
+	}
+
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"--addr=:80"}, ":80"},
+		{[]string{"--listen-address=:81"}, ":81"},
+		{[]string{"--bind", ":82"}, ":82"},
+		{[]string{"--bind", ":82", "--addr", ":83"}, ":83"},
+	}
+
+	for pname, newParser := range parsers {
+		for _, c := range cases {
+			conf := NewConfig().AddParser(newParser()).AliasOpt("", "addr", "listen_address", "bind")
+			conf.RegisterCliOpt("", Str("addr", "", "the address"))
+			if err := conf.Parse(c.args...); err != nil {
+				t.Errorf("%s %v: %s", pname, c.args, err)
+			} else if v := conf.String("addr"); v != c.expect {
+				t.Errorf("%s %v: expected '%s', got '%s'", pname, c.args, c.expect, v)
+			}
+		}
+	}
+
+	// The environment variable of the alias
+	os.Setenv("TEST_ALIAS_BIND", ":84")
+	defer os.Unsetenv("TEST_ALIAS_BIND")
+	conf := NewConfig().AddParser(NewEnvVarParser("test_alias")).AliasOpt("", "addr", "listen_address", "bind")
+	conf.RegisterCliOpt("", Str("addr", "", "the address"))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	} else if v := conf.String("addr"); v != ":84" {
+		t.Errorf("expected the address ':84' from the alias env, got '%s'", v)
+	}
+
+	// The aliases are listed in the help.
+	if opts := conf.HelpData("test", true).Groups[0].Opts; len(opts) != 1 {
+		t.Errorf("expected 1 option in the help, got %d", len(opts))
+	} else if flags := opts[0].Flags; flags != "--addr, --bind, --listen-address" {
+		t.Errorf("expected the flags '--addr, --bind, --listen-address', got '%s'", flags)
+	}
+}
+
+func TestCliParserPositionalArgs(t *testing.T) {
+	cases := []struct {
+		args   []string
+		expect string
+		err    string
+	}{
+		{[]string{"a.txt"}, "src=a.txt count=1 dsts=", ""},
+		{[]string{"a.txt", "3"}, "src=a.txt count=3 dsts=", ""},
+		{[]string{"-v", "a.txt", "3", "b.txt", "c.txt"}, "src=a.txt count=3 dsts=b.txt,c.txt", ""},
+		{[]string{"a.txt", "-1"}, "src=a.txt count=-1 dsts=", ""},
+		{[]string{"--", "-a.txt"}, "src=-a.txt count=1 dsts=", ""},
+		{[]string{"-v"}, "", "missing the argument 'src'"},
+		{[]string{"a.txt", "x"}, "", "invalid argument 'count'"},
+	}
+
+	for _, c := range cases {
+		conf := NewConfig().AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true))
+		conf.RegisterCliOpt("", BoolOpt("v", "verbose", false, ""))
+		conf.RegisterArg(Str("src", "", "the source file"), true)
+		conf.RegisterArg(Int("count", 1, "the count"), false)
+		conf.RegisterArg(Strings("dsts", nil, "the destination files"), false)
+
+		err := conf.Parse(c.args...)
+		if c.err != "" {
+			if err == nil {
+				t.Errorf("%v: expected the error '%s', got nil", c.args, c.err)
+			} else if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%v: expected the error '%s', got '%s'", c.args, c.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%v: %s", c.args, err)
+			continue
+		}
+
+		args := conf.ArgGroup()
+		result := fmt.Sprintf("src=%s count=%d dsts=%s", args.String("src"),
+			args.Int("count"), strings.Join(args.Strings("dsts"), ","))
+		if result != c.expect {
+			t.Errorf("%v: expected '%s', got '%s'", c.args, c.expect, result)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect a panic for the argument after the variadic one")
+			}
+		}()
+		NewConfig().RegisterArg(Strings("srcs", nil, ""), true).RegisterArg(Str("dst", "", ""), true)
+	}()
+}
+
+type testPrompter struct {
+	inputs  []string
+	prompts []string
+}
+
+func (p *testPrompter) Prompt(prompt string, secret bool) (string, error) {
+	if secret {
+		prompt = "[secret] " + prompt
+	}
+	p.prompts = append(p.prompts, prompt)
+	if len(p.inputs) == 0 {
+		return "", fmt.Errorf("no input")
+	}
+
+	input := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	return input, nil
+}
+
+func TestPromptMissingOpts(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		inputs  []string
+		expect  string
+		prompts []string
+		err     string
+	}{
+		{
+			name:   "prompt all the missing",
+			inputs: []string{"123456", "3306", "root"},
+			expect: "user=root pass=123456 port=3306",
+			prompts: []string{
+				"[secret] Please input the option 'db.pass' (the password): ",
+				"Please input the option 'db.port': ",
+				"Please input the option 'db.user' (the user): ",
+			},
+		},
+		{
+			name:   "prompt only the missing",
+			args:   []string{"--db.user=admin", "--db.port=3307"},
+			inputs: []string{"123456"},
+			expect: "user=admin pass=123456 port=3307",
+			prompts: []string{
+				"[secret] Please input the option 'db.pass' (the password): ",
+			},
+		},
+		{
+			name:   "prompt again for the invalid",
+			args:   []string{"--db.user=admin", "--db.pass=123456"},
+			inputs: []string{"abc", "3306"},
+			expect: "user=admin pass=123456 port=3306",
+			prompts: []string{
+				"Please input the option 'db.port': ",
+				"Please input the option 'db.port': ",
+			},
+		},
+		{
+			name:   "still missing for the empty",
+			args:   []string{"--db.user=admin", "--db.pass=123456"},
+			inputs: []string{""},
+			prompts: []string{
+				"Please input the option 'db.port': ",
+			},
+			err: "port",
+		},
+		{
+			name: "prompt error",
+			args: []string{"--db.user=admin", "--db.pass=123456"},
+			prompts: []string{
+				"Please input the option 'db.port': ",
+			},
+			err: "no input",
+		},
+	}
+
+	for _, c := range cases {
+		prompter := &testPrompter{inputs: c.inputs}
+		conf := NewConfig().AddParser(NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)).
+			SetPrompter(prompter).MarkRequired("db", "user", "pass", "port")
+		conf.RegisterCliOpt("db", Str("user", "", "the user"))
+		conf.RegisterCliOpt("db", SecretStr("pass", "", "the password"))
+		conf.RegisterCliOpt("db", Int("port", 0, ""))
+		conf.SetDebug(true) // Print the warnings by Printf, not to os.Stderr.
+
+		err := conf.Parse(append(c.args, "--")...) // Not to parse os.Args.
+		if prompts := strings.Join(prompter.prompts, "|"); prompts != strings.Join(c.prompts, "|") {
+			t.Errorf("%s: expected the prompts '%s', got '%s'", c.name, strings.Join(c.prompts, "|"), prompts)
+		}
+		if c.err != "" {
+			if err == nil {
+				t.Errorf("%s: expected the error '%s', got nil", c.name, c.err)
+			} else if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected the error '%s', got '%s'", c.name, c.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+
+		db := conf.Group("db")
+		result := fmt.Sprintf("user=%s pass=%s port=%d", db.String("user"),
+			db.Value("pass").(Secret).Unmask(), db.Int("port"))
+		if result != c.expect {
+			t.Errorf("%s: expected '%s', got '%s'", c.name, c.expect, result)
+		}
+	}
+}
+
+func TestNormalizeFlagArgs(t *testing.T) {
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Bool("a", false, "")
+	fset.Bool("b", false, "")
+	fset.Bool("v", false, "")
+	fset.String("o", "", "")
+	fset.Int("n", 0, "")
+	fset.Bool("debug", false, "")
+	fset.String("output", "", "")
+
+	cases := []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"-ab"}, []string{"-a", "-b"}},
+		{[]string{"-abv", "x"}, []string{"-a", "-b", "-v", "x"}},
+		{[]string{"-vofile"}, []string{"-v", "-o", "file"}},
+		{[]string{"-vo", "file"}, []string{"-v", "-o", "file"}},
+		{[]string{"-avn", "-5"}, []string{"-a", "-v", "-n", "-5"}},
+		{[]string{"-o", "-ab"}, []string{"-o", "-ab"}},
+		{[]string{"-o=x", "-ab"}, []string{"-o=x", "-a", "-b"}},
+		{[]string{"-debug", "false", "-ab"}, []string{"-debug=false", "-a", "-b"}},
+		{[]string{"--debug", "x"}, []string{"--debug", "x"}},
+		{[]string{"-ax"}, []string{"-ax"}},
+		{[]string{"--ab"}, []string{"--ab"}},
+		{[]string{"-5", "-ab"}, []string{"--", "-5", "-ab"}},
+		{[]string{"-a", "x", "-b"}, []string{"-a", "x", "-b"}},
+		{[]string{"-a", "--", "-b"}, []string{"-a", "--", "-b"}},
+		{[]string{"-n", "-5", "-ab"}, []string{"-n", "-5", "-a", "-b"}},
+	}
+
+	for _, c := range cases {
+		if result := normalizeFlagArgs(fset, c.args); strings.Join(result, " ") != strings.Join(c.expect, " ") {
+			t.Errorf("%v: expected %v, got %v", c.args, c.expect, result)
+		}
+	}
+}
+
+func TestConvertSlashFlags(t *testing.T) {
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Bool("verbose", false, "")
+	fset.String("output", "", "")
+	fset.String("db.conn", "", "")
+
+	cases := []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"/verbose"}, []string{"-verbose"}},
+		{[]string{"/VERBOSE"}, []string{"-verbose"}},
+		{[]string{"/output:a.txt"}, []string{"-output=a.txt"}},
+		{[]string{"/Output:C:\\a.txt"}, []string{"-output=C:\\a.txt"}},
+		{[]string{"/output:"}, []string{"-output="}},
+		{[]string{"/db.conn:x", "/verbose"}, []string{"-db.conn=x", "-verbose"}},
+		{[]string{"/?"}, []string{"-help"}},
+		{[]string{"/unknown", "/tmp/a.txt"}, []string{"/unknown", "/tmp/a.txt"}},
+		{[]string{"-verbose", "/output", "x"}, []string{"-verbose", "-output", "x"}},
+		{[]string{"/", "x"}, []string{"/", "x"}},
+		{[]string{"/verbose", "--", "/output:x"}, []string{"-verbose", "--", "/output:x"}},
+	}
+
+	for _, c := range cases {
+		if result := convertSlashFlags(fset, c.args); strings.Join(result, " ") != strings.Join(c.expect, " ") {
+			t.Errorf("%v: expected %v, got %v", c.args, c.expect, result)
+		}
+	}
+}
+
+func TestCliParserDidYouMean(t *testing.T) {
+	parsers := map[string]func() Parser{
+		"flag": func() Parser {
+			return NewFlagCliParser(flag.NewFlagSet("test", flag.ContinueOnError), true)
+		},
+		"pflag": func() Parser {
+			return NewPFlagCliParser(pflag.NewFlagSet("test", pflag.ContinueOnError), true)
+		},
+		"getopt": func() Parser { return NewGetoptCliParser("", true) },
+	}
+
+	cases := []struct {
+		arg    string
+		expect map[string]string
+	}{
+		{"--prot=80", map[string]string{
+			"flag":   "flag provided but not defined: -prot, did you mean -port?",
+			"pflag":  "unknown flag: --prot, did you mean --port?",
+			"getopt": "unrecognized option '--prot', did you mean --port?",
+		}},
+		{"--maxconn=1", map[string]string{
+			"flag":   "flag provided but not defined: -maxconn, did you mean -db.mysql.max-conn?",
+			"pflag":  "unknown flag: --maxconn, did you mean --db.mysql.max-conn?",
+			"getopt": "unrecognized option '--maxconn', did you mean --db.mysql.max-conn?",
+		}},
+		{"--db.mysql.max-con=1", map[string]string{
+			"flag":   "flag provided but not defined: -db.mysql.max-con, did you mean -db.mysql.max-conn?",
+			"pflag":  "unknown flag: --db.mysql.max-con, did you mean --db.mysql.max-conn?",
+			"getopt": "",
+		}},
+		{"--porter=80", map[string]string{
+			"flag":   "flag provided but not defined: -porter, did you mean -port, -ports?",
+			"pflag":  "unknown flag: --porter, did you mean --port, --ports?",
+			"getopt": "unrecognized option '--porter', did you mean --port, --ports?",
+		}},
+		{"--xyz=1", map[string]string{
+			"flag":   "flag provided but not defined: -xyz",
+			"pflag":  "unknown flag: --xyz",
+			"getopt": "unrecognized option '--xyz'",
+		}},
+	}
+
+	for pname, newParser := range parsers {
+		for _, c := range cases {
+			conf := NewConfig().AddParser(newParser())
+			conf.RegisterCliOpt("", Int("port", 0, ""))
+			conf.RegisterCliOpt("", Ints("ports", nil, ""))
+			conf.RegisterCliOpt("db.mysql", Int("max_conn", 0, ""))
+
+			expect := c.expect[pname]
+			err := conf.Parse(c.arg)
+			if expect == "" { // The unique prefix of the long option by getopt
+				if err != nil {
+					t.Errorf("%s %s: %s", pname, c.arg, err)
+				}
+				continue
+			}
+
+			if err == nil {
+				t.Errorf("%s %s: expected the error '%s', got nil", pname, c.arg, expect)
+			} else if msg := err.Error(); !strings.HasSuffix(msg, expect) {
+				t.Errorf("%s %s: expected the error '%s', got '%s'", pname, c.arg, expect, msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathCheck is the check of the file or directory option when parsing it,
+// which may be combined by "|", such as PathExist|PathReadable.
+type PathCheck int
+
+// Predefine some path checks.
+const (
+	// PathExpand expands the prefix "~" to the home directory
+	// and converts the path to the absolute path.
+	PathExpand PathCheck = 1 << iota
+
+	// PathExist checks whether the path exists.
+	PathExist
+
+	// PathReadable checks whether the path exists and is readable.
+	PathReadable
+
+	// PathWritable checks whether the path is writable. If the path
+	// does not exist, it checks whether its parent directory exists.
+	PathWritable
+)
+
+type pathOpt struct {
+	baseOpt
+	dir    bool
+	checks PathCheck
+}
+
+func newPathOpt(short, name, _default, help string, dir bool, checks PathCheck) pathOpt {
+	return pathOpt{
+		baseOpt: newBaseOpt(short, name, _default, help, stringType),
+		dir:     dir,
+		checks:  checks,
+	}
+}
+
+// FileOpt returns a new file path option, the value of which is a string.
+//
+// When parsing the value, it checks the path by checks, such as
+// PathExpand|PathExist, and returns the error if the path is not the file.
+// The empty path is not checked.
+func FileOpt(short, name, _default, help string, checks PathCheck) ValidatorChainOpt {
+	return newPathOpt(short, name, _default, help, false, checks)
+}
+
+// DirOpt is the same as FileOpt, but the path is the directory.
+func DirOpt(short, name, _default, help string, checks PathCheck) ValidatorChainOpt {
+	return newPathOpt(short, name, _default, help, true, checks)
+}
+
+// File is equal to FileOpt("", name, _default, help, checks).
+func File(name, _default, help string, checks PathCheck) ValidatorChainOpt {
+	return FileOpt("", name, _default, help, checks)
+}
+
+// Dir is equal to DirOpt("", name, _default, help, checks).
+func Dir(name, _default, help string, checks PathCheck) ValidatorChainOpt {
+	return DirOpt("", name, _default, help, checks)
+}
+
+func (o pathOpt) kind() string {
+	if o.dir {
+		return "directory"
+	}
+	return "file"
+}
+
+// SetValidators resets the validator chain.
+func (o pathOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o pathOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
+
+// Parse parses the value of the option to the path and checks it.
+func (o pathOpt) Parse(data interface{}) (interface{}, error) {
+	path, err := ToString(data)
+	if err != nil || path == "" {
+		return path, err
+	}
+
+	if o.checks&PathExpand != 0 {
+		if path, err = expandPath(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.checks&(PathExist|PathReadable|PathWritable) == 0 {
+		return path, nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		} else if o.checks&(PathExist|PathReadable) != 0 {
+			return nil, fmt.Errorf("the %s '%s' does not exist", o.kind(), path)
+		} else if _, err = os.Stat(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("the %s '%s' is not writable: %s", o.kind(), path, err)
+		}
+		return path, nil
+	}
+
+	if fi.IsDir() != o.dir {
+		return nil, fmt.Errorf("'%s' is not a %s", path, o.kind())
+	}
+
+	if o.checks&PathReadable != 0 {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("the %s '%s' is not readable: %s", o.kind(), path, err)
+		}
+		f.Close()
+	}
+
+	if o.checks&PathWritable != 0 {
+		if o.dir {
+			f, err := ioutil.TempFile(path, ".writable")
+			if err != nil {
+				return nil, fmt.Errorf("the directory '%s' is not writable: %s", path, err)
+			}
+			f.Close()
+			os.Remove(f.Name())
+		} else {
+			f, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return nil, fmt.Errorf("the file '%s' is not writable: %s", path, err)
+			}
+			f.Close()
+		}
+	}
+
+	return path, nil
+}
+
+// expandPath expands the prefix "~" of the path to the home directory
+// and returns the absolute path.
+func expandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home := os.Getenv("HOME")
+		if home == "" {
+			home = os.Getenv("USERPROFILE")
+		}
+		if home == "" {
+			return "", fmt.Errorf("cannot expand '%s': the home directory is unknown", path)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Abs(path)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type percentOpt struct {
+	baseOpt
+}
+
+// PercentOpt returns a new percentage option, such as the sampling rate,
+// the value of which is a float64 in [0, 1].
+//
+// The value may be the ratio, such as 0.75, or the percentage, such as "75"
+// or "75%", both of which are normalized to 0.75 by ToPercent. The value out
+// of the range is rejected when parsing.
+func PercentOpt(short, name string, _default float64, help string) ValidatorChainOpt {
+	v, err := ToPercent(_default)
+	if err != nil {
+		panic(fmt.Errorf("the default of the percent option '%s' is invalid: %s", name, err))
+	}
+	return percentOpt{newBaseOpt(short, name, v, help, float64Type)}
+}
+
+// Percent is equal to PercentOpt("", name, _default, help).
+func Percent(name string, _default float64, help string) ValidatorChainOpt {
+	return PercentOpt("", name, _default, help)
+}
+
+// SetValidators resets the validator chain.
+func (o percentOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o percentOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
+
+// Parse parses the value of the option to the ratio in [0, 1].
+func (o percentOpt) Parse(data interface{}) (interface{}, error) {
+	return ToPercent(data)
+}
+
+// ToPercent does the best to convert a certain value to the ratio in [0, 1].
+//
+// The string with the suffix "%", such as "75%", is the percentage. Or, the
+// number in [0, 1] is the ratio and the number in (1, 100] is the percentage,
+// so use "1%" instead of 1 for one percent.
+func ToPercent(_v interface{}) (v float64, err error) {
+	var percent bool
+	switch s := _v.(type) {
+	case string:
+		s = strings.TrimSpace(s)
+		if strings.HasSuffix(s, "%") {
+			s, percent = strings.TrimSpace(s[:len(s)-1]), true
+		}
+		if v, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, fmt.Errorf("invalid percentage '%s'", _v)
+		}
+	case []byte:
+		return ToPercent(string(s))
+	default:
+		if v, err = ToFloat64(_v); err != nil {
+			return
+		}
+	}
+
+	if math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid percentage '%v'", _v)
+	} else if percent || v > 1 {
+		if v < 0 || v > 100 {
+			return 0, fmt.Errorf("the percentage '%v' is out of the range [0%%, 100%%]", _v)
+		}
+		return v / 100, nil
+	} else if v < 0 {
+		return 0, fmt.Errorf("the percentage '%v' is out of the range [0, 1]", _v)
+	}
+	return
+}
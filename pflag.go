@@ -0,0 +1,337 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type pflagParser struct {
+	utoh bool
+	fset *pflag.FlagSet
+}
+
+// NewPFlagCliParser returns a new CLI parser based on github.com/spf13/pflag,
+// which supports the POSIX/GNU-style flags, such as "-v", "-abc", "-f value",
+// "--flag value" and "--flag=value".
+//
+// If flagSet is nil, it will create a default pflag.FlagSet, which is equal to
+//
+//    pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+//
+// If underlineToHyphen is true, it will convert the underline to the hyphen.
+//
+// The short name of the option will be registered as the shorthand flag if it
+// is a single character. For the slice options, such as []string and []int,
+// the values can be given by the comma-separated form, or by repeating the flag.
+//
+// The value can be given by "--flag=value" or "--flag value" for all the types,
+// including the bool flag, such as "--debug false". The negative number, such
+// as "-5", which is not the value of the flag, is the non-flag argument.
+func NewPFlagCliParser(flagSet *pflag.FlagSet, underlineToHyphen bool) Parser {
+	if flagSet == nil {
+		flagSet = pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+	}
+
+	return pflagParser{
+		fset: flagSet,
+		utoh: underlineToHyphen,
+	}
+}
+
+func (f pflagParser) Name() string {
+	return "pflag"
+}
+
+func (f pflagParser) isCliParser() {}
+
+func (f pflagParser) Priority() int {
+	return 0
+}
+
+// Pre reports the conflicts of the names of the CLI options, which are
+// the same after converting the underlines to the hyphens or have been
+// defined in the flag set, and of the shorthands which have been used
+// by the flags of the flag set.
+func (f pflagParser) Pre(c *Config) error {
+	names := make(map[string]string, 16)
+	for _, group := range c.Groups() {
+		for _, opt := range group.CliOpts() {
+			key := c.optKey(group.FullName(), opt.Name())
+			name := c.cliOptName(group.FullName(), opt.Name(), f.utoh)
+			if other, ok := names[name]; ok {
+				c.addConflict(fmt.Errorf("the flag '%s' of the option '%s' has been used by the option '%s'",
+					name, key, other))
+			} else if f.fset.Lookup(name) != nil {
+				c.addConflict(fmt.Errorf("the flag '%s' of the option '%s' has been defined",
+					name, key))
+			}
+			names[name] = key
+
+			if s := opt.Short(); len(s) == 1 {
+				if fg := f.fset.ShorthandLookup(s); fg != nil {
+					c.addConflict(fmt.Errorf(
+						"the short name '%s' of the option '%s' has been used by the flag '%s'",
+						s, key, fg.Name))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f pflagParser) Post(c *Config) error {
+	return nil
+}
+
+func (f pflagParser) Parse(c *Config) (err error) {
+	// Convert the option name.
+	name2group := make(map[string]string, 8)
+	name2opt := make(map[string]string, 8)
+	slices := make(map[string]bool, 8)
+	for _, group := range c.Groups() {
+		gname := group.FullName()
+		for _, opt := range group.CliOpts() {
+			name := opt.Name()
+			if gname != c.GetDefaultGroupName() {
+				name = fmt.Sprintf("%s%s%s", gname, c.GetGroupSeparator(), name)
+			}
+
+			if f.utoh {
+				name = strings.Replace(name, "_", "-", -1)
+			}
+
+			// The conflicts of the names have been reported by Pre.
+			if _, ok := name2group[name]; ok {
+				continue
+			}
+
+			name2group[name] = gname
+			name2opt[name] = opt.Name()
+
+			// The conflicts of the shorthands have been reported by Pre.
+			var short string
+			if s := opt.Short(); len(s) == 1 && f.fset.ShorthandLookup(s) == nil {
+				short = s
+			}
+
+			usage := c.cliOptUsage(gname, opt)
+			zero := opt.Zero()
+			if isCountOpt(opt) {
+				zero = countValue(0)
+			} else if isSizeOpt(opt) {
+				zero = "" // The size accepts the human-readable string, such as "2GB".
+			} else if _, ok := opt.(percentOpt); ok {
+				zero = "" // The percentage accepts the string with "%", such as "75%".
+			}
+
+			switch zero.(type) {
+			case countValue:
+				f.fset.CountP(name, short, usage)
+			case bool:
+				var _default bool
+				if v := opt.Default(); v != nil {
+					_default = v.(bool)
+				}
+				f.fset.BoolP(name, short, _default, usage)
+			case int, int8, int16, int32, int64:
+				var _default int64
+				if v := opt.Default(); v != nil {
+					_default, _ = ToInt64(v)
+				}
+				f.fset.Int64P(name, short, _default, usage)
+			case uint, uint8, uint16, uint32, uint64:
+				var _default uint64
+				if v := opt.Default(); v != nil {
+					_default, _ = ToUint64(v)
+				}
+				f.fset.Uint64P(name, short, _default, usage)
+			case float32, float64:
+				var _default float64
+				if v := opt.Default(); v != nil {
+					_default, _ = ToFloat64(v)
+				}
+				f.fset.Float64P(name, short, _default, usage)
+			case time.Duration:
+				var _default time.Duration
+				if v := opt.Default(); v != nil {
+					_default = v.(time.Duration)
+				}
+				f.fset.DurationP(name, short, _default, usage)
+			case []string, []int, []int64, []uint, []uint64, []float64,
+				[]time.Duration, []time.Time, []*net.IPNet:
+				var _default []string
+				if v := opt.Default(); v != nil {
+					_default = toStringSlice(v)
+				}
+				f.fset.StringSliceP(name, short, _default, usage)
+				slices[name] = true
+			default:
+				var _default string
+				if v := opt.Default(); v != nil {
+					_default = fmt.Sprintf("%v", v)
+				}
+				f.fset.StringP(name, short, _default, usage)
+			}
+		}
+	}
+
+	// Register the aliases and the old names of the deprecated options,
+	// which are hidden.
+	for _, d := range c.optRedirects() {
+		if d.newName == "" {
+			continue
+		}
+
+		old := c.cliOptName(d.group, d.name, f.utoh)
+		newName := c.cliOptName(d.newGroup, d.newName, f.utoh)
+		target := f.fset.Lookup(newName)
+		if target != nil && f.fset.Lookup(old) == nil {
+			f.fset.Var(target.Value, old, d.usage(c, f.utoh))
+			f.fset.MarkHidden(old)
+			name2group[old] = d.group
+			name2opt[old] = d.name
+			slices[old] = slices[newName]
+		}
+	}
+
+	// Register the built-in flags, such as the version.
+	builtins := c.registerBuiltinFlags(f.fset, func(name, usage string) *[]string {
+		return f.fset.StringArray(name, nil, usage)
+	})
+	if cname := c.GetCompletion(); cname != "" {
+		f.fset.MarkHidden(cname)
+	}
+
+	// Render the help output by the help template.
+	f.fset.Usage = func() { c.PrintHelp(f.fset.Output(), filepath.Base(os.Args[0]), f.utoh) }
+
+	// Parse the CLI arguments.
+	if err = f.fset.Parse(normalizePFlagArgs(f.fset, c.CliArgs())); err != nil {
+		const unknown = "unknown flag: --"
+		if msg := err.Error(); strings.HasPrefix(msg, unknown) {
+			var names []string
+			f.fset.VisitAll(func(fg *pflag.Flag) {
+				if !fg.Hidden {
+					names = append(names, fg.Name)
+				}
+			})
+			err = c.didYouMean(err, msg[len(unknown):], "--", names)
+		}
+		return
+	}
+
+	if err = builtins.run(c, filepath.Base(os.Args[0]), f.utoh); err != nil {
+		return
+	}
+
+	// Acquire the result.
+	c.SetArgs(f.fset.Args())
+	f.fset.Visit(func(fg *pflag.Flag) {
+		c.Printf("[%s] Parsing flag '%s'", f.Name(), fg.Name)
+		gname := name2group[fg.Name]
+		optname := name2opt[fg.Name]
+		if gname == "" || optname == "" || fg.Name == builtins.version {
+			return
+		}
+
+		value := fg.Value.String()
+		if slices[fg.Name] {
+			vs, _ := f.fset.GetStringSlice(fg.Name)
+			value = strings.Join(vs, ",")
+		}
+
+		if e := c.SetOptValue(0, gname, optname, value); e != nil && err == nil {
+			err = e
+		}
+	})
+
+	if err == nil {
+		err = builtins.override(c)
+	}
+	return
+}
+
+// normalizePFlagArgs normalizes the CLI arguments for the pflag package:
+//
+//   1. Convert "--flag true" and "--flag false" to "--flag=true" and
+//      "--flag=false" for the bool flag.
+//   2. Move the non-flag arguments after "--" if there is a negative number
+//      which is not the value of the flag, such as "-5", so it is regarded as
+//      the non-flag argument, not the shorthand flag.
+func normalizePFlagArgs(fset *pflag.FlagSet, args []string) []string {
+	var hasNegative bool
+	results := make([]string, 0, len(args))
+	flags := make([]string, 0, len(args))
+	nonflags := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			results = append(results, args[i:]...)
+			nonflags = append(nonflags, args[i+1:]...)
+			i = len(args)
+			continue
+		case len(arg) < 2 || arg[0] != '-' || isNegativeNumber(arg):
+			hasNegative = hasNegative || isNegativeNumber(arg)
+			results = append(results, arg)
+			nonflags = append(nonflags, arg)
+			continue
+		}
+
+		values := []string{arg}
+		if arg[1] == '-' {
+			// The long flag
+			if fg := fset.Lookup(arg[2:]); fg != nil && i+1 < len(args) {
+				if fg.NoOptDefVal == "" {
+					i++
+					values = append(values, args[i])
+				} else if fg.Value.Type() == "bool" && isBoolValue(args[i+1]) {
+					i++
+					values[0] = fmt.Sprintf("%s=%s", arg, args[i])
+				}
+			}
+		} else {
+			// The shorthand flags, the last one of which may take the value.
+			for j := 1; j < len(arg); j++ {
+				fg := fset.ShorthandLookup(arg[j : j+1])
+				if fg == nil || fg.NoOptDefVal != "" {
+					continue
+				} else if j+1 == len(arg) && i+1 < len(args) {
+					i++
+					values = append(values, args[i])
+				}
+				break
+			}
+		}
+
+		results = append(results, values...)
+		flags = append(flags, values...)
+	}
+
+	if hasNegative {
+		return append(append(flags, "--"), nonflags...)
+	}
+	return results
+}
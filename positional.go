@@ -0,0 +1,131 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ArgGroupName is the name of the group of the positional arguments.
+const ArgGroupName = "ARGS"
+
+// isVariadicArg reports whether the positional argument consumes all the rest
+// arguments, that's, the type of the option is a slice, such as []string.
+func isVariadicArg(opt Opt) bool {
+	zero := opt.Zero()
+	if _, ok := zero.(json.RawMessage); ok {
+		return false
+	}
+	return zero != nil && reflect.TypeOf(zero).Kind() == reflect.Slice
+}
+
+type argOpt struct {
+	Opt
+	required bool
+}
+
+// RegisterArg registers the positional argument declared by the option,
+// which is parsed from the rest arguments, Args(), in the registration order
+// after all the parsers run, and can be got by the typed getters of ArgGroup(),
+// such as
+//
+//    conf.RegisterArg(config.Str("src", "", "The source file"), true)
+//    conf.RegisterArg(config.Strings("dsts", nil, "The destination files"), false)
+//    conf.Parse()
+//    src := conf.ArgGroup().String("src")
+//    dsts := conf.ArgGroup().Strings("dsts")
+//
+// The name, the type and the help of the option are used by the argument.
+// The argument whose type is a slice is variadic, which consumes all the rest
+// arguments, so it must be the last, and it needs one argument at least if
+// required. If the optional argument is not given, it's the default value of
+// the option, so all the arguments after it must be optional, too.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) RegisterArg(opt Opt, required bool) *Config {
+	c.panicIsParsed(true)
+	if n := len(c.argOpts); n > 0 {
+		last := c.argOpts[n-1]
+		if isVariadicArg(last) {
+			panic(fmt.Errorf("the variadic argument '%s' must be the last", last.Name()))
+		} else if !last.required && required {
+			panic(fmt.Errorf("the required argument '%s' must not follow the optional argument '%s'",
+				opt.Name(), last.Name()))
+		}
+	}
+
+	c.ArgGroup().registerOpt(false, opt)
+	c.argOpts = append(c.argOpts, argOpt{Opt: opt, required: required})
+	return c
+}
+
+// ArgGroup returns the group of the positional arguments registered
+// by RegisterArg, which is used to get the values by the typed getters.
+//
+// Notice: the group does not belong to the groups of the options, so it
+// cannot be got by Group().
+func (c *Config) ArgGroup() *OptGroup {
+	if c.argGrp == nil {
+		c.argGrp = newOptGroup(ArgGroupName, ArgGroupName, c)
+	}
+	return c.argGrp
+}
+
+// parseArgs parses the positional arguments registered by RegisterArg
+// from the rest arguments.
+func (c *Config) parseArgs() (err error) {
+	if len(c.argOpts) == 0 {
+		return nil
+	}
+
+	args := c.Args()
+	for _, opt := range c.argOpts {
+		var value interface{}
+		if isVariadicArg(opt) {
+			if len(args) > 0 {
+				if _, ok := opt.Zero().([]string); ok {
+					value = args
+				} else {
+					value = strings.Join(args, ",")
+				}
+				args = nil
+			}
+		} else if len(args) > 0 {
+			value, args = args[0], args[1:]
+		}
+
+		if value == nil {
+			if opt.required {
+				return fmt.Errorf("missing the argument '%s'", opt.Name())
+			} else if value = opt.Default(); value == nil {
+				continue
+			}
+		}
+
+		if err = c.argGrp.setOptValue(0, opt.Name(), value); err != nil {
+			return fmt.Errorf("invalid argument '%s': %s", opt.Name(), err)
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments: %s", strings.Join(args, " "))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SetPrintConfig sets the information of the CLI option to print the fully
+// resolved configuration after all the parsers run, then exit.
+//
+// It supports:
+//     SetPrintConfig()           // SetPrintConfig("print-config")
+//     SetPrintConfig(name)       // SetPrintConfig("print-config")
+//     SetPrintConfig(name, help) // SetPrintConfig("print-config", "Print the config")
+//
+// Notice: it is for the CLI parser.
+func (c *Config) SetPrintConfig(args ...string) *Config {
+	name := "print-config"
+	help := "Print the resolved configuration and exit."
+	if len(args) == 1 {
+		name = args[0]
+	} else if len(args) > 1 {
+		name = args[0]
+		help = args[1]
+	}
+
+	if name == "" || help == "" {
+		panic(fmt.Errorf("The arguments about print config must not be empty"))
+	}
+
+	c.pName = name
+	c.pHelp = help
+	return c
+}
+
+// GetPrintConfig returns the information about the print config option.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) GetPrintConfig() (name, help string) {
+	return c.pName, c.pHelp
+}
+
+// requestPrintConfig is called by the CLI parser when the print config option
+// is given, then Parse will print the configuration and exit at last.
+func (c *Config) requestPrintConfig() {
+	c.pRequested = true
+}
+
+// formatOptValue formats the option value as the string, which can be parsed
+// by the option again. The slice is separated by the comma.
+func formatOptValue(v interface{}) string {
+	switch _v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return _v.Format(time.RFC3339Nano)
+	case []time.Time:
+		vs := make([]string, len(_v))
+		for i, t := range _v {
+			vs[i] = t.Format(time.RFC3339Nano)
+		}
+		return strings.Join(vs, ",")
+	case []string:
+		return strings.Join(_v, ",")
+	case json.RawMessage:
+		return string(_v)
+	case tls.Certificate:
+		return certificateSummary(_v)
+	case *big.Int:
+		if _v == nil {
+			return ""
+		}
+		return _v.String()
+	case *net.IPNet:
+		if _v == nil {
+			return ""
+		}
+		return _v.String()
+	case encoding.TextMarshaler:
+		if text, err := _v.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	s := fmt.Sprintf("%v", v)
+	if len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']' {
+		s = strings.Replace(s[1:len(s)-1], " ", ",", -1)
+	}
+	return s
+}
+
+// sortedGroups returns the groups having the options, which are sorted by
+// the full name and the default group is the first.
+func (c *Config) sortedGroups() []*OptGroup {
+	parsed := make(map[string]bool, 8)
+	groups := make([]*OptGroup, 0, 8)
+	for _, group := range c.Groups() {
+		if gname := group.FullName(); !parsed[gname] {
+			parsed[gname] = true
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		gi, gj := groups[i].FullName(), groups[j].FullName()
+		if gi == c.groupName || gj == c.groupName {
+			return gi == c.groupName && gj != c.groupName
+		}
+		return gi < gj
+	})
+	return groups
+}
+
+// PrintConfig writes the current values of all the options into w by the INI
+// format, which are sorted by the group and the option name. The value of the
+// secret option, see SetPrompter, is masked.
+func (c *Config) PrintConfig(w io.Writer) error {
+	buf := bytes.NewBuffer(nil)
+	for _, group := range c.sortedGroups() {
+		gname := group.FullName()
+		opts := group.AllOpts()
+		sort.Slice(opts, func(i, j int) bool { return opts[i].Name() < opts[j].Name() })
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "[%s]\n", gname)
+		for _, opt := range opts {
+			value := formatOptValue(group.Value(opt.Name()))
+			if optIsSecret(opt) && value != "" {
+				value = SecretMask
+			}
+			fmt.Fprintf(buf, "%s = %s\n", opt.Name(), encodeMultiLineValue(value))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Prompter is used to prompt the user to input the values of the missing
+// required options.
+type Prompter interface {
+	// Prompt shows the prompt and returns the input without the trailing
+	// newline. If secret is true, the input should not be echoed.
+	Prompt(prompt string, secret bool) (string, error)
+}
+
+type terminalPrompter struct {
+	in     *os.File
+	out    io.Writer
+	reader *bufio.Reader
+}
+
+// NewTerminalPrompter returns a Prompter to read the input from os.Stdin and
+// write the prompt into os.Stderr, which returns nil if os.Stdin is not a TTY.
+// So it can be passed to SetPrompter directly, for example,
+//
+//    conf.SetPrompter(config.NewTerminalPrompter())
+//
+// Notice: the secret input is masked by "stty -echo", so it is echoed if the
+// command stty does not exist, such as on Windows.
+func NewTerminalPrompter() Prompter {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return terminalPrompter{in: os.Stdin, out: os.Stderr, reader: bufio.NewReader(os.Stdin)}
+}
+
+func (p terminalPrompter) stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = p.in
+	return cmd.Run()
+}
+
+func (p terminalPrompter) Prompt(prompt string, secret bool) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	if secret && p.stty("-echo") == nil {
+		defer func() {
+			p.stty("echo")
+			fmt.Fprintln(p.out)
+		}()
+	}
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// SetPrompter sets the prompter to prompt the user to input the values of the
+// missing required options after all the parsers run, instead of returning
+// an error. The default is nil, that's, not to prompt.
+//
+// The option is secret, the input of which is not echoed, if the option or one
+// of its validators has the method "IsSecret() bool" and it returns true.
+//
+// If the input is empty, the option is still missing. If the input is invalid,
+// it will prompt again.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetPrompter(p Prompter) *Config {
+	c.panicIsParsed(true)
+	c.prompter = p
+	return c
+}
+
+// optIsSecret reports whether the option is secret.
+func optIsSecret(opt Opt) bool {
+	type secret interface {
+		IsSecret() bool
+	}
+
+	if s, ok := opt.(secret); ok {
+		return s.IsSecret()
+	}
+
+	if vopt, ok := opt.(ValidatorChainOpt); ok {
+		for _, v := range vopt.GetValidators() {
+			if s, ok := v.(secret); ok && s.IsSecret() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// promptMissingOpts prompts the user to input the values of the required
+// options that have not been set by the parsers.
+func (c *Config) promptMissingOpts() error {
+	if c.prompter == nil {
+		return nil
+	}
+
+	groups := c.Groups()
+	sort.Slice(groups, func(i, j int) bool { return groups[i].FullName() < groups[j].FullName() })
+
+	parsed := make(map[string]bool, 8)
+	for _, group := range groups {
+		gname := group.FullName()
+		if parsed[gname] {
+			continue
+		}
+		parsed[gname] = true
+
+		opts := group.AllOpts()
+		sort.Slice(opts, func(i, j int) bool { return opts[i].Name() < opts[j].Name() })
+		for _, opt := range opts {
+			if group.isSet(opt.Name()) || !c.isOptMissing(gname, opt) {
+				continue
+			}
+
+			prompt := fmt.Sprintf("Please input the option '%s'", c.optKey(gname, opt.Name()))
+			if help := c.optHelp(gname, opt); help != "" {
+				prompt = fmt.Sprintf("%s (%s)", prompt, help)
+			}
+			prompt += ": "
+
+			for {
+				value, err := c.prompter.Prompt(prompt, optIsSecret(opt))
+				if err != nil {
+					return err
+				} else if value == "" {
+					break
+				}
+
+				if err = c.SetOptValue(0, gname, opt.Name(), value); err == nil {
+					break
+				}
+				c.warnf("invalid value: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isOptMissing reports whether the option will miss if it has not been set,
+// that's, it is marked by MarkRequired, or it has neither the default value
+// nor the ZERO value when SetRequired(true).
+func (c *Config) isOptMissing(group string, opt Opt) bool {
+	if c.isOptRequired(group, opt.Name()) {
+		return true
+	}
+	return c.isRequired && opt.Default() == nil && !(c.isZero && opt.Zero() != nil)
+}
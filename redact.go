@@ -0,0 +1,122 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "encoding/json"
+
+// SecretMask is the string printed instead of the value of Secret.
+const SecretMask = "*****"
+
+// Secret is the value of the secret option, such as the password or the token,
+// which is masked as SecretMask when printed, logged or marshaled, in order to
+// prevent the credentials from leaking accidentally.
+//
+// Use Unmask to get the real value.
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a new Secret with the value.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// Unmask returns the real value of the secret.
+func (s Secret) Unmask() string {
+	return s.value
+}
+
+// IsEmpty reports whether the value of the secret is empty.
+func (s Secret) IsEmpty() bool {
+	return s.value == ""
+}
+
+// String implements the interface fmt.Stringer, which returns SecretMask,
+// or "" if the value is empty.
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+// GoString implements the interface fmt.GoStringer to mask the value for "%#v".
+func (s Secret) GoString() string {
+	return `config.Secret("` + s.String() + `")`
+}
+
+// MarshalText implements the interface encoding.TextMarshaler,
+// which returns the masked value.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MarshalJSON implements the interface json.Marshaler,
+// which returns the masked value.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ToSecret does the best to convert a certain value to Secret.
+func ToSecret(_v interface{}) (Secret, error) {
+	switch v := _v.(type) {
+	case Secret:
+		return v, nil
+	case *Secret:
+		return *v, nil
+	}
+
+	s, err := ToString(_v)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{value: s}, nil
+}
+
+type secretValueOpt struct {
+	baseOpt
+}
+
+// SecretOpt returns a new secret option, the value of which is Secret.
+//
+// The option is secret, so its value is masked by PrintConfig and not echoed
+// when prompting, see SetPrompter.
+func SecretOpt(short, name string, _default string, help string) ValidatorChainOpt {
+	return secretValueOpt{newBaseOpt(short, name, NewSecret(_default), help, secretType)}
+}
+
+// SecretStr is equal to SecretOpt("", name, _default, help).
+func SecretStr(name string, _default string, help string) ValidatorChainOpt {
+	return SecretOpt("", name, _default, help)
+}
+
+// IsSecret reports whether the option is secret, which is always true.
+func (o secretValueOpt) IsSecret() bool {
+	return true
+}
+
+// SetValidators resets the validator chain.
+func (o secretValueOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o secretValueOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
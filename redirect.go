@@ -0,0 +1,186 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// optRedirect redirects the option to another one, which is used by
+// the deprecated options and the aliases.
+type optRedirect struct {
+	group    string
+	name     string
+	newGroup string
+	newName  string
+	msg      string
+	alias    bool
+}
+
+func (d optRedirect) warning(c *Config) string {
+	msg := fmt.Sprintf("the option '%s' is deprecated", c.optKey(d.group, d.name))
+	if d.newName != "" {
+		msg = fmt.Sprintf("%s, please use '%s' instead", msg, c.optKey(d.newGroup, d.newName))
+	}
+	if d.msg != "" {
+		msg = fmt.Sprintf("%s: %s", msg, d.msg)
+	}
+	return msg
+}
+
+// usage returns the usage of the redirected option used by the CLI parser.
+func (d optRedirect) usage(c *Config, underlineToHyphen bool) string {
+	if d.alias {
+		return fmt.Sprintf("The alias of --%s.", c.cliOptName(d.newGroup, d.newName, underlineToHyphen))
+	}
+	return "DEPRECATED: " + d.warning(c)
+}
+
+// DeprecateOpt marks the option optName in the group deprecated with msg.
+//
+// If replacement is not empty, which is the option name with its group, such
+// as "db.mysql.conn" for the option "conn" in the group "db.mysql", optName is
+// regarded as the old name of the replacement, and it has no need to register
+// the old option. The CLI, environment variable and file parsers still accept
+// the old name, but the value will be stored under the replacement.
+//
+// When the value of the deprecated option is set, a warning will be emitted.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) DeprecateOpt(group, optName, replacement, msg string) *Config {
+	c.panicIsParsed(true)
+	return c.deprecateOpt(group, optName, replacement, msg)
+}
+
+func (c *Config) deprecateOpt(group, optName, replacement, msg string) *Config {
+	if optName == "" {
+		panic(fmt.Errorf("the deprecated option name must not be empty"))
+	}
+
+	d := optRedirect{group: c.normalizeGroupName(group), name: optName, msg: msg}
+	if replacement != "" {
+		d.newGroup, d.newName = c.splitOptKey(replacement)
+		d.newGroup = c.normalizeGroupName(d.newGroup)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.redirects == nil {
+		c.redirects = make(map[string]optRedirect, 4)
+	}
+	c.redirects[c.optKey(d.group, d.name)] = d
+	return c
+}
+
+// AliasOpt registers the alternative names of the option optName in the group,
+// which are resolved to the same option by the CLI, environment variable and
+// file parsers, for example,
+//
+//    conf.AliasOpt("", "listen_address", "addr")
+//
+// So the option can be renamed without breaking the existing deployments.
+// Different from DeprecateOpt, it does not emit the warning.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) AliasOpt(group, optName string, aliases ...string) *Config {
+	c.panicIsParsed(true)
+	if optName == "" {
+		panic(fmt.Errorf("the option name must not be empty"))
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.redirects == nil {
+		c.redirects = make(map[string]optRedirect, len(aliases))
+	}
+
+	group = c.normalizeGroupName(group)
+	for _, alias := range aliases {
+		if alias == "" || alias == optName {
+			panic(fmt.Errorf("the alias of the option '%s' is invalid", optName))
+		}
+
+		c.redirects[c.optKey(group, alias)] = optRedirect{group: group, name: alias,
+			newGroup: group, newName: optName, alias: true}
+	}
+	return c
+}
+
+// optAliases returns the aliases of the option.
+func (c *Config) optAliases(group, name string) (aliases []string) {
+	group = c.normalizeGroupName(group)
+	for _, d := range c.optRedirects() {
+		if d.alias && d.newGroup == group && d.newName == name {
+			aliases = append(aliases, d.name)
+		}
+	}
+	sort.Strings(aliases)
+	return
+}
+
+// getOptRedirect returns the redirection information of the option.
+func (c *Config) getOptRedirect(group, name string) (d optRedirect, ok bool) {
+	c.lock.RLock()
+	if len(c.redirects) > 0 {
+		d, ok = c.redirects[c.optKey(group, name)]
+	}
+	c.lock.RUnlock()
+	return
+}
+
+// optRedirects returns the redirection information of all the options.
+func (c *Config) optRedirects() []optRedirect {
+	c.lock.RLock()
+	redirects := make([]optRedirect, 0, len(c.redirects))
+	for _, d := range c.redirects {
+		redirects = append(redirects, d)
+	}
+	c.lock.RUnlock()
+	return redirects
+}
+
+// redirectOpt emits the warning if the option is deprecated,
+// and returns the group and the name of its replacement if having.
+func (c *Config) redirectOpt(group, name string) (string, string) {
+	d, ok := c.getOptRedirect(group, name)
+	if !ok {
+		return group, name
+	}
+
+	if !d.alias {
+		c.warnf("%s", d.warning(c))
+	}
+	if d.newName != "" {
+		return d.newGroup, d.newName
+	}
+	return group, name
+}
+
+// warnf prints the warning message to os.Stderr, or by Printf if debug.
+func (c *Config) warnf(format string, args ...interface{}) {
+	if c.isDebug {
+		c.Printf("[WARNING] "+format, args...)
+	} else {
+		fmt.Fprintf(os.Stderr, "[WARNING] "+format+"\n", args...)
+	}
+}
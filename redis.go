@@ -0,0 +1,143 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RedisClient is the Redis client interface used by the Redis parser.
+//
+// It is a small subset of the Redis commands, so any Redis client,
+// such as github.com/go-redis/redis, can be adapted to it easily.
+type RedisClient interface {
+	// HGetAll returns all the fields and values of the hash stored at key.
+	//
+	// If the key does not exist, it should return an empty map, not an error.
+	HGetAll(key string) (map[string]string, error)
+
+	// Subscribe subscribes to the channel, and returns a channel
+	// to receive the payload of the published messages.
+	Subscribe(channel string) (<-chan string, error)
+}
+
+type redisParser struct {
+	prio    int
+	prefix  string
+	channel string
+	client  RedisClient
+}
+
+// NewRedisParser returns a new parser based on the Redis hashes.
+//
+// Each group is stored in a hash, the key of which is keyPrefix plus the full
+// name of the group, such as "myapp:DEFAULT" and "myapp:db.mysql" if keyPrefix
+// is "myapp:". The fields of the hash are the option names.
+//
+// If channel is not empty, the parser will subscribe to the channel. When
+// a message is published to it, the parser will reload the groups and update
+// the option values by SetOptValue. If the payload of the message is the full
+// name of a group, only that group is reloaded, or all the groups are reloaded.
+//
+// Notice: the fields that have not been registered as the options are ignored.
+func NewRedisParser(priority int, client RedisClient, keyPrefix, channel string) Parser {
+	if client == nil {
+		panic(fmt.Errorf("the Redis client must not be nil"))
+	}
+	return redisParser{prio: priority, client: client, prefix: keyPrefix, channel: channel}
+}
+
+func (p redisParser) Name() string {
+	return "redis"
+}
+
+func (p redisParser) Priority() int {
+	return p.prio
+}
+
+func (p redisParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p redisParser) Post(c *Config) error {
+	return nil
+}
+
+func (p redisParser) Parse(c *Config) (err error) {
+	for _, group := range c.Groups() {
+		if err = p.loadGroup(c, group); err != nil {
+			return
+		}
+	}
+
+	if p.channel != "" && !c.staging {
+		var msgs <-chan string
+		if msgs, err = p.client.Subscribe(p.channel); err != nil {
+			return
+		}
+		c.goWatch(func(ctx context.Context) { p.watch(ctx, c, msgs) })
+	}
+
+	return
+}
+
+func (p redisParser) loadGroup(c *Config, group *OptGroup) error {
+	key := p.prefix + group.FullName()
+	c.Printf("[%s] Parsing hash '%s'", p.Name(), key)
+
+	fields, err := p.client.HGetAll(key)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range fields {
+		if err = c.setOptValueIfExist(p.prio, group.Name(), name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p redisParser) watch(ctx context.Context, c *Config, msgs <-chan string) {
+	for {
+		var msg string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok = <-msgs:
+			if !ok {
+				return
+			}
+		}
+
+		c.Printf("[%s] Receive the invalidation message '%s'", p.Name(), msg)
+
+		groups := c.Groups()
+		if gname := strings.TrimSpace(msg); gname != "" && c.HasGroup(gname) {
+			groups = []*OptGroup{c.Group(gname)}
+		}
+
+		for _, group := range groups {
+			if err := p.loadGroup(c, group); err != nil {
+				c.Printf("[%s] Failed to reload the group '%s': %s", p.Name(), group.FullName(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRedisClient struct {
+	lock   sync.Mutex
+	hashes map[string]map[string]string
+	msgs   chan string
+}
+
+func (c *testRedisClient) HGetAll(key string) (map[string]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	fields := make(map[string]string, len(c.hashes[key]))
+	for name, value := range c.hashes[key] {
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+func (c *testRedisClient) Subscribe(channel string) (<-chan string, error) {
+	return c.msgs, nil
+}
+
+func (c *testRedisClient) hset(key, field, value string) {
+	c.lock.Lock()
+	c.hashes[key][field] = value
+	c.lock.Unlock()
+}
+
+func TestRedisParser(t *testing.T) {
+	client := &testRedisClient{
+		hashes: map[string]map[string]string{
+			"myapp:DEFAULT":  {"port": "80", "unused": "xxx"},
+			"myapp:db.mysql": {"conn": "root@tcp"},
+		},
+		msgs: make(chan string),
+	}
+
+	updated := make(chan interface{}, 1)
+	conf := NewConfig().AddParser(NewRedisParser(50, client, "myapp:", "myapp"))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	conf.Observe(func(group, name string, value interface{}) { updated <- value })
+	client.hset("myapp:db.mysql", "conn", "admin@tcp")
+	client.msgs <- "db.mysql"
+	select {
+	case v := <-updated:
+		if v != "admin@tcp" {
+			t.Errorf("conn: expected 'admin@tcp', got '%v'", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout to wait for the update")
+	}
+
+	// The subscription is not received any more after closed.
+	conf.Close()
+	select {
+	case client.msgs <- "":
+		t.Error("expect the subscription to be stopped")
+	case <-time.After(time.Millisecond * 10):
+	}
+}
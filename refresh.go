@@ -0,0 +1,218 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+)
+
+// RefreshJitter is the max ratio of the random jitter added to the refresh
+// interval of RefreshEvery, so that many instances started at the same time
+// don't refresh the source at the same time.
+var RefreshJitter = 0.1
+
+type refreshParser struct {
+	Parser
+	interval time.Duration
+}
+
+// RefreshEvery wraps the parser to re-invoke it every interval after parsing,
+// which is used for the source without the change notification, such as
+// HTTP, S3 or SQL. The random jitter up to RefreshJitter of interval is added
+// to each interval.
+//
+// The parser is run against the snapshot of the configuration, and only
+// the option values which it changes are applied, so the observers registered
+// by Observe are not notified for the unchanged ones. When refreshing, they are
+// applied all or nothing by the transaction, see Begin. The error to refresh
+// is only logged by Printf.
+//
+// Notice: the wrapped parser should not refresh itself, such as the parser
+// with the interval or watching the source, because it would update
+// the snapshot instead of the configuration.
+func RefreshEvery(p Parser, interval time.Duration) Parser {
+	if p == nil {
+		panic(fmt.Errorf("the parser must not be nil"))
+	} else if interval <= 0 {
+		panic(fmt.Errorf("the refresh interval must be greater than 0"))
+	}
+	return refreshParser{Parser: p, interval: interval}
+}
+
+func (p refreshParser) Parse(c *Config) error {
+	r := &refresher{parser: p.Parser, conf: c, interval: p.interval}
+	if err := r.load(false); err != nil {
+		return err
+	}
+
+	c.goWatch(r.run)
+	return nil
+}
+
+type refresher struct {
+	parser   Parser
+	conf     *Config
+	interval time.Duration
+	values   map[string]map[string]interface{}
+}
+
+func (r *refresher) load(staged bool) error {
+	values, err := r.conf.stageParser(r.parser)
+	if err != nil {
+		return err
+	}
+
+	if staged {
+		err = r.conf.applyStaged(r.parser.Priority(), values, r.values)
+	} else {
+		err = r.conf.applyValues(r.parser.Priority(), values, r.values)
+	}
+
+	if err == nil {
+		r.values = values
+	}
+	return err
+}
+
+func (r *refresher) run(ctx context.Context) {
+	for {
+		interval := r.interval
+		if jitter := int64(float64(interval) * RefreshJitter); jitter > 0 {
+			interval += time.Duration(rand.Int63n(jitter))
+		}
+		if !sleepContext(ctx, interval) {
+			return
+		}
+
+		if err := r.load(true); err != nil {
+			r.conf.Printf("[%s] Failed to refresh: %s", r.parser.Name(), err)
+		}
+	}
+}
+
+// stageParser runs the parser against the snapshot of the configuration,
+// and returns the option values set by it, so the configuration is not
+// changed if the parser fails.
+func (c *Config) stageParser(p Parser) (map[string]map[string]interface{}, error) {
+	snapshot := c.snapshot()
+	if err := p.Parse(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot.parsedValues(), nil
+}
+
+// applyValues sets the option values which are changed compared to last.
+func (c *Config) applyValues(priority int, values, last map[string]map[string]interface{}) error {
+	for group, opts := range values {
+		for name, value := range opts {
+			if v, ok := last[group][name]; ok && reflect.DeepEqual(v, value) {
+				continue
+			}
+
+			if err := c.setOptValueIfExist(priority, group, name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.touchOpts(priority, values)
+	return nil
+}
+
+// applyStaged is the same as applyValues, but sets the option values
+// all or nothing by the transaction, which validates the whole configuration.
+func (c *Config) applyStaged(priority int, values, last map[string]map[string]interface{}) error {
+	txn := c.Begin()
+	for group, opts := range values {
+		for name, value := range opts {
+			if v, ok := last[group][name]; !ok || !reflect.DeepEqual(v, value) {
+				txn.SetOptValue(priority, group, name, value)
+			}
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+	c.touchOpts(priority, values)
+	return nil
+}
+
+// snapshot returns a new Config which has the same options and values
+// as the current one, but does not bind any struct or observer, so the parser
+// can be run against it without side effect.
+func (c *Config) snapshot() *Config {
+	s := NewConfig()
+	s.isDebug = c.isDebug
+	s.staging = true
+	s.groupSep = c.groupSep
+	s.groupName = c.groupName
+	s.groupPrefix = c.groupPrefix
+	c.lock.RLock()
+	s.envNames = make(map[string]string, len(c.envNames))
+	for key, env := range c.envNames {
+		s.envNames[key] = env
+	}
+	s.redirects = make(map[string]optRedirect, len(c.redirects))
+	for key, d := range c.redirects {
+		s.redirects[key] = d
+	}
+	c.lock.RUnlock()
+	s.cliArgs = c.cliArgs
+	s.ignoreUnknown = c.ignoreUnknown
+
+	for _, g := range c.AllGroups() {
+		g.lock.RLock()
+		if len(g.opts) > 0 {
+			sg := s.getGroupByName(g.name, true)
+			for name, opt := range g.opts {
+				sg.opts[name] = &option{opt: opt.opt, isCli: opt.isCli, prio: 1 << 31}
+				if value, ok := g.values[name]; ok {
+					sg.values[name] = value
+				}
+			}
+		}
+		g.lock.RUnlock()
+	}
+	return s
+}
+
+// parsedValues returns the option values set by the parser, which have
+// the priority, that's, not copied from the original config by snapshot.
+func (c *Config) parsedValues() map[string]map[string]interface{} {
+	groups := c.AllGroups()
+	values := make(map[string]map[string]interface{}, len(groups))
+	for _, g := range groups {
+		g.lock.RLock()
+		for name, opt := range g.opts {
+			if opt.prio < 1<<31 {
+				opts, ok := values[g.name]
+				if !ok {
+					opts = make(map[string]interface{}, len(g.opts))
+					values[g.name] = opts
+				}
+				opts[name] = g.values[name]
+			}
+		}
+		g.lock.RUnlock()
+	}
+	return values
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// secretFetcher fetches the payload and the version of the secret by ref.
+type secretFetcher func(ref string) (payload []byte, version string, err error)
+
+type secretOpt struct {
+	key   string
+	group string
+	name  string
+	field string
+}
+
+type secretParser struct {
+	name    string
+	prio    int
+	refresh time.Duration
+	secrets map[string]string
+	fetch   secretFetcher
+}
+
+// newSecretParser returns a parser to resolve the options from the secrets
+// of the secret manager, which fetches the secret by fetch.
+//
+// secrets is the mapping from the option key, such as "db.mysql.password",
+// to the secret reference, such as "myapp/mysql#password".
+func newSecretParser(name string, priority int, secrets map[string]string,
+	refresh time.Duration, fetch secretFetcher) Parser {
+	return secretParser{
+		name:    name,
+		prio:    priority,
+		refresh: refresh,
+		secrets: secrets,
+		fetch:   fetch,
+	}
+}
+
+func (p secretParser) Name() string {
+	return p.name
+}
+
+func (p secretParser) Priority() int {
+	return p.prio
+}
+
+func (p secretParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p secretParser) Post(c *Config) error {
+	return nil
+}
+
+func (p secretParser) Parse(c *Config) error {
+	r := &secretRefresher{
+		secretParser: p,
+		conf:         c,
+		opts:         make(map[string][]secretOpt, len(p.secrets)),
+		versions:     make(map[string]string, len(p.secrets)),
+	}
+
+	for key, ref := range p.secrets {
+		opt := secretOpt{key: key}
+		opt.group, opt.name = c.splitOptKey(key)
+		if index := strings.LastIndexByte(ref, '#'); index > -1 {
+			ref, opt.field = ref[:index], ref[index+1:]
+			if opt.field == "" {
+				return fmt.Errorf("the field of the secret for '%s' is empty", key)
+			}
+		}
+
+		if ref == "" {
+			return fmt.Errorf("the secret for '%s' is empty", key)
+		}
+		r.opts[ref] = append(r.opts[ref], opt)
+	}
+
+	for ref := range r.opts {
+		if err := r.load(ref); err != nil {
+			return err
+		}
+	}
+
+	if p.refresh > 0 {
+		c.goWatch(r.run)
+	}
+	return nil
+}
+
+type secretRefresher struct {
+	secretParser
+
+	conf     *Config
+	opts     map[string][]secretOpt
+	versions map[string]string
+}
+
+func (r *secretRefresher) load(ref string) (err error) {
+	r.conf.Printf("[%s] Fetching the secret '%s'", r.Name(), ref)
+	payload, version, err := r.fetch(ref)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the secret '%s': %s", ref, err)
+	}
+
+	// Use the payload to detect the change if the secret has no version.
+	if version == "" {
+		version = string(payload)
+	}
+	if last, ok := r.versions[ref]; ok && last == version {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if len(payload) > 0 && payload[0] == '{' {
+		dec := json.NewDecoder(strings.NewReader(string(payload)))
+		dec.UseNumber()
+		if err = dec.Decode(&fields); err != nil {
+			fields = nil
+		}
+	}
+
+	for _, opt := range r.opts[ref] {
+		var value string
+		if fields != nil {
+			field := opt.field
+			if field == "" {
+				field = opt.name
+			}
+
+			v, ok := fields[field]
+			if !ok {
+				return fmt.Errorf("no field '%s' in the secret '%s'", field, ref)
+			}
+			value = jsonValueToString(v)
+		} else if opt.field != "" {
+			return fmt.Errorf("the secret '%s' is not a JSON object", ref)
+		} else {
+			value = string(payload)
+		}
+
+		if err = r.conf.SetOptValue(r.prio, opt.group, opt.name, value); err != nil {
+			return
+		}
+	}
+
+	r.versions[ref] = version
+	return nil
+}
+
+func (r *secretRefresher) run(ctx context.Context) {
+	ticker := time.NewTicker(r.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for ref := range r.opts {
+			if err := r.load(ref); err != nil {
+				r.conf.Printf("[%s] Failed to refresh the secret '%s': %s", r.Name(), ref, err)
+			}
+		}
+	}
+}
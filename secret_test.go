@@ -0,0 +1,185 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testAWSSecretsClient map[string]string
+
+func (c testAWSSecretsClient) GetSecretValue(id string) ([]byte, string, error) {
+	if v, ok := c[id]; ok {
+		return []byte(v), "v1", nil
+	}
+	return nil, "", fmt.Errorf("no secret '%s'", id)
+}
+
+func TestAWSSecretsParser(t *testing.T) {
+	client := testAWSSecretsClient{
+		"myapp/mysql": `{"user": "root", "password": "pass", "port": 3306}`,
+		"myapp/token": "abc",
+	}
+
+	conf := NewConfig().AddParser(NewAWSSecretsParser(50, client, map[string]string{
+		"mysql.user":   "myapp/mysql",
+		"mysql.pass":   "myapp/mysql#password",
+		"mysql.port":   "myapp/mysql",
+		"mysql.backup": "myapp/mysql#user",
+		"token":        "myapp/token",
+	}, 0))
+
+	conf.RegisterOpt("", Str("token", "", ""))
+	conf.RegisterOpts("mysql", []Opt{
+		Str("user", "", ""),
+		Str("pass", "", ""),
+		Int("port", 0, ""),
+		Str("backup", "", ""),
+	})
+
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	mysql := conf.Group("mysql")
+	if v := conf.String("token"); v != "abc" {
+		t.Errorf("token: expected 'abc', got '%s'", v)
+	}
+	if v := mysql.String("user"); v != "root" {
+		t.Errorf("user: expected 'root', got '%s'", v)
+	}
+	if v := mysql.String("pass"); v != "pass" {
+		t.Errorf("pass: expected 'pass', got '%s'", v)
+	}
+	if v := mysql.Int("port"); v != 3306 {
+		t.Errorf("port: expected 3306, got %d", v)
+	}
+	if v := mysql.String("backup"); v != "root" {
+		t.Errorf("backup: expected 'root', got '%s'", v)
+	}
+}
+
+type testGCPSecretsClient struct {
+	lock    sync.Mutex
+	secrets map[string][]string // The versions of the secret, starting with 1.
+}
+
+func (c *testGCPSecretsClient) AccessSecretVersion(name string) ([]byte, string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var secret, version string
+	if _, err := fmt.Sscanf(strings.Replace(name, "/", " ", -1),
+		"projects myproject secrets %s versions %s", &secret, &version); err != nil {
+		return nil, "", fmt.Errorf("invalid secret name '%s'", name)
+	}
+
+	versions := c.secrets[secret]
+	index := len(versions)
+	if version != "latest" {
+		fmt.Sscanf(version, "%d", &index)
+	}
+	if index < 1 || index > len(versions) {
+		return nil, "", fmt.Errorf("no secret version '%s'", name)
+	}
+
+	name = fmt.Sprintf("projects/myproject/secrets/%s/versions/%d", secret, index)
+	return []byte(versions[index-1]), name, nil
+}
+
+func (c *testGCPSecretsClient) add(secret, value string) {
+	c.lock.Lock()
+	c.secrets[secret] = append(c.secrets[secret], value)
+	c.lock.Unlock()
+}
+
+func TestGCPSecretsParser(t *testing.T) {
+	client := &testGCPSecretsClient{secrets: map[string][]string{
+		"mysql": {`{"user": "root", "password": "pass1"}`, `{"user": "root", "password": "pass2"}`},
+		"token": {"abc"},
+	}}
+
+	conf := NewConfig().AddParser(NewGCPSecretsParser(50, client, "myproject", map[string]string{
+		"mysql.user": "mysql",
+		"mysql.pass": "mysql@latest#password",
+		"mysql.old":  "mysql@1#password",
+		"token":      "projects/myproject/secrets/token",
+	}, time.Millisecond*10))
+
+	conf.RegisterOpt("", Str("token", "", ""))
+	conf.RegisterOpts("mysql", []Opt{
+		Str("user", "", ""),
+		Str("pass", "", ""),
+		Str("old", "", ""),
+	})
+
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	mysql := conf.Group("mysql")
+	if v := conf.String("token"); v != "abc" {
+		t.Errorf("token: expected 'abc', got '%s'", v)
+	}
+	if v := mysql.String("user"); v != "root" {
+		t.Errorf("user: expected 'root', got '%s'", v)
+	}
+	if v := mysql.String("pass"); v != "pass2" {
+		t.Errorf("pass: expected 'pass2', got '%s'", v)
+	}
+	if v := mysql.String("old"); v != "pass1" {
+		t.Errorf("old: expected 'pass1', got '%s'", v)
+	}
+
+	// Only the latest version is refreshed.
+	client.add("mysql", `{"user": "root", "password": "pass3"}`)
+	for i := 0; i < 100 && mysql.String("pass") != "pass3"; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := mysql.String("pass"); v != "pass3" {
+		t.Errorf("pass: expected 'pass3', got '%s'", v)
+	}
+	if v := mysql.String("old"); v != "pass1" {
+		t.Errorf("old: expected 'pass1', got '%s'", v)
+	}
+}
+
+func TestGCPSecretVersionName(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"mysql":                               "projects/p/secrets/mysql/versions/latest",
+		"mysql@3":                             "projects/p/secrets/mysql/versions/3",
+		"projects/q/secrets/mysql":            "projects/q/secrets/mysql/versions/latest",
+		"projects/q/secrets/mysql/versions/2": "projects/q/secrets/mysql/versions/2",
+	} {
+		if name, err := gcpSecretVersionName("p", ref); err != nil {
+			t.Errorf("%s: %s", ref, err)
+		} else if name != expected {
+			t.Errorf("%s: expected '%s', got '%s'", ref, expected, name)
+		}
+	}
+
+	if _, err := gcpSecretVersionName("p", "mysql@"); err == nil {
+		t.Error("expect an error for the empty version")
+	} else if _, err = gcpSecretVersionName("", "mysql"); err == nil {
+		t.Error("expect an error for the empty project")
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSpringCloudConfigParser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else if r.URL.Path != "/myapp/prod/release(_)v1" {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			fmt.Fprint(w, `{"name": "myapp", "propertySources": [
+				{"name": "myapp-prod.yml", "source": {"port": 8080}},
+				{"name": "myapp.yml", "source": {"port": 80, "db.mysql.conn": "root@tcp",
+					"db.mysql.hosts": ["a", "b"], "unused": "xxx"}}
+			]}`)
+		}
+	}))
+	defer server.Close()
+
+	uri := strings.Replace(server.URL, "://", "://user:pass@", 1)
+	conf := NewConfig().AddParser(NewSpringCloudConfigParser(50, uri, "myapp", "prod", "release/v1"))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpts("db.mysql", []Opt{Str("conn", "", ""), Strings("hosts", nil, "")})
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	mysql := conf.Group("db.mysql")
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+	if v := mysql.String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+	if v := mysql.Strings("hosts"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("hosts: expected [a b], got %v", v)
+	}
+
+	conf = NewConfig().AddParser(NewSpringCloudConfigParser(50, server.URL, "myapp", "prod", ""))
+	if err := conf.Parse(); err == nil {
+		t.Error("expect an error for the unauthorized request")
+	}
+}
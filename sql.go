@@ -0,0 +1,163 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// sqlTableName matches the table name, which may be qualified by the schema,
+// such as "config" or "myapp.config", so that it's safe to be put into SQL.
+var sqlTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type sqlParser struct {
+	prio     int
+	db       *sql.DB
+	table    string
+	interval time.Duration
+}
+
+// NewSQLParser returns a new parser to read the options from a table
+// of the database by database/sql.
+//
+// The table must have three columns of the string type, that's,
+// "group_name", "opt_name" and "opt_value", such as
+//
+//    CREATE TABLE config (
+//        group_name VARCHAR(128) NOT NULL DEFAULT '',
+//        opt_name   VARCHAR(128) NOT NULL,
+//        opt_value  TEXT         NOT NULL,
+//        PRIMARY KEY (group_name, opt_name)
+//    );
+//
+// The empty group name is the default group. The table name must be
+// the identifier, which may be qualified by the schema, such as "myapp.config".
+//
+// If interval is greater than 0, it will poll the table periodically, and
+// update the option values by SetOptValue when the rows are changed.
+//
+// Notice: the rows that have not been registered as the options are ignored.
+func NewSQLParser(priority int, db *sql.DB, table string, interval time.Duration) Parser {
+	if db == nil {
+		panic(fmt.Errorf("the database must not be nil"))
+	} else if table == "" {
+		panic(fmt.Errorf("the table must not be empty"))
+	} else if !sqlTableName.MatchString(table) {
+		panic(fmt.Errorf("invalid table name '%s'", table))
+	}
+	return sqlParser{prio: priority, db: db, table: table, interval: interval}
+}
+
+func (p sqlParser) Name() string {
+	return "sql"
+}
+
+func (p sqlParser) Priority() int {
+	return p.prio
+}
+
+func (p sqlParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p sqlParser) Post(c *Config) error {
+	return nil
+}
+
+func (p sqlParser) Parse(c *Config) error {
+	r := &sqlPoller{
+		sqlParser: p,
+		conf:      c,
+		values:    make(map[string]map[string]string, 8),
+	}
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	if p.interval > 0 {
+		c.goWatch(r.run)
+	}
+	return nil
+}
+
+type sqlPoller struct {
+	sqlParser
+
+	conf   *Config
+	values map[string]map[string]string
+}
+
+// querySQLTable returns all the option values in the table, which has
+// the columns "group_name", "opt_name" and "opt_value".
+func querySQLTable(db *sql.DB, table string) (map[string]map[string]string, error) {
+	rows, err := db.Query("SELECT group_name, opt_name, opt_value FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]map[string]string, 8)
+	for rows.Next() {
+		var group, name, value string
+		if err = rows.Scan(&group, &name, &value); err != nil {
+			return nil, err
+		}
+		addDecodedValue(values, group, name, value)
+	}
+
+	return values, rows.Err()
+}
+
+func (r *sqlPoller) load() error {
+	values, err := querySQLTable(r.db, r.table)
+	if err != nil {
+		return fmt.Errorf("failed to query the table '%s': %s", r.table, err)
+	}
+
+	changes := changedValues(r.values, values)
+	if len(changes) > 0 {
+		r.conf.Printf("[%s] Loading the changed rows from the table '%s'", r.Name(), r.table)
+		if err = r.conf.setOptValues(r.prio, changes, true); err != nil {
+			return err
+		}
+	}
+
+	r.values = values
+	return nil
+}
+
+func (r *sqlPoller) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.load(); err != nil {
+			r.conf.Printf("[%s] Failed to reload: %s", r.Name(), err)
+		}
+	}
+}
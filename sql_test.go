@@ -0,0 +1,178 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSQLDriver is the fake driver of database/sql, the databases of which
+// are the mapping from the table name to the rows.
+type testSQLDriver struct {
+	lock sync.Mutex
+	dbs  map[string]map[string][][]string
+}
+
+var testSQL = &testSQLDriver{dbs: make(map[string]map[string][][]string)}
+
+func init() { sql.Register("configtest", testSQL) }
+
+func (d *testSQLDriver) setTable(db, table string, rows [][]string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.dbs[db] == nil {
+		d.dbs[db] = make(map[string][][]string)
+	}
+	d.dbs[db][table] = rows
+}
+
+func (d *testSQLDriver) Open(name string) (driver.Conn, error) {
+	return testSQLConn{driver: d, db: name}, nil
+}
+
+type testSQLConn struct {
+	driver *testSQLDriver
+	db     string
+}
+
+func (c testSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return testSQLStmt{conn: c, query: query}, nil
+}
+
+func (c testSQLConn) Close() error              { return nil }
+func (c testSQLConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("no transaction") }
+
+type testSQLStmt struct {
+	conn  testSQLConn
+	query string
+}
+
+func (s testSQLStmt) Close() error  { return nil }
+func (s testSQLStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s testSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("no support for Exec")
+}
+
+// Query supports "SELECT columns FROM table" and the query of sqlite_master
+// to count the table by its name.
+func (s testSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.conn.driver
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	tables := d.dbs[s.conn.db]
+	if strings.Contains(s.query, "sqlite_master") {
+		count := "0"
+		if _, ok := tables[args[0].(string)]; ok {
+			count = "1"
+		}
+		return &testSQLRows{columns: []string{"count"}, rows: [][]string{{count}}}, nil
+	}
+
+	index := strings.Index(s.query, " FROM ")
+	if !strings.HasPrefix(s.query, "SELECT ") || index < 0 {
+		return nil, fmt.Errorf("invalid query '%s'", s.query)
+	}
+
+	table := strings.Trim(s.query[index+6:], `"`)
+	rows, ok := tables[table]
+	if !ok {
+		return nil, fmt.Errorf("no table '%s'", table)
+	}
+
+	columns := strings.Split(s.query[7:index], ", ")
+	return &testSQLRows{columns: columns, rows: rows}, nil
+}
+
+type testSQLRows struct {
+	columns []string
+	rows    [][]string
+}
+
+func (r *testSQLRows) Columns() []string { return r.columns }
+func (r *testSQLRows) Close() error      { return nil }
+
+func (r *testSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	for i := range dest {
+		dest[i] = r.rows[0][i]
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLParser(t *testing.T) {
+	db, err := sql.Open("configtest", "TestSQLParser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testSQL.setTable("TestSQLParser", "myapp.config", [][]string{
+		{"", "port", "80"},
+		{"db.mysql", "conn", "root@tcp"},
+		{"db.mysql", "unused", "xxx"},
+	})
+
+	conf := NewConfig().AddParser(NewSQLParser(50, db, "myapp.config", time.Millisecond*10))
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err = conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	testSQL.setTable("TestSQLParser", "myapp.config", [][]string{{"", "port", "8080"}})
+	for i := 0; i < 100 && conf.Int("port") != 8080; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := conf.Int("port"); v != 8080 {
+		t.Errorf("port: expected 8080, got %d", v)
+	}
+}
+
+func TestSQLParserTableName(t *testing.T) {
+	for _, table := range []string{"config; DROP TABLE users", "my-config", "a.b.c", "1config"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expect a panic for the table name '%s'", table)
+				}
+			}()
+			NewSQLParser(50, &sql.DB{}, table, 0)
+		}()
+	}
+}
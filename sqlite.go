@@ -0,0 +1,155 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type sqliteParser struct {
+	prio   int
+	opt    string
+	table  string
+	driver string
+	init   func(*Config) error
+}
+
+// NewSimpleSQLiteParser returns a SQLite parser with the priority 100 and
+// the driver "sqlite3", which registers the option, optName, before parsing
+// the option.
+func NewSimpleSQLiteParser(optName, table string) Parser {
+	return NewSQLiteParser(100, "sqlite3", optName, table, func(c *Config) error {
+		c.RegisterCliOpt("", Str(optName, "", "The path of the SQLite config file."))
+		return nil
+	})
+}
+
+// NewSQLiteParser returns a new parser based on the SQLite database file.
+//
+// driverName is the name of the SQLite driver registered into database/sql,
+// such as "sqlite3" for github.com/mattn/go-sqlite3 or "sqlite" for
+// modernc.org/sqlite, which must be imported by the caller.
+//
+// optName is the option name of the path of the database file. It will be
+// registered, and parsed before this parser runs. The parser does nothing
+// if the path is empty.
+//
+// If table is not empty, all the options are stored in that table, which has
+// the columns "group_name", "opt_name" and "opt_value", like NewSQLParser.
+// Or, each group is stored in its own table, which is named the full name
+// of the group, such as "DEFAULT" and "db.mysql", and has the columns
+// "opt_name" and "opt_value". The nonexistent group tables are ignored.
+//
+// Notice: the rows that have not been registered as the options are ignored.
+func NewSQLiteParser(priority int, driverName, optName, table string,
+	init func(*Config) error) Parser {
+	return sqliteParser{
+		prio:   priority,
+		opt:    optName,
+		table:  table,
+		driver: driverName,
+		init:   init,
+	}
+}
+
+func (p sqliteParser) Name() string {
+	return "sqlite"
+}
+
+func (p sqliteParser) Priority() int {
+	return p.prio
+}
+
+func (p sqliteParser) Pre(c *Config) error {
+	if p.init != nil {
+		return p.init(c)
+	}
+	return nil
+}
+
+func (p sqliteParser) Post(c *Config) error {
+	return nil
+}
+
+func (p sqliteParser) Parse(c *Config) error {
+	filename := c.StringD(p.opt, "")
+	if filename == "" {
+		return nil
+	}
+
+	db, err := sql.Open(p.driver, filename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Single table
+	if p.table != "" {
+		c.Printf("[%s] Parsing the table '%s' of '%s'", p.Name(), p.table, filename)
+		values, err := querySQLTable(db, quoteSQLiteName(p.table))
+		if err != nil {
+			return err
+		}
+		return c.setOptValues(p.prio, values, true)
+	}
+
+	// Per-group tables
+	for _, group := range c.Groups() {
+		table := group.FullName()
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?",
+			table).Scan(&count)
+		if err != nil {
+			return err
+		} else if count == 0 {
+			continue
+		}
+
+		c.Printf("[%s] Parsing the table '%s' of '%s'", p.Name(), table, filename)
+		if err = p.parseGroupTable(c, db, group, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p sqliteParser) parseGroupTable(c *Config, db *sql.DB, group *OptGroup, table string) error {
+	rows, err := db.Query("SELECT opt_name, opt_value FROM " + quoteSQLiteName(table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		if err = c.setOptValueIfExist(p.prio, group.FullName(), name, value); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// quoteSQLiteName quotes the identifier, such as the table name "db.mysql".
+func quoteSQLiteName(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
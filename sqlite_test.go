@@ -0,0 +1,55 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestSQLiteParser(t *testing.T) {
+	testSQL.setTable("single.db", "config", [][]string{
+		{"", "port", "80"},
+		{"db.mysql", "conn", "root@tcp"},
+	})
+	testSQL.setTable("groups.db", "DEFAULT", [][]string{{"port", "90"}, {"unused", "xxx"}})
+	testSQL.setTable("groups.db", "db.mysql", [][]string{{"conn", "admin@tcp"}})
+
+	for _, c := range []struct {
+		file  string
+		table string
+		port  int
+		conn  string
+	}{
+		{"single.db", "config", 80, "root@tcp"},
+		{"groups.db", "", 90, "admin@tcp"},
+	} {
+		conf := NewConfig().AddParser(NewSQLiteParser(50, "configtest", "sqlite-file", c.table, nil))
+		conf.RegisterOpt("", Str("sqlite-file", "", ""))
+		conf.RegisterOpt("", Int("port", 0, ""))
+		conf.RegisterOpts("db.mysql", []Opt{Str("conn", "", ""), Str("user", "", "")})
+		conf.RegisterOpt("db.redis", Str("conn", "", ""))
+		conf.SetOptValue(0, "", "sqlite-file", c.file)
+		if err := conf.Parse(); err != nil {
+			t.Fatal(err)
+		}
+
+		if v := conf.Int("port"); v != c.port {
+			t.Errorf("%s: port: expected %d, got %d", c.file, c.port, v)
+		}
+		if v := conf.Group("db.mysql").String("conn"); v != c.conn {
+			t.Errorf("%s: conn: expected '%s', got '%s'", c.file, c.conn, v)
+		}
+	}
+}
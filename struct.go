@@ -0,0 +1,564 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StructError is the error of the struct registered by RegisterStructE,
+// which reports all the offending fields.
+type StructError struct {
+	Errors []error
+}
+
+func (e StructError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("the struct is invalid:\n%s", strings.Join(msgs, "\n"))
+}
+
+// StructOpt is the option built from the field of the struct.
+type StructOpt struct {
+	Group string // The group of the option, which is "" for the default group.
+	Opt   Opt
+	Cli   bool // Whether the option is registered into the CLI parser.
+}
+
+// OptsFromStruct builds the options from the struct s like RegisterStruct,
+// but returns them instead of registering them, so they can be inspected
+// or registered into more than one Config, such as
+//
+//    opts, err := OptsFromStruct(&s)
+//    if err != nil {
+//        // ...
+//    }
+//    for _, o := range opts {
+//        if o.Cli {
+//            conf.RegisterCliOpt(o.Group, o.Opt)
+//        } else {
+//            conf.RegisterOpt(o.Group, o.Opt)
+//        }
+//    }
+//
+// Notice: the options are not bound to the fields of the struct, and the tags
+// "env", "deprecated" and "immutable" are ignored.
+func OptsFromStruct(s interface{}) ([]StructOpt, error) {
+	c := NewConfig()
+	sopts, err := c.collectStruct(c.groupName, s, false)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := make([]StructOpt, len(sopts))
+	for i, so := range sopts {
+		opts[i] = so.StructOpt
+		if so.Group == c.groupName {
+			opts[i].Group = ""
+		}
+	}
+	return opts, nil
+}
+
+// structOpt is the option built from the field of the struct,
+// which is bound to the field.
+type structOpt struct {
+	StructOpt
+	field reflect.Value
+
+	env        string
+	deprecated bool
+	depMsg     string
+	immutable  bool
+}
+
+// structCollector builds the options from the fields of the struct,
+// and collects the errors of all the offending fields.
+type structCollector struct {
+	conf *Config
+	opts []structOpt
+	errs []error
+}
+
+// collectStruct builds the options from the struct s, which must be
+// a pointer to a struct variable, into the group.
+func (c *Config) collectStruct(group string, s interface{}, cli bool) ([]structOpt, error) {
+	sv := reflect.ValueOf(s)
+	if !sv.IsValid() || sv.Kind() != reflect.Ptr || sv.IsNil() {
+		return nil, fmt.Errorf("the struct is invalid or can't be set")
+	} else if sv = sv.Elem(); sv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the struct is not a struct")
+	}
+
+	sc := structCollector{conf: c}
+	sc.collect(group, "", sv, cli)
+	if len(sc.errs) > 0 {
+		return nil, StructError{Errors: sc.errs}
+	}
+	return sc.opts, nil
+}
+
+// registerStructOpts registers the options built from the struct,
+// and returns the indexes of the options bound to the fields.
+func (c *Config) registerStructOpts(opts []structOpt) (bound []int) {
+	for i, so := range opts {
+		name := so.Opt.Name()
+		if so.env != "" {
+			c.setOptEnv(so.Group, name, so.env)
+		}
+		if so.deprecated {
+			c.deprecateOpt(so.Group, name, "", so.depMsg)
+		}
+		if so.immutable {
+			c.markImmutable(so.Group, name)
+		}
+
+		group := c.getGroupByName(so.Group, true)
+		group.lock.Lock()
+		if group.registerOpt(so.Cli, so.Opt) {
+			group.fields[name] = so.field
+			bound = append(bound, i)
+		}
+		group.lock.Unlock()
+	}
+	return
+}
+
+// StructBinding is the binding between the options and the fields
+// of the struct registered by RegisterStruct.
+type StructBinding struct {
+	conf  *Config
+	group string
+	cli   bool
+	bound []int
+
+	lock sync.RWMutex
+	ptr  interface{}
+}
+
+// Struct returns the pointer to the struct bound currently.
+func (b *StructBinding) Struct() interface{} {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.ptr
+}
+
+// Swap repoints the options to the fields of the new struct, which must be
+// a pointer to the struct of the same type as the bound one, and returns
+// the old one. The current option values are written into the new struct
+// before swapping, so it's a complete snapshot of the configuration.
+//
+// It is used to update the configuration atomically during hot reload,
+// such as binding to a freshly allocated copy, then the readers, which hold
+// the struct locker set by SetStructLocker, never see a half-updated struct.
+// After swapping, the old struct is no longer updated.
+func (b *StructBinding) Swap(newPtr interface{}) (old interface{}, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if reflect.TypeOf(newPtr) != reflect.TypeOf(b.ptr) {
+		return nil, fmt.Errorf("the struct type '%T' is not '%T'", newPtr, b.ptr)
+	}
+
+	c := b.conf
+	opts, err := c.collectStruct(b.group, newPtr, b.cli)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock all the groups in order to avoid the deadlock.
+	groups := make(map[string]*OptGroup, 4)
+	for _, i := range b.bound {
+		if g := c.getGroupByName(opts[i].Group, false); g != nil {
+			groups[g.name] = g
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		groups[name].lock.Lock()
+		defer groups[name].lock.Unlock()
+	}
+
+	if c.structLock != nil {
+		c.structLock.Lock()
+		defer c.structLock.Unlock()
+	}
+
+	for _, i := range b.bound {
+		so := opts[i]
+		name := so.Opt.Name()
+		g := c.getGroupByName(so.Group, false)
+		if g == nil || g.opts[name] == nil {
+			continue // The option has been unregistered.
+		}
+
+		if v, ok := g.values[name]; ok {
+			setFieldValue(so.field, v)
+		}
+		g.fields[name] = so.field
+	}
+
+	old, b.ptr = b.ptr, newPtr
+	return old, nil
+}
+
+// validate calls the Validate method of the struct bound currently.
+func (b *StructBinding) validate() error {
+	return b.Struct().(StructValidator).Validate()
+}
+
+// setFieldValue sets the value into the field of the struct.
+func setFieldValue(field reflect.Value, value interface{}) {
+	if value == nil { // Such as the nil value of the interface{} field
+		field.Set(reflect.Zero(field.Type()))
+	} else {
+		field.Set(reflect.ValueOf(value))
+	}
+}
+
+// checkStructConflicts returns the errors of the options conflicting with
+// the registered ones or each other if the conflict policy is ConflictPanic,
+// and the errors of the CLI options whose short names collide.
+func (c *Config) checkStructConflicts(opts []structOpt) (errs []error) {
+	keys := make(map[string]bool, len(opts))
+	shorts := make(map[string]string, len(opts))
+	for _, so := range opts {
+		key := c.optKey(so.Group, so.Opt.Name())
+		if c.conflict == ConflictPanic {
+			group := c.getGroupByName(so.Group, false)
+			if keys[key] || (group != nil && group.HasOpt(so.Opt.Name())) {
+				errs = append(errs, fmt.Errorf("the option '%s' has been registered", key))
+			}
+		}
+		keys[key] = true
+
+		if short := so.Opt.Short(); so.Cli && short != "" {
+			other, ok := shorts[short]
+			if !ok {
+				other = c.lookupShort(short, key)
+			}
+			if other != "" {
+				errs = append(errs, fmt.Errorf(
+					"the short name '%s' of the option '%s' has been used by the option '%s'",
+					short, key, other))
+			}
+			shorts[short] = key
+		}
+	}
+	return
+}
+
+func (sc *structCollector) collect(group, path string, sv reflect.Value, cli bool) {
+	if sv.Kind() == reflect.Ptr {
+		sv = sv.Elem()
+	}
+	st := sv.Type()
+
+	// Compute the defaults by the struct, which are the field values.
+	var defaults bool
+	if sv.CanAddr() {
+		if d, ok := sv.Addr().Interface().(StructDefaulter); ok {
+			d.SetDefaults()
+			defaults = true
+		}
+	}
+
+	// Build the field as the option
+	num := sv.NumField()
+	for i := 0; i < num; i++ {
+		field := st.Field(i)
+		field.Tag = sc.conf.expandStructTag(field.Tag)
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+
+		err := sc.collectField(group, fieldPath, field, sv.Field(i), cli, defaults)
+		if err != nil {
+			sc.errs = append(sc.errs, fmt.Errorf("the field %s: %s", fieldPath, err))
+		}
+	}
+}
+
+func (sc *structCollector) collectField(group, path string, field reflect.StructField,
+	fieldV reflect.Value, cli, defaults bool) (err error) {
+	c := sc.conf
+
+	// Flatten the anonymous struct into the current group like encoding/json,
+	// unless the tag "name" or "group" is given.
+	if field.Anonymous && field.Tag.Get("name") == "" && field.Tag.Get("group") == "" {
+		if ok, err := sc.collectEmbedded(group, path, field, fieldV, cli); ok || err != nil {
+			return err
+		}
+	}
+
+	// Check whether the field can be set.
+	if !fieldV.CanSet() {
+		return nil
+	}
+
+	name := c.fieldName(field.Name)
+	tagname := strings.TrimSpace(field.Tag.Get("name"))
+	if tagname == "-" {
+		return nil
+	} else if tagname != "" {
+		name = tagname
+	}
+
+	isCli, err := fieldBoolTag(field, "cli", cli)
+	if err != nil {
+		return err
+	}
+
+	gname := group
+	taggroup, resetgroup := field.Tag.Lookup("group")
+	if resetgroup {
+		taggroup = strings.TrimSpace(taggroup)
+		gname = taggroup
+	}
+
+	// Get the short name from the tag "short"
+	short := strings.TrimSpace(field.Tag.Get("short"))
+
+	// Get the help doc from the tag "help"
+	help := strings.TrimSpace(field.Tag.Get("help"))
+
+	// Allocate the nil pointer to the struct, such as *TLSConfig,
+	// and register the struct which it points to.
+	if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+		if _, ok := lookupOptType(fieldV); !ok {
+			if fieldV.IsNil() {
+				fieldV.Set(reflect.New(field.Type.Elem()))
+			}
+			fieldV = fieldV.Elem()
+			field.Type = fieldV.Type()
+		}
+	}
+
+	// Check whether the field is the struct.
+	if t := field.Type.Kind(); t == reflect.Struct {
+		switch fieldV.Interface().(type) {
+		case time.Time, Secret, Decimal:
+		default:
+			if isTextUnmarshaler(field.Type) {
+				break // The custom type, see CustomOpt.
+			}
+
+			// Register the options of the nested struct into the current group.
+			squash, err := fieldBoolTag(field, "squash", false)
+			if err != nil {
+				return err
+			} else if !resetgroup && squash {
+				sc.collect(group, path, fieldV, isCli)
+				return nil
+			}
+
+			parentGroup := c.mergeGroupName(group, name)
+			if resetgroup {
+				if strings.Contains(taggroup, c.groupSep) {
+					parentGroup = strings.Trim(taggroup, c.groupSep)
+				} else if taggroup == "" {
+					parentGroup = c.groupName // Default Group
+				} else {
+					parentGroup = c.mergeGroupName(group, taggroup)
+				}
+			}
+
+			sc.collect(parentGroup, path, fieldV, isCli)
+			return nil
+		}
+	}
+
+	so := structOpt{StructOpt: StructOpt{Group: gname, Cli: isCli}, field: fieldV}
+
+	// Get the name of the environment variable from the tag "env"
+	so.env = strings.TrimSpace(field.Tag.Get("env"))
+
+	// Get the deprecated message from the tag "deprecated"
+	if msg, ok := field.Tag.Lookup("deprecated"); ok {
+		so.deprecated, so.depMsg = true, strings.TrimSpace(msg)
+	}
+
+	// Get whether the option is immutable from the tag "immutable"
+	if so.immutable, err = fieldBoolTag(field, "immutable", false); err != nil {
+		return err
+	}
+
+	_type, ok := lookupOptType(fieldV)
+	if _type == int64Type {
+		if _, ok := fieldV.Interface().(time.Duration); ok {
+			_type = durationType
+		}
+	} else if _type == intType {
+		if _, ok := fieldV.Interface().(Level); ok {
+			_type = levelType
+		}
+	}
+
+	// The custom type implementing encoding.TextUnmarshaler, which is not
+	// the builtin type, such as net.IP or "type Color string".
+	if isTextUnmarshaler(field.Type) &&
+		(!ok || reflect.TypeOf(baseOpt{_type: _type}.Zero()) != field.Type) {
+		prototype := reflect.New(field.Type).Interface().(encoding.TextUnmarshaler)
+		opt := CustomOpt(short, name, prototype, "", help).(customOpt)
+
+		// Get the default value from the tag "default"
+		if v := strings.TrimSpace(field.Tag.Get("default")); v != "" {
+			if opt._default, err = opt.Parse(v); err != nil {
+				return fmt.Errorf("can't parse the default: %s", err)
+			}
+		} else if defaults && !isZeroValue(fieldV) {
+			opt._default = fieldV.Interface()
+		}
+
+		vs, err := fieldValidators(field, opt.Zero())
+		if err != nil {
+			return err
+		}
+
+		so.Opt = opt.AddValidators(vs...)
+		sc.opts = append(sc.opts, so)
+		return nil
+	} else if !ok {
+		return fmt.Errorf("doesn't support the type %s", field.Type.String())
+	}
+
+	opt := newBaseOpt(short, name, nil, help, _type)
+
+	// Get the time layouts separated by "|" from the tag "layout"
+	if layout := strings.TrimSpace(field.Tag.Get("layout")); layout != "" {
+		if _type != timeType && _type != timesType {
+			return fmt.Errorf("not time.Time or []time.Time for layout")
+		}
+		opt.layouts = strings.Split(layout, "|")
+	}
+
+	// Get the default value from the tag "default"
+	if v, ok := field.Tag.Lookup("default"); ok {
+		if opt._default, err = opt.Parse(strings.TrimSpace(v)); err != nil {
+			return fmt.Errorf("can't parse the default: %s", err)
+		}
+	} else if defaults && !isZeroValue(fieldV) {
+		opt._default = fieldV.Interface()
+	}
+
+	// Get the validators from the tag "validate"
+	vs, err := fieldValidators(field, opt.Zero())
+	if err != nil {
+		return err
+	} else if len(vs) > 0 {
+		opt = opt.AddValidators(vs...).(baseOpt)
+	}
+
+	if _type == secretType {
+		so.Opt = secretValueOpt{opt}
+	} else {
+		so.Opt = opt
+	}
+	sc.opts = append(sc.opts, so)
+	return nil
+}
+
+// collectEmbedded builds the options from the fields of the anonymous struct
+// field into the current group, and reports whether the field is the struct.
+func (sc *structCollector) collectEmbedded(group, path string, field reflect.StructField,
+	fieldV reflect.Value, cli bool) (bool, error) {
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if ft.Kind() != reflect.Struct || isTextUnmarshaler(ft) {
+		return false, nil
+	} else if _, ok := lookupOptType(reflect.Zero(field.Type)); ok {
+		return false, nil
+	}
+
+	if field.Type.Kind() == reflect.Ptr {
+		// The unexported pointer can't be allocated, so ignore it.
+		if !fieldV.CanSet() {
+			return true, nil
+		} else if fieldV.IsNil() {
+			fieldV.Set(reflect.New(ft))
+		}
+	}
+
+	cli, err := fieldBoolTag(field, "cli", cli)
+	if err != nil {
+		return true, err
+	}
+
+	sc.collect(group, path, fieldV, cli)
+	return true, nil
+}
+
+// isZeroValue reports whether v is the zero value of its type.
+func isZeroValue(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// fieldValidators returns the validators declared by the tags "choices"
+// and "validate".
+func fieldValidators(field reflect.StructField, zero interface{}) (vs []Validator, err error) {
+	if tag := strings.TrimSpace(field.Tag.Get("choices")); tag != "" {
+		if _, ok := zero.(string); !ok {
+			return nil, fmt.Errorf("not string for choices")
+		}
+
+		choices := strings.Split(tag, ",")
+		for i := range choices {
+			choices[i] = strings.TrimSpace(choices[i])
+		}
+		vs = append(vs, NewStrArrayValidator(choices))
+	}
+
+	if tag := strings.TrimSpace(field.Tag.Get("validate")); tag != "" {
+		_vs, err := parseValidateTag(tag, zero)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, _vs...)
+	}
+
+	return
+}
+
+// fieldBoolTag returns the bool value of the tag of the field, or _default.
+func fieldBoolTag(field reflect.StructField, tag string, _default bool) (bool, error) {
+	if value := strings.TrimSpace(field.Tag.Get(tag)); value != "" {
+		switch value {
+		case "1", "t", "T", "on", "On", "ON", "true", "True", "TRUE":
+			return true, nil
+		case "0", "f", "F", "off", "Off", "OFF", "false", "False", "FALSE":
+			return false, nil
+		default:
+			return false, fmt.Errorf("no support '%s' for %s", value, tag)
+		}
+	}
+	return _default, nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of the suggested flag names.
+const maxSuggestions = 3
+
+// editDistance returns the Levenshtein distance between s1 and s2.
+func editDistance(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	prev := make([]int, len(r2)+1)
+	curr := make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		curr[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j] + 1
+			if v := curr[j-1] + 1; v < curr[j] {
+				curr[j] = v
+			}
+			if v := prev[j-1] + cost; v < curr[j] {
+				curr[j] = v
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(r2)]
+}
+
+// suggestNames returns the closest candidates of name by the edit distance.
+//
+// The candidate is compared with both its full name, such as
+// "db.mysql.maxconn", and its last part split by sep, such as "maxconn",
+// so "--maxconn" and "--db.mysql.maxcon" both suggest "--db.mysql.maxconn".
+func suggestNames(name, sep string, candidates []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := len(name) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	suggestions := make([]suggestion, 0, maxSuggestions)
+	for _, candidate := range candidates {
+		distance := editDistance(name, candidate)
+		if index := strings.LastIndex(candidate, sep); sep != "" && index > -1 {
+			if d := editDistance(name, candidate[index+len(sep):]); d < distance {
+				distance = d
+			}
+		}
+
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{name: candidate, distance: distance})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance == suggestions[j].distance {
+			return suggestions[i].name < suggestions[j].name
+		}
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	names := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		names[i] = s.name
+	}
+	return names
+}
+
+// didYouMean appends the closest candidates of the unknown flag name into
+// the error, the prefix of which is the flag prefix, such as "-" or "--".
+func (c *Config) didYouMean(err error, name, prefix string, candidates []string) error {
+	names := suggestNames(name, c.GetGroupSeparator(), candidates)
+	if len(names) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s, did you mean %s%s?", err, prefix, strings.Join(names, ", "+prefix))
+}
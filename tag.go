@@ -0,0 +1,105 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultStructTagKey is the default key of the consolidated struct tag.
+const DefaultStructTagKey = "config"
+
+// SetStructTagKey sets the key of the consolidated struct tag, which declares
+// all the tags of the field registered by RegisterStruct in a single tag,
+// such as
+//
+//    Port int `config:"port,short=p,default=80,help=The port,group=http"`
+//
+// The first element is the name of the option, which may be empty, and
+// the rest are the pairs "tag=value" of the other tags, such as "short",
+// "default", "help", "group", "env", "cli", "choices" and "validate".
+// The value may contain the comma, such as "choices=json,yaml,table", unless
+// the comma is followed by "tag=".
+//
+// The separate tag takes precedence over the one in the consolidated tag.
+//
+// The default is DefaultStructTagKey. If key is empty, disable it.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetStructTagKey(key string) *Config {
+	c.panicIsParsed(true)
+	c.tagKey = key
+	return c
+}
+
+// expandStructTag expands the consolidated struct tag into the separate tags,
+// which are appended to the end of the original tag.
+func (c *Config) expandStructTag(tag reflect.StructTag) reflect.StructTag {
+	if c.tagKey == "" {
+		return tag
+	}
+
+	value, ok := tag.Lookup(c.tagKey)
+	if !ok {
+		return tag
+	}
+
+	var key string
+	tags := make([]string, 0, 8)
+	values := make(map[string]string, 8)
+	for i, elem := range strings.Split(value, ",") {
+		if i == 0 && !isStructTagPair(elem) {
+			key, elem = "name", "name="+elem
+		} else if !isStructTagPair(elem) {
+			if key != "" {
+				values[key] += "," + elem
+			}
+			continue
+		}
+
+		index := strings.IndexByte(elem, '=')
+		if key = strings.TrimSpace(elem[:index]); key == c.tagKey {
+			key = "" // Ignore the recursive tag.
+			continue
+		} else if _, ok := values[key]; !ok {
+			tags = append(tags, key)
+		}
+		values[key] = elem[index+1:]
+	}
+
+	buf := make([]byte, 0, len(tag)+len(value)+8*len(tags))
+	buf = append(buf, tag...)
+	for _, key := range tags {
+		if len(buf) > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		buf = strconv.AppendQuote(buf, values[key])
+	}
+	return reflect.StructTag(buf)
+}
+
+// isStructTagPair reports whether s is the pair "tag=value", the tag of which
+// only contains the lower-case letters.
+func isStructTagPair(s string) bool {
+	s = strings.TrimLeft(s, " ")
+	index := strings.IndexByte(s, '=')
+	return index > 0 && isAlpha(s[:index])
+}
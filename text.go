@@ -0,0 +1,64 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "strings"
+
+type textOpt struct {
+	baseOpt
+}
+
+// TextOpt returns a new text option, the value of which is the long
+// multi-line string, such as the PEM block or the SQL snippet.
+//
+// The line endings of the value are normalized to "\n". In the INI or
+// property file, the value may be the triple-quoted or heredoc block,
+// see DecodeProperty, and the multi-line default is written as the block
+// by GenerateConfig.
+func TextOpt(short, name string, _default string, help string) ValidatorChainOpt {
+	return textOpt{newBaseOpt(short, name, normalizeNewlines(_default), help, stringType)}
+}
+
+// Text is equal to TextOpt("", name, _default, help).
+func Text(name string, _default string, help string) ValidatorChainOpt {
+	return TextOpt("", name, _default, help)
+}
+
+// SetValidators resets the validator chain.
+func (o textOpt) SetValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.SetValidators(vs...).(baseOpt)
+	return o
+}
+
+// AddValidators adds some new validators into the validator chain.
+func (o textOpt) AddValidators(vs ...Validator) ValidatorChainOpt {
+	o.baseOpt = o.baseOpt.AddValidators(vs...).(baseOpt)
+	return o
+}
+
+// Parse parses the value of the option to the string with "\n" line endings.
+func (o textOpt) Parse(data interface{}) (interface{}, error) {
+	s, err := ToString(data)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeNewlines(s), nil
+}
+
+func normalizeNewlines(s string) string {
+	return strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\r", "\n", -1)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetOptTTL sets the TTL of the value of the option in the group, which
+// reverts to the default, and the observers are notified, if it's not
+// refreshed by the source in time, such as the feature flag served from
+// the remote store which might go away. The default value never expires.
+//
+// The value is refreshed when the source sets it again, or the parser
+// refreshing the source, such as RefreshEvery or Reload, returns it again
+// even if it's unchanged.
+//
+// If the group name is "", it's regarded as the default group.
+//
+// If parsed, it will panic when calling it.
+func (c *Config) SetOptTTL(group, name string, ttl time.Duration) *Config {
+	c.panicIsParsed(true)
+	if ttl <= 0 {
+		panic(fmt.Errorf("the TTL must be greater than 0"))
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.optTTLs == nil {
+		c.optTTLs = make(map[string]time.Duration, 4)
+	}
+	c.optTTLs[c.optKey(group, name)] = ttl
+	return c
+}
+
+type ttlParser struct {
+	Parser
+	ttl time.Duration
+}
+
+// ExpireAfter wraps the parser to attach the TTL to all the option values
+// set by it, see SetOptTTL, which is overridden by the TTL of the option.
+//
+// It's used together with the parser refreshing the source, such as
+//
+//    conf.AddParser(ExpireAfter(RefreshEvery(parser, time.Minute), time.Minute*5))
+//
+// Notice: the TTL is bound to the priority of the parser, so the other parsers
+// should not have the same priority.
+func ExpireAfter(p Parser, ttl time.Duration) Parser {
+	if p == nil {
+		panic(fmt.Errorf("the parser must not be nil"))
+	} else if ttl <= 0 {
+		panic(fmt.Errorf("the TTL must be greater than 0"))
+	}
+	return ttlParser{Parser: p, ttl: ttl}
+}
+
+func (p ttlParser) Pre(c *Config) error {
+	c.lock.Lock()
+	if c.sourceTTLs == nil {
+		c.sourceTTLs = make(map[int]time.Duration, 2)
+	}
+	c.sourceTTLs[p.Priority()] = p.ttl
+	c.lock.Unlock()
+	return p.Parser.Pre(c)
+}
+
+// optTTL returns the TTL of the option value set by the priority,
+// which is 0 if it never expires.
+func (c *Config) optTTL(group, name string, priority int) time.Duration {
+	if c.dryRun || c.sourceName(priority) == "default" {
+		return 0
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if ttl, ok := c.optTTLs[c.optKey(group, name)]; ok {
+		return ttl
+	}
+	return c.sourceTTLs[priority]
+}
+
+// resetTTL restarts the timer to expire the value of the option,
+// which must be called by holding the lock of the group.
+func (g *OptGroup) resetTTL(name string, opt *option) {
+	if opt.timer != nil {
+		opt.timer.Stop()
+		opt.timer = nil
+	}
+
+	if ttl := g.conf.optTTL(g.name, name, opt.prio); ttl > 0 {
+		opt.ttlSeq++
+		seq := opt.ttlSeq
+		opt.timer = time.AfterFunc(ttl, func() { g.expireOpt(name, opt, seq) })
+	}
+}
+
+// expireOpt reverts the value of the option to the default if the timer
+// has not been reset.
+func (g *OptGroup) expireOpt(name string, opt *option, seq uint64) {
+	var old, value interface{}
+	var expired, secret bool
+	func() {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+
+		if g.opts[name] != opt || opt.ttlSeq != seq {
+			return
+		}
+
+		expired, secret, opt.timer, opt.counts = true, optIsSecret(opt.opt), nil, nil
+		if value = opt.opt.Default(); value == nil && g.conf.isZero {
+			value = opt.opt.Zero()
+		}
+
+		old = g.values[name]
+		if value == nil {
+			opt.prio = 1 << 31
+			delete(g.values, name)
+		} else {
+			opt.prio = 1000
+			g.values[name] = value
+		}
+		if field, ok := g.fields[name]; ok {
+			g.conf.setStructField(field, value)
+		}
+	}()
+
+	if expired {
+		gen := g.conf.nextGeneration()
+		g.conf.debug("Expire [%s]:[%s] to [%v]", g.name, name, value)
+		g.conf.optChanged(ChangeEvent{Group: g.name, Name: name, Old: old, New: value,
+			Source: "expired", Generation: gen}, secret)
+	}
+}
+
+// touchOpts resets the TTLs of the option values which are returned by
+// the parser with the priority again, even if they are unchanged.
+func (c *Config) touchOpts(priority int, values map[string]map[string]interface{}) {
+	c.lock.RLock()
+	noTTL := len(c.optTTLs) == 0 && len(c.sourceTTLs) == 0
+	c.lock.RUnlock()
+	if noTTL {
+		return
+	}
+
+	for gname, opts := range values {
+		group := c.getGroupByName(gname, false)
+		if group == nil {
+			continue
+		}
+
+		group.lock.Lock()
+		for name := range opts {
+			if opt, ok := group.opts[name]; ok && opt.prio == priority {
+				group.resetTTL(name, opt)
+			}
+		}
+		group.lock.Unlock()
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Txn is the transaction to update several options together, which is
+// created by Config.Begin.
+type Txn struct {
+	conf *Config
+	sets []txnSet
+	done bool
+}
+
+type txnSet struct {
+	prio  int
+	group string
+	name  string
+	value interface{}
+}
+
+// txnChange is the change of the option applied by the transaction.
+type txnChange struct {
+	group *OptGroup
+	name  string
+	prio  int
+	value interface{}
+
+	applied bool
+	oldPrio int
+	old     interface{}
+	had     bool          // Whether the option had the old value.
+	secret  bool          // Whether the option is secret.
+	field   reflect.Value // The copy of the old value of the bound field
+	counts  map[int]int   // The copy of the old counts of the count option
+}
+
+// Begin starts a transaction to update several options together, such as
+// the interdependent options like the min and max sizes of the pool.
+//
+//    txn := conf.Begin()
+//    txn.SetOptValue(0, "pool", "min", 10)
+//    txn.SetOptValue(0, "pool", "max", 100)
+//    if err := txn.Commit(); err != nil {
+//        // No option is changed.
+//    }
+//
+// The values are validated by the validators of the options, then applied
+// together. After applied, the validators of the whole configuration, such as
+// the struct implementing StructValidator, are called, and all the changes
+// are rolled back if any of them fails. The observers are notified only after
+// all the changes are committed.
+//
+// Notice: the applied values may be read by others during validating
+// the whole configuration, even if they are rolled back later.
+func (c *Config) Begin() *Txn {
+	return &Txn{conf: c}
+}
+
+// SetOptValue is the same as Config.SetOptValue, but the value is not set
+// until committing the transaction.
+func (t *Txn) SetOptValue(priority int, groupName, optName string, optValue interface{}) *Txn {
+	t.sets = append(t.sets, txnSet{prio: priority, group: groupName, name: optName, value: optValue})
+	return t
+}
+
+// Rollback discards the transaction.
+func (t *Txn) Rollback() {
+	t.done = true
+	t.sets = nil
+}
+
+// Commit validates and applies all the option values of the transaction,
+// and returns ValidateError containing all the errors if failing.
+func (t *Txn) Commit() error {
+	if t.done {
+		return fmt.Errorf("the transaction has been done")
+	}
+	t.done = true
+
+	c := t.conf
+	changes, err := t.parse()
+	if err != nil {
+		return err
+	}
+
+	// Apply all the changes, then validate the whole configuration.
+	if err = t.apply(changes, false); err != nil {
+		return ValidateError{Errors: []error{err}}
+	}
+	for _, v := range c.validators {
+		if err = v(); err != nil {
+			t.apply(changes, true)
+			return ValidateError{Errors: []error{err}}
+		}
+	}
+
+	// All the changes of the transaction have the same generation.
+	var gen uint64
+	for _, ch := range changes {
+		if !ch.applied || (ch.had && reflect.DeepEqual(ch.old, ch.value)) {
+			continue // Not notify the unchanged value.
+		} else if gen == 0 {
+			gen = c.nextGeneration()
+		}
+
+		c.debug("Set [%s]:[%s] to [%v]", ch.group.name, ch.name, ch.value)
+		c.optChanged(ChangeEvent{Group: ch.group.name, Name: ch.name, Old: ch.old,
+			New: ch.value, Source: c.sourceName(ch.prio), Generation: gen}, ch.secret)
+	}
+	return nil
+}
+
+// parse parses and validates all the values of the transaction.
+func (t *Txn) parse() (changes []*txnChange, err error) {
+	c := t.conf
+	var errs []error
+	indexes := make(map[*OptGroup]map[string]int, 4)
+	for _, set := range t.sets {
+		if set.prio < 0 {
+			errs = append(errs, fmt.Errorf("the priority must not be the negative"))
+			continue
+		}
+
+		gname, name := c.redirectOpt(set.group, set.name)
+		group := c.getGroupByName(gname, false)
+		if group == nil {
+			errs = append(errs, fmt.Errorf("no group '%s'", gname))
+			continue
+		}
+
+		value, err := group.parseOptValue(name, set.value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Only the value of the same option with the highest priority,
+		// or the last one, is applied.
+		ch := &txnChange{group: group, name: name, prio: set.prio, value: value}
+		if index, ok := indexes[group][name]; ok {
+			if ch.prio <= changes[index].prio {
+				changes[index] = ch
+			}
+		} else {
+			if indexes[group] == nil {
+				indexes[group] = make(map[string]int, 4)
+			}
+			indexes[group][name] = len(changes)
+			changes = append(changes, ch)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, ValidateError{Errors: errs}
+	}
+	return
+}
+
+// apply applies or rolls back the changes by holding the locks of all
+// the groups. Nothing is applied if any option has been unregistered.
+func (t *Txn) apply(changes []*txnChange, rollback bool) error {
+	groups := make(map[string]*OptGroup, len(changes))
+	for _, ch := range changes {
+		groups[ch.group.name] = ch.group
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		groups[name].lock.Lock()
+		defer groups[name].lock.Unlock()
+	}
+
+	c := t.conf
+	if c.structLock != nil {
+		c.structLock.Lock()
+		defer c.structLock.Unlock()
+	}
+
+	if rollback {
+		for _, ch := range changes {
+			if !ch.applied {
+				continue
+			}
+
+			opt := ch.group.opts[ch.name]
+			opt.prio, opt.counts = ch.oldPrio, ch.counts
+			ch.group.resetTTL(ch.name, opt)
+			if ch.had {
+				ch.group.values[ch.name] = ch.old
+			} else {
+				delete(ch.group.values, ch.name)
+			}
+			if field, ok := ch.group.fields[ch.name]; ok && ch.field.IsValid() {
+				field.Set(ch.field)
+			}
+		}
+		return nil
+	}
+
+	// The option may be unregistered after parsing the transaction.
+	for _, ch := range changes {
+		if ch.group.opts[ch.name] == nil {
+			return fmt.Errorf("no the option '%s' in the group '%s'", ch.name, ch.group.name)
+		}
+	}
+
+	for _, ch := range changes {
+		opt := ch.group.opts[ch.name]
+		prio := ch.prio
+		if isCountOpt(opt.opt) {
+			ch.counts = make(map[int]int, len(opt.counts))
+			for p, count := range opt.counts {
+				ch.counts[p] = count
+			}
+			ch.value, prio = opt.addCount(ch.prio, ch.value.(int))
+		} else if ch.prio > opt.prio {
+			c.debug("Ignore the option [%s]:[%s]: %d > %d", ch.group.name, ch.name, ch.prio, opt.prio)
+			continue
+		}
+
+		ch.applied = true
+		ch.secret = optIsSecret(opt.opt)
+		ch.old, ch.had = ch.group.values[ch.name]
+		ch.oldPrio, opt.prio = opt.prio, prio
+		ch.group.resetTTL(ch.name, opt)
+		ch.group.values[ch.name] = ch.value
+		if field, ok := ch.group.fields[ch.name]; ok {
+			ch.field = reflect.New(field.Type()).Elem()
+			ch.field.Set(field)
+			setFieldValue(field, ch.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Unmarshal is equal to c.Group("").Unmarshal(v), which copies the current
+// option values of the default group and its sub-groups into the struct v.
+func (c *Config) Unmarshal(v interface{}) error {
+	return c.getGroupByName(c.groupName, true).Unmarshal(v)
+}
+
+// Unmarshal copies the current option values of the group into the struct v,
+// which must be a pointer to a struct variable, so the consumer which doesn't
+// register the struct can still get a typed snapshot, such as
+//
+//    var opts struct {
+//        Addr     string
+//        MaxConns int
+//        Log      struct {
+//            Level string
+//        }
+//    }
+//    conf.Unmarshal(&opts)
+//
+// The field matches the option by the tag "name", or the field name
+// case-insensitively ignoring "-" and "_", such as "max_conns" for MaxConns.
+// The nested struct matches the sub-group, and the fields of the anonymous
+// struct match the options of the current group like RegisterStruct.
+// The field which has no matched option or value is not changed.
+//
+// The value is converted to the type of the field if necessary, such as
+// int to int64, or the string to the type implementing encoding.TextUnmarshaler.
+// But it will return an error if it can't be converted.
+func (g *OptGroup) Unmarshal(v interface{}) error {
+	sv := reflect.ValueOf(v)
+	if !sv.IsValid() || sv.Kind() != reflect.Ptr || sv.IsNil() {
+		return fmt.Errorf("the struct is invalid or can't be set")
+	} else if sv = sv.Elem(); sv.Kind() != reflect.Struct {
+		return fmt.Errorf("the struct is not a struct")
+	}
+	return g.unmarshal(sv)
+}
+
+func (g *OptGroup) unmarshal(sv reflect.Value) error {
+	names := make(map[string]string, len(g.opts))
+	g.lock.RLock()
+	for name := range g.opts {
+		names[normalizeFieldName(name)] = name
+	}
+	g.lock.RUnlock()
+
+	st := sv.Type()
+	for i, num := 0, sv.NumField(); i < num; i++ {
+		field := st.Field(i)
+		field.Tag = g.conf.expandStructTag(field.Tag)
+		fieldV := sv.Field(i)
+		if !fieldV.CanSet() {
+			continue
+		}
+
+		tagname := strings.TrimSpace(field.Tag.Get("name"))
+		if tagname == "-" {
+			continue
+		}
+
+		name := names[normalizeFieldName(field.Name)]
+		if tagname != "" {
+			name = tagname
+		}
+
+		if isNestedStruct(field.Type) && (name == "" || !g.HasOpt(name)) {
+			if fieldV.Kind() == reflect.Ptr {
+				if fieldV.IsNil() {
+					fieldV.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldV = fieldV.Elem()
+			}
+
+			group := g
+			if !field.Anonymous || tagname != "" {
+				if tagname == "" {
+					tagname = strings.ToLower(field.Name)
+				}
+				group = g.conf.getGroupByName(g.conf.mergeGroupName(g.name, tagname), false)
+			}
+
+			if group != nil {
+				if err := group.unmarshal(fieldV); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if value := g.Value(name); value != nil {
+			if err := setUnmarshalValue(fieldV, value); err != nil {
+				return fmt.Errorf("the field %s: %s", field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeFieldName returns the lower-case name without "-" and "_".
+func normalizeFieldName(name string) string {
+	name = strings.Replace(name, "-", "", -1)
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// isNestedStruct reports whether the type is the struct or the pointer to
+// the struct, which is not the value type, such as time.Time.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || isTextUnmarshaler(t) {
+		return false
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(Secret{}), reflect.TypeOf(Decimal{}):
+		return false
+	}
+	return true
+}
+
+// setUnmarshalValue sets the option value into the field,
+// which is converted to the type of the field if necessary.
+func setUnmarshalValue(field reflect.Value, value interface{}) error {
+	ft := field.Type()
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(ft) {
+		field.Set(v)
+		return nil
+	}
+
+	if s, ok := value.(string); ok && isTextUnmarshaler(ft) {
+		fv := reflect.New(ft)
+		if err := fv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		field.Set(fv.Elem())
+		return nil
+	}
+
+	// Only convert the number to the number, or the string to the string,
+	// such as int to int64, or string to "type Color string".
+	vk, fk := kindClass(v.Kind()), kindClass(ft.Kind())
+	if vk != 0 && vk == fk && v.Type().ConvertibleTo(ft) {
+		field.Set(v.Convert(ft))
+		return nil
+	} else if v.Kind() == reflect.Slice && ft.Kind() == reflect.Slice &&
+		v.Type().Elem() == ft.Elem() {
+		field.Set(v.Convert(ft))
+		return nil
+	}
+
+	return fmt.Errorf("can't set the value of the type %T to %s", value, ft.String())
+}
+
+// kindClass returns 1 for the number, 2 for the string, or 0 for others.
+func kindClass(k reflect.Kind) int {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.String:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -17,8 +17,18 @@ limitations under the License.
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/xgfone/go-tools/types"
 )
@@ -191,10 +201,93 @@ func ToFloat64Slice(_v interface{}) (v []float64, err error) {
 	return
 }
 
+// UnixLayout is the special time layout to parse the Unix epoch seconds,
+// such as "1577836800", which is used by ToTimeWithLayouts.
+const UnixLayout = "unix"
+
+// ToTimeWithLayouts does the best to convert a certain value to time.Time.
+//
+// If the value is string, it is parsed by the layouts in turn until one
+// succeeds, then time.RFC3339Nano. The layout UnixLayout parses the Unix epoch
+// seconds. If the value is integer, it's the Unix epoch seconds.
+func ToTimeWithLayouts(_v interface{}, layouts ...string) (v time.Time, err error) {
+	var s string
+	switch vv := _v.(type) {
+	case time.Time:
+		return vv, nil
+	case string:
+		s = strings.TrimSpace(vv)
+	case []byte:
+		s = strings.TrimSpace(string(vv))
+	default:
+		var sec int64
+		if sec, err = ToInt64(_v); err != nil {
+			return
+		}
+		return time.Unix(sec, 0), nil
+	}
+
+	for _, layout := range layouts {
+		if layout == UnixLayout {
+			if sec, e := strconv.ParseInt(s, 10, 64); e == nil {
+				return time.Unix(sec, 0), nil
+			}
+		} else if v, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+
+	if v, err = time.Parse(time.RFC3339Nano, s); err != nil {
+		layouts = append(append([]string{}, layouts...), time.RFC3339Nano)
+		err = fmt.Errorf("the time '%s' does not match the layouts '%s'",
+			s, strings.Join(layouts, "', '"))
+	}
+	return
+}
+
+// ToTimesWithLayouts does the best to convert a certain value to []time.Time.
+//
+// If the value is string, they are separated by the comma and the each value
+// is parsed by ToTimeWithLayouts.
+func ToTimesWithLayouts(_v interface{}, layouts ...string) (v []time.Time, err error) {
+	switch vv := _v.(type) {
+	case string:
+		vs := strings.Split(vv, ",")
+		v = make([]time.Time, 0, len(vs))
+		for _, s := range vs {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+
+			t, err := ToTimeWithLayouts(s, layouts...)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, t)
+		}
+	case []string, []interface{}:
+		vs := toInterfaceSlice(vv)
+		v = make([]time.Time, len(vs))
+		for i, s := range vs {
+			if v[i], err = ToTimeWithLayouts(s, layouts...); err != nil {
+				return nil, err
+			}
+		}
+	case []time.Time:
+		v = vv
+	default:
+		err = types.ErrUnknownType
+	}
+	return
+}
+
 // ToTimes does the best to convert a certain value to []time.Time.
 //
 // If the value is string, they are separated by the comma and the each value
 // is parsed by the format, layout.
+//
+// If the value is []string or []interface{}, such as the list decoded from
+// JSON or YAML, the each element is parsed by the format, layout, too.
 func ToTimes(layout string, _v interface{}) (v []time.Time, err error) {
 	switch vv := _v.(type) {
 	case string:
@@ -213,6 +306,22 @@ func ToTimes(layout string, _v interface{}) (v []time.Time, err error) {
 		}
 	case []time.Time:
 		v = vv
+	case []string, []interface{}:
+		vs := toInterfaceSlice(vv)
+		v = make([]time.Time, len(vs))
+		for i, s := range vs {
+			if t, ok := s.(time.Time); ok {
+				v[i] = t
+				continue
+			}
+
+			ss, err := ToString(s)
+			if err != nil {
+				return nil, err
+			} else if v[i], err = time.Parse(layout, strings.TrimSpace(ss)); err != nil {
+				return nil, err
+			}
+		}
 	default:
 		err = types.ErrUnknownType
 	}
@@ -223,6 +332,10 @@ func ToTimes(layout string, _v interface{}) (v []time.Time, err error) {
 //
 // If the value is string, they are separated by the comma and the each value
 // is parsed by time.ParseDuration().
+//
+// If the value is []string or []interface{}, such as the list decoded from
+// JSON or YAML, the each string element is parsed by time.ParseDuration()
+// and the each integer element is the nanoseconds.
 func ToDurations(_v interface{}) (v []time.Duration, err error) {
 	switch vv := _v.(type) {
 	case string:
@@ -241,8 +354,356 @@ func ToDurations(_v interface{}) (v []time.Duration, err error) {
 		}
 	case []time.Duration:
 		v = vv
+	case []string, []interface{}:
+		vs := toInterfaceSlice(vv)
+		v = make([]time.Duration, len(vs))
+		for i, s := range vs {
+			switch d := s.(type) {
+			case time.Duration:
+				v[i] = d
+			case string:
+				if v[i], err = time.ParseDuration(strings.TrimSpace(d)); err != nil {
+					return nil, err
+				}
+			default:
+				var n int64
+				if n, err = ToInt64(d); err != nil {
+					return nil, err
+				}
+				v[i] = time.Duration(n)
+			}
+		}
 	default:
 		err = types.ErrUnknownType
 	}
 	return
 }
+
+// toInterfaceSlice converts []string or []interface{} to []interface{}.
+func toInterfaceSlice(v interface{}) []interface{} {
+	switch vs := v.(type) {
+	case []interface{}:
+		return vs
+	case []string:
+		is := make([]interface{}, len(vs))
+		for i, s := range vs {
+			is[i] = s
+		}
+		return is
+	default:
+		return nil
+	}
+}
+
+var sizeUnits = map[string]float64{
+	"":  1,
+	"b": 1,
+
+	"k":  1e3,
+	"kb": 1e3,
+	"m":  1e6,
+	"mb": 1e6,
+	"g":  1e9,
+	"gb": 1e9,
+	"t":  1e12,
+	"tb": 1e12,
+	"p":  1e15,
+	"pb": 1e15,
+
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// ToSize does the best to convert a certain value to the int64 byte count.
+//
+// If the value is string, it is the number with the optional unit suffix,
+// which is case-insensitive, such as "512KiB", "2GB" or "1.5M". The SI units,
+// "K", "KB", "M", "MB", "G", "GB", "T", "TB", "P" and "PB", are based on 1000,
+// and the IEC units, "Ki", "KiB", "Mi", "MiB", "Gi", "GiB", "Ti", "TiB", "Pi"
+// and "PiB", are based on 1024.
+func ToSize(_v interface{}) (v int64, err error) {
+	var s string
+	switch vv := _v.(type) {
+	case string:
+		s = vv
+	case []byte:
+		s = string(vv)
+	default:
+		return ToInt64(_v)
+	}
+
+	s = strings.TrimSpace(s)
+	index := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if index < 0 {
+		index = len(s)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[index:]))]
+	if !ok || index == 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	number, err := strconv.ParseFloat(s[:index], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(number * unit), nil
+}
+
+// ToBigInt does the best to convert a certain value to *big.Int.
+//
+// If the value is string, it is the decimal integer, or the integer with
+// the prefix "0x", "0o" or "0b".
+func ToBigInt(_v interface{}) (v *big.Int, err error) {
+	switch vv := _v.(type) {
+	case *big.Int:
+		if vv == nil {
+			return nil, types.ErrUnknownType
+		}
+		return new(big.Int).Set(vv), nil
+	case big.Int:
+		return new(big.Int).Set(&vv), nil
+	case string, []byte:
+		s, _ := ToString(vv)
+		s = strings.Replace(strings.TrimSpace(s), "_", "", -1)
+		if strings.HasPrefix(s, "0o") || strings.HasPrefix(s, "0O") {
+			s = "0" + s[2:] // Go 1.11 does not support the prefix "0o".
+		}
+
+		var ok bool
+		if v, ok = new(big.Int).SetString(s, 0); !ok {
+			return nil, fmt.Errorf("invalid integer '%s'", s)
+		}
+		return v, nil
+	case uint, uint8, uint16, uint32, uint64:
+		var u uint64
+		if u, err = ToUint64(vv); err == nil {
+			v = new(big.Int).SetUint64(u)
+		}
+		return
+	case float32, float64:
+		var f float64
+		if f, err = ToFloat64(vv); err == nil {
+			if v, _ = big.NewFloat(f).Int(nil); !big.NewFloat(f).IsInt() {
+				return nil, fmt.Errorf("the float '%v' is not an integer", f)
+			}
+		}
+		return
+	default:
+		var i int64
+		if i, err = ToInt64(vv); err == nil {
+			v = big.NewInt(i)
+		}
+		return
+	}
+}
+
+// ToJSON does the best to convert a certain value to the compacted
+// json.RawMessage.
+//
+// If the value is string, []byte or json.RawMessage, it must be the valid JSON,
+// and the empty is regarded as "null". Or, it is encoded by json.Marshal.
+func ToJSON(_v interface{}) (json.RawMessage, error) {
+	var data []byte
+	switch v := _v.(type) {
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return json.Marshal(v)
+	}
+
+	if data = bytes.TrimSpace(data); len(data) == 0 {
+		return json.RawMessage("null"), nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := json.Compact(buf, data); err != nil {
+		return nil, fmt.Errorf("invalid json: %s", err)
+	}
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+// ToCIDR does the best to convert a certain value to *net.IPNet.
+//
+// If the value is string, it is parsed by net.ParseCIDR().
+func ToCIDR(_v interface{}) (v *net.IPNet, err error) {
+	switch vv := _v.(type) {
+	case string:
+		_, v, err = net.ParseCIDR(strings.TrimSpace(vv))
+	case []byte:
+		_, v, err = net.ParseCIDR(strings.TrimSpace(string(vv)))
+	case *net.IPNet:
+		v = vv
+	case net.IPNet:
+		v = &vv
+	default:
+		err = types.ErrUnknownType
+	}
+	return
+}
+
+// ToCIDRs does the best to convert a certain value to []*net.IPNet.
+//
+// If the value is string, they are separated by the comma and the each value
+// is parsed by net.ParseCIDR().
+func ToCIDRs(_v interface{}) (v []*net.IPNet, err error) {
+	switch vv := _v.(type) {
+	case string:
+		vs := strings.Split(vv, ",")
+		v = make([]*net.IPNet, 0, len(vs))
+		for _, s := range vs {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+
+			_, i, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, i)
+		}
+	case []string:
+		v = make([]*net.IPNet, len(vv))
+		for i, s := range vv {
+			if _, v[i], err = net.ParseCIDR(strings.TrimSpace(s)); err != nil {
+				return nil, err
+			}
+		}
+	case []*net.IPNet:
+		v = vv
+	default:
+		err = types.ErrUnknownType
+	}
+	return
+}
+
+// doHTTPJSON sends the http request and decodes the JSON response body
+// into result if result is not nil.
+//
+// It returns an error if the status code of the response is not 2xx.
+func doHTTPJSON(client *http.Client, req *http.Request, result interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status,
+			strings.TrimSpace(string(data)))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return dec.Decode(result)
+}
+
+// jsonValueToString converts the value decoded from JSON to the string
+// which can be parsed by the option.
+//
+// The array is converted to the string separated by the comma.
+func jsonValueToString(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case []interface{}:
+		ss := make([]string, len(vv))
+		for i, _v := range vv {
+			ss[i] = jsonValueToString(_v)
+		}
+		return strings.Join(ss, ",")
+	case map[string]interface{}:
+		data, _ := json.Marshal(vv)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", vv)
+	}
+}
+
+// toStringSlice converts the slice value to []string.
+func toStringSlice(v interface{}) []string {
+	switch vs := v.(type) {
+	case []string:
+		return vs
+	case []time.Time:
+		ss := make([]string, len(vs))
+		for i, t := range vs {
+			ss[i] = t.Format(time.RFC3339)
+		}
+		return ss
+	}
+
+	var ss []string
+	for _, s := range strings.Split(strings.Trim(fmt.Sprintf("%v", v), "[]"), " ") {
+		if s != "" {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// LowerCaseName converts the field name to the lower case,
+// such as "AccessKeyID" to "accesskeyid".
+func LowerCaseName(name string) string {
+	return strings.ToLower(name)
+}
+
+// SnakeCaseName converts the field name to the snake case,
+// such as "AccessKeyID" to "access_key_id".
+func SnakeCaseName(name string) string {
+	return splitCamelCase(name, '_')
+}
+
+// KebabCaseName converts the field name to the kebab case,
+// such as "AccessKeyID" to "access-key-id".
+func KebabCaseName(name string) string {
+	return splitCamelCase(name, '-')
+}
+
+// AsIsName returns the field name as it is, such as "AccessKeyID".
+func AsIsName(name string) string {
+	return name
+}
+
+// splitCamelCase splits the camel-case name into the lower-case words
+// separated by sep, in which the acronym is regarded as a word.
+func splitCamelCase(name string, sep byte) string {
+	rs := []rune(name)
+	buf := make([]rune, 0, len(rs)+4)
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				buf = append(buf, rune(sep))
+			}
+		}
+		buf = append(buf, unicode.ToLower(r))
+	}
+	return string(buf)
+}
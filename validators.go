@@ -22,6 +22,8 @@ import (
 	"net/mail"
 	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Validator is an interface to validate whether the value v is valid.
@@ -109,8 +111,11 @@ func toString(v interface{}) (string, error) {
 	if v == nil {
 		return "", errNil
 	}
-	if s, ok := v.(string); ok {
+	switch s := v.(type) {
+	case string:
 		return s, nil
+	case Secret:
+		return s.Unmask(), nil
 	}
 	return "", errStrType
 }
@@ -180,7 +185,7 @@ func NewStrLenValidator(min, max int) Validator {
 
 		_len := len(s)
 		if _len > max || _len < min {
-			return NewValidatorErrorf(group, name,
+			return NewValidatorErrorf(group, name, v,
 				"the length of '%s' is %d, not between %d and %d",
 				s, _len, min, max)
 		}
@@ -204,21 +209,30 @@ func NewStrNotEmptyValidator() Validator {
 	})
 }
 
+type strArrayValidator []string
+
+// Choices returns the valid values, which is used by the shell completion.
+func (a strArrayValidator) Choices() []string {
+	return a
+}
+
+func (a strArrayValidator) Validate(group, name string, v interface{}) error {
+	s, err := toString(v)
+	if err != nil {
+		return NewValidatorError(group, name, v, err)
+	}
+	for _, v := range a {
+		if s == v {
+			return nil
+		}
+	}
+	return NewValidatorErrorf(group, name, v, "the value %s is not in %v", s, []string(a))
+}
+
 // NewStrArrayValidator returns a validator to validate that the value is in
 // the array.
 func NewStrArrayValidator(array []string) Validator {
-	return ValidatorFunc(func(group, name string, v interface{}) error {
-		s, err := toString(v)
-		if err != nil {
-			return NewValidatorError(group, name, v, err)
-		}
-		for _, v := range array {
-			if s == v {
-				return nil
-			}
-		}
-		return NewValidatorErrorf(group, name, "the value %s is not in %v", s, array)
-	})
+	return strArrayValidator(array)
 }
 
 // NewRegexpValidator returns a validator to validate whether the value match
@@ -235,7 +249,7 @@ func NewRegexpValidator(pattern string) Validator {
 		if ok, err := regexp.MatchString(pattern, s); err != nil {
 			return NewValidatorError(group, name, v, err)
 		} else if !ok {
-			return NewValidatorErrorf(group, name,
+			return NewValidatorErrorf(group, name, v,
 				"'%s' doesn't match the value '%s'", s, pattern)
 		}
 		return nil
@@ -344,3 +358,130 @@ func NewAddressValidator() Validator {
 		return nil
 	})
 }
+
+// parseValidateTag compiles the validators declared by the struct tag
+// "validate", such as `validate:"notempty,url"` or `validate:"range(1,65535)"`,
+// which are separated by the comma.
+//
+// zero is the zero value of the option, which is used to decide whether
+// "range" is for the integer or the float.
+func parseValidateTag(tag string, zero interface{}) (vs []Validator, err error) {
+	for _, item := range splitValidateTag(tag) {
+		name, args := item, []string(nil)
+		if index := strings.IndexByte(item, '('); index > -1 {
+			if item[len(item)-1] != ')' {
+				return nil, fmt.Errorf("invalid validator '%s'", item)
+			}
+
+			name = strings.TrimSpace(item[:index])
+			if arg := item[index+1 : len(item)-1]; name == "regexp" {
+				args = []string{arg}
+			} else {
+				args = strings.Split(arg, ",")
+				for i := range args {
+					args[i] = strings.TrimSpace(args[i])
+				}
+			}
+		}
+
+		var v Validator
+		if v, err = newTagValidator(name, args, zero); err != nil {
+			return nil, fmt.Errorf("invalid validator '%s': %s", item, err)
+		}
+		vs = append(vs, v)
+	}
+	return
+}
+
+func newTagValidator(name string, args []string, zero interface{}) (Validator, error) {
+	argn := map[string]int{"len": 2, "range": 2, "regexp": 1}[name]
+	if name == "oneof" {
+		if len(args) == 0 || (len(args) == 1 && args[0] == "") {
+			return nil, fmt.Errorf("no choices")
+		}
+	} else if len(args) != argn {
+		return nil, fmt.Errorf("expect %d arguments, but got %d", argn, len(args))
+	}
+
+	switch name {
+	case "notempty":
+		return NewStrNotEmptyValidator(), nil
+	case "url":
+		return NewURLValidator(), nil
+	case "ip":
+		return NewIPValidator(), nil
+	case "email":
+		return NewEmailValidator(), nil
+	case "address":
+		return NewAddressValidator(), nil
+	case "port":
+		return NewPortValidator(), nil
+	case "oneof":
+		return NewStrArrayValidator(args), nil
+	case "regexp":
+		if _, err := regexp.Compile(args[0]); err != nil {
+			return nil, err
+		}
+		return NewRegexpValidator(args[0]), nil
+	case "len":
+		min, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, err
+		}
+		max, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return NewStrLenValidator(min, max), nil
+	case "range":
+		switch zero.(type) {
+		case float32, float64:
+			min, err := strconv.ParseFloat(args[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			max, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			return NewFloatRangeValidator(min, max), nil
+		default:
+			min, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			max, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			return NewIntegerRangeValidator(min, max), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown validator")
+	}
+}
+
+// splitValidateTag splits the tag by the comma out of the parentheses.
+func splitValidateTag(tag string) (items []string) {
+	var depth, start int
+	for i, c := range tag {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				if item := strings.TrimSpace(tag[start:i]); item != "" {
+					items = append(items, item)
+				}
+				start = i + 1
+			}
+		}
+	}
+
+	if item := strings.TrimSpace(tag[start:]); item != "" {
+		items = append(items, item)
+	}
+	return
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewVaultParser returns a new parser to resolve the secret options
+// from the KV v2 secrets engine of HashiCorp Vault.
+//
+// addr is the address of the Vault server, such as "http://127.0.0.1:8200",
+// and token is the Vault token. If they are empty, the environment variables
+// VAULT_ADDR and VAULT_TOKEN will be used instead.
+//
+// secrets is the mapping from the option to the secret field. The key is
+// the option name with its group, such as "db.mysql.password", and the value
+// is the secret path with the mount point plus the field, such as
+// "secret/myapp/mysql#password". If the field is missing, it is the option
+// name. Only the options in secrets are resolved from Vault.
+//
+// If refresh is greater than 0, the secrets will be checked every refresh
+// interval. When the version of a secret has been changed, the new values
+// will be updated by SetOptValue. If the token is renewable, it is renewed
+// when checking the secrets after half of its TTL, so refresh should be less
+// than that.
+func NewVaultParser(priority int, addr, token string, secrets map[string]string,
+	refresh time.Duration) Parser {
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	client := &vaultClient{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	return newSecretParser("vault", priority, secrets, refresh, client.fetch)
+}
+
+type vaultClient struct {
+	addr   string
+	token  string
+	client *http.Client
+
+	lock    sync.Mutex
+	lookup  bool
+	renewAt time.Time
+}
+
+func (c *vaultClient) request(method, path string, result interface{}) error {
+	req, err := http.NewRequest(method, c.addr+"/v1/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+	return doHTTPJSON(c.client, req, result)
+}
+
+// fetch reads the KV v2 secret by ref, such as "secret/myapp/mysql",
+// and returns the JSON object of its data and its version.
+func (c *vaultClient) fetch(ref string) ([]byte, string, error) {
+	if c.addr == "" {
+		return nil, "", fmt.Errorf("the address of Vault is empty")
+	}
+
+	// Insert the "data" segment after the mount point for KV v2.
+	ss := strings.SplitN(strings.Trim(ref, "/"), "/", 2)
+	if len(ss) != 2 || ss[0] == "" || ss[1] == "" {
+		return nil, "", fmt.Errorf("invalid Vault secret path '%s'", ref)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.renewToken(); err != nil {
+		return nil, "", err
+	}
+
+	var resp struct {
+		Data struct {
+			Data     json.RawMessage `json:"data"`
+			Metadata struct {
+				Version int64 `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+
+	if err := c.request(http.MethodGet, ss[0]+"/data/"+ss[1], &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Data.Data, strconv.FormatInt(resp.Data.Metadata.Version, 10), nil
+}
+
+// renewToken looks up the token for the first time, and renews it after
+// half of its TTL if it is renewable.
+func (c *vaultClient) renewToken() error {
+	if !c.lookup {
+		var resp struct {
+			Data struct {
+				TTL       int64 `json:"ttl"`
+				Renewable bool  `json:"renewable"`
+			} `json:"data"`
+		}
+
+		// The token without the permission to look up itself is not renewed.
+		c.lookup = true
+		if c.request(http.MethodGet, "auth/token/lookup-self", &resp) == nil {
+			c.setTTL(resp.Data.TTL, resp.Data.Renewable)
+		}
+		return nil
+	} else if c.renewAt.IsZero() || time.Now().Before(c.renewAt) {
+		return nil
+	}
+
+	var resp struct {
+		Auth struct {
+			LeaseDuration int64 `json:"lease_duration"`
+			Renewable     bool  `json:"renewable"`
+		} `json:"auth"`
+	}
+
+	if err := c.request(http.MethodPost, "auth/token/renew-self", &resp); err != nil {
+		return fmt.Errorf("failed to renew the token: %s", err)
+	}
+	c.setTTL(resp.Auth.LeaseDuration, resp.Auth.Renewable)
+	return nil
+}
+
+func (c *vaultClient) setTTL(ttl int64, renewable bool) {
+	c.renewAt = time.Time{}
+	if renewable && ttl > 0 {
+		c.renewAt = time.Now().Add(time.Duration(ttl) * time.Second / 2)
+	}
+}
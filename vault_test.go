@@ -0,0 +1,132 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testVaultServer struct {
+	lock    sync.Mutex
+	version int
+	pass    string
+	renewed int
+}
+
+func (s *testVaultServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/auth/token/lookup-self":
+		fmt.Fprint(w, `{"data": {"ttl": 3600, "renewable": true}}`)
+	case "/v1/auth/token/renew-self":
+		s.renewed++
+		fmt.Fprint(w, `{"auth": {"lease_duration": 3600, "renewable": true}}`)
+	case "/v1/secret/data/myapp/mysql":
+		fmt.Fprintf(w, `{"data": {"data": {"user": "root", "password": "%s", "port": 3306},
+			"metadata": {"version": %d}}}`, s.pass, s.version)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testVaultServer) update(pass string) {
+	s.lock.Lock()
+	s.pass = pass
+	s.version++
+	s.lock.Unlock()
+}
+
+func TestVaultParser(t *testing.T) {
+	vault := &testVaultServer{version: 1, pass: "pass"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	conf := NewConfig().AddParser(NewVaultParser(50, server.URL, "token", map[string]string{
+		"mysql.user": "secret/myapp/mysql",
+		"mysql.pass": "secret/myapp/mysql#password",
+		"mysql.port": "secret/myapp/mysql",
+	}, time.Millisecond*10))
+
+	conf.RegisterOpts("mysql", []Opt{
+		Str("user", "", ""),
+		Str("pass", "", ""),
+		Int("port", 0, ""),
+	})
+
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	mysql := conf.Group("mysql")
+	if v := mysql.String("user"); v != "root" {
+		t.Errorf("user: expected 'root', got '%s'", v)
+	}
+	if v := mysql.String("pass"); v != "pass" {
+		t.Errorf("pass: expected 'pass', got '%s'", v)
+	}
+	if v := mysql.Int("port"); v != 3306 {
+		t.Errorf("port: expected 3306, got %d", v)
+	}
+
+	vault.update("newpass")
+	for i := 0; i < 100 && mysql.String("pass") != "newpass"; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if v := mysql.String("pass"); v != "newpass" {
+		t.Errorf("pass: expected 'newpass', got '%s'", v)
+	}
+}
+
+func TestVaultTokenRenewal(t *testing.T) {
+	vault := &testVaultServer{version: 1, pass: "pass"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	client := &vaultClient{addr: server.URL, token: "token", client: server.Client()}
+	if _, _, err := client.fetch("secret/myapp/mysql"); err != nil {
+		t.Fatal(err)
+	} else if client.renewAt.IsZero() {
+		t.Fatal("expect the token to be renewable")
+	}
+
+	client.renewAt = time.Now().Add(-time.Second)
+	if _, version, err := client.fetch("secret/myapp/mysql"); err != nil {
+		t.Error(err)
+	} else if version != "1" {
+		t.Errorf("expect the version '1', but got '%s'", version)
+	}
+	if vault.renewed != 1 || !client.renewAt.After(time.Now()) {
+		t.Errorf("expect the token to be renewed once, but got %d", vault.renewed)
+	}
+
+	if _, _, err := client.fetch("secret"); err == nil {
+		t.Error("expect an error for the invalid secret path")
+	}
+}
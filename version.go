@@ -0,0 +1,116 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// VersionPrinter is used to print the version information into w
+// when giving the CLI version option.
+type VersionPrinter func(w io.Writer, version string) error
+
+// BuildInfo is the build metadata of the program, which is used by
+// NewTextVersionPrinter and NewJSONVersionPrinter.
+//
+// The build metadata is generally injected by the linker flags, such as
+//
+//    go build -ldflags "-X main.commit=$(git rev-parse HEAD) -X main.date=$(date +%F)"
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+func (info BuildInfo) complete(version string) BuildInfo {
+	if info.Version == "" {
+		info.Version = version
+	}
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+	if info.Platform == "" {
+		info.Platform = runtime.GOOS + "/" + runtime.GOARCH
+	}
+	return info
+}
+
+// NewTextVersionPrinter returns a VersionPrinter to print the build metadata
+// line by line, such as
+//
+//    Version:    1.0.0
+//    Commit:     1a2b3c4
+//    Date:       2020-01-01
+//    Go Version: go1.12
+//    Platform:   linux/amd64
+//
+// If Version is empty, it is the version set by SetVersion. If GoVersion or
+// Platform is empty, it is that of the runtime. The empty Commit and Date are
+// omitted.
+func NewTextVersionPrinter(info BuildInfo) VersionPrinter {
+	return func(w io.Writer, version string) (err error) {
+		info := info.complete(version)
+		lines := [][2]string{
+			{"Version", info.Version},
+			{"Commit", info.Commit},
+			{"Date", info.Date},
+			{"Go Version", info.GoVersion},
+			{"Platform", info.Platform},
+		}
+
+		for _, line := range lines {
+			if line[1] != "" {
+				if _, err = fmt.Fprintf(w, "%-11s %s\n", line[0]+":", line[1]); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}
+}
+
+// NewJSONVersionPrinter is the same as NewTextVersionPrinter, but prints
+// the build metadata as a JSON object.
+func NewJSONVersionPrinter(info BuildInfo) VersionPrinter {
+	return func(w io.Writer, version string) error {
+		return json.NewEncoder(w).Encode(info.complete(version))
+	}
+}
+
+// SetVersionPrinter sets the printer to print the version information when
+// giving the CLI version option set by SetVersion. The default is to print
+// the version string.
+//
+// Notice: it is for the CLI parser.
+func (c *Config) SetVersionPrinter(printer VersionPrinter) *Config {
+	c.vPrinter = printer
+	return c
+}
+
+// PrintVersion prints the version information into w by the version printer.
+func (c *Config) PrintVersion(w io.Writer) error {
+	if c.vPrinter != nil {
+		return c.vPrinter(w, c.vVersion)
+	}
+	_, err := fmt.Fprintln(w, c.vVersion)
+	return err
+}
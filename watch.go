@@ -0,0 +1,145 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// WatchParser is the optional interface of the parser for the long-lived
+// source pushing the changes, such as etcd, consul or the gRPC stream,
+// the lifecycle of which is managed by Config instead of the ad-hoc goroutine.
+type WatchParser interface {
+	Parser
+
+	// Watch watches the changes of the source until ctx is done, which is
+	// started in a new goroutine after parsing, and stopped by Config.Close.
+	//
+	// When the source changes, it should call apply with the changed option
+	// values, which are the map from the group name to the option name
+	// and value. They are applied all or nothing with the priority
+	// of the parser by the transaction, see Config.Begin, and the unregistered
+	// options are ignored.
+	//
+	// The returned error is logged by Config.Printf if ctx is not done.
+	Watch(ctx context.Context, apply func(values map[string]map[string]interface{}) error) error
+}
+
+// watchParserOf returns the WatchParser of the parser, which may be wrapped
+// by ExpireAfter.
+func watchParserOf(p Parser) (WatchParser, bool) {
+	if tp, ok := p.(ttlParser); ok {
+		p = tp.Parser
+	}
+	wp, ok := p.(WatchParser)
+	return wp, ok
+}
+
+// startWatchParsers starts to watch the sources of all the WatchParsers.
+func (c *Config) startWatchParsers() {
+	for _, parser := range c.parsers {
+		wp, ok := watchParserOf(parser)
+		if !ok {
+			continue
+		}
+
+		priority := parser.Priority()
+		apply := func(values map[string]map[string]interface{}) error {
+			return c.applyWatched(priority, values)
+		}
+
+		c.goWatch(func(ctx context.Context) {
+			c.debug("Watching the parser '%s'", wp.Name())
+			if err := wp.Watch(ctx, apply); err != nil && ctx.Err() == nil {
+				c.Printf("[%s] Failed to watch: %s", wp.Name(), err)
+			}
+		})
+	}
+}
+
+// goWatch runs f in a new goroutine to watch or poll the source until ctx
+// is canceled by Close, which also waits for f to return.
+//
+// The snapshot staging the parser, such as by Reload, watches nothing,
+// because it is thrown away after parsing.
+func (c *Config) goWatch(f func(ctx context.Context)) {
+	if c.staging {
+		return
+	}
+
+	c.initWatch()
+
+	c.watchWG.Add(1)
+	go func() {
+		defer c.watchWG.Done()
+		f(c.watchCtx)
+	}()
+}
+
+func (c *Config) initWatch() {
+	c.watchOnce.Do(func() {
+		c.watchCtx, c.watchCancel = context.WithCancel(context.Background())
+	})
+}
+
+// sleepContext sleeps for the duration, and reports whether ctx is not done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// applyWatched applies the option values watched by WatchParser.
+func (c *Config) applyWatched(priority int, values map[string]map[string]interface{}) error {
+	txn := c.Begin()
+	for group, opts := range values {
+		for name, value := range opts {
+			gname, oname := c.redirectOpt(group, name)
+			if g := c.getGroupByName(gname, false); g == nil || !g.HasOpt(oname) {
+				c.debug("Ignore the unregistered option [%s]:[%s]", group, name)
+				continue
+			}
+			txn.SetOptValue(priority, group, name, value)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+	c.touchOpts(priority, values)
+	return nil
+}
+
+// Close stops watching or refreshing the sources, such as the parsers
+// implementing WatchParser, the config files by WatchFiles, the parsers
+// wrapped by RefreshEvery and the built-in parsers watching the source
+// by themselves, then waits for them to return. It's safe to call it
+// more than once, and nothing will be watched after closed.
+func (c *Config) Close() error {
+	c.initWatch()
+
+	c.watchCancel()
+	c.watchWG.Wait()
+	return nil
+}
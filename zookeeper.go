@@ -0,0 +1,222 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ZkClient is the ZooKeeper client interface used by the ZooKeeper parser.
+//
+// It is a small subset of the ZooKeeper API, so any ZooKeeper client,
+// such as github.com/samuel/go-zookeeper/zk, can be adapted to it easily.
+//
+// The event channels returned by ChildrenW and GetW should be closed or
+// sent to only once when the znode is changed, which is the same as the
+// one-time trigger watch of ZooKeeper.
+type ZkClient interface {
+	// Children returns the names of the children of the znode path.
+	Children(path string) ([]string, error)
+
+	// ChildrenW is the same as Children, but also watches the change of
+	// the children of the znode path.
+	ChildrenW(path string) ([]string, <-chan struct{}, error)
+
+	// Get returns the data of the znode path.
+	Get(path string) ([]byte, error)
+
+	// GetW is the same as Get, but also watches the change of the data
+	// of the znode path.
+	GetW(path string) ([]byte, <-chan struct{}, error)
+}
+
+type zkParser struct {
+	root   string
+	prio   int
+	watch  bool
+	client ZkClient
+}
+
+// NewZkParser returns a new parser based on the znode tree of ZooKeeper.
+//
+// The children of the znode root are the groups, and the leaf znodes are
+// the options, the data of which is the option value. The leaf znodes
+// directly under root belong to the default group. For example,
+//
+//    /root/port            => the option "port" in the default group
+//    /root/redis/conn      => the option "conn" in the group "redis"
+//    /root/db/mysql/conn   => the option "conn" in the group "db.mysql"
+//
+// If watch is true, it will subscribe to the change events of the znodes,
+// and update the option value by SetOptValue when the znode is changed.
+//
+// Notice: the znodes that have not been registered as the options are ignored.
+func NewZkParser(priority int, client ZkClient, root string, watch bool) Parser {
+	if client == nil {
+		panic(fmt.Errorf("the ZooKeeper client must not be nil"))
+	}
+
+	root = "/" + strings.Trim(root, "/")
+	return zkParser{root: root, prio: priority, watch: watch, client: client}
+}
+
+func (p zkParser) Name() string {
+	return "zookeeper"
+}
+
+func (p zkParser) Priority() int {
+	return p.prio
+}
+
+func (p zkParser) Pre(c *Config) error {
+	return nil
+}
+
+func (p zkParser) Post(c *Config) error {
+	return nil
+}
+
+func (p zkParser) Parse(c *Config) error {
+	p.watch = p.watch && !c.staging
+	w := &zkWatcher{zkParser: p, conf: c, watched: make(map[string]bool)}
+	return w.loadGroup(p.root, "")
+}
+
+type zkWatcher struct {
+	zkParser
+
+	conf    *Config
+	lock    sync.Mutex
+	watched map[string]bool
+}
+
+func (w *zkWatcher) joinPath(parent, name string) string {
+	if parent == "/" {
+		return parent + name
+	}
+	return parent + "/" + name
+}
+
+// markWatched reports whether the znode path needs to be watched.
+func (w *zkWatcher) markWatched(path string) bool {
+	if !w.watch {
+		return false
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.watched[path] {
+		return false
+	}
+	w.watched[path] = true
+	return true
+}
+
+func (w *zkWatcher) unmarkWatched(path string) {
+	w.lock.Lock()
+	delete(w.watched, path)
+	w.lock.Unlock()
+}
+
+func (w *zkWatcher) loadGroup(path, group string) (err error) {
+	var children []string
+	var event <-chan struct{}
+	if w.markWatched(path) {
+		if children, event, err = w.client.ChildrenW(path); err != nil {
+			w.unmarkWatched(path)
+			return
+		}
+	} else if children, err = w.client.Children(path); err != nil {
+		return
+	}
+
+	for _, child := range children {
+		cpath := w.joinPath(path, child)
+		grandchildren, err := w.client.Children(cpath)
+		if err != nil {
+			return err
+		}
+
+		if len(grandchildren) > 0 {
+			err = w.loadGroup(cpath, w.conf.mergeGroupName(group, child))
+		} else {
+			err = w.loadOpt(cpath, group, child)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if event != nil {
+		w.conf.goWatch(func(ctx context.Context) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-event:
+			}
+
+			w.unmarkWatched(path)
+			w.conf.Printf("[%s] The children of '%s' changed", w.Name(), path)
+			if err := w.loadGroup(path, group); err != nil {
+				w.conf.Printf("[%s] Failed to reload '%s': %s", w.Name(), path, err)
+			}
+		})
+	}
+
+	return nil
+}
+
+func (w *zkWatcher) loadOpt(path, group, name string) (err error) {
+	var data []byte
+	var event <-chan struct{}
+	if w.markWatched(path) {
+		if data, event, err = w.client.GetW(path); err != nil {
+			w.unmarkWatched(path)
+			return
+		}
+	} else if data, err = w.client.Get(path); err != nil {
+		return
+	}
+
+	w.conf.Printf("[%s] Parsing znode '%s'", w.Name(), path)
+	value := strings.TrimSpace(string(data))
+	if err = w.conf.setOptValueIfExist(w.prio, group, name, value); err != nil {
+		return
+	}
+
+	if event != nil {
+		w.conf.goWatch(func(ctx context.Context) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-event:
+			}
+
+			w.unmarkWatched(path)
+			w.conf.Printf("[%s] The data of '%s' changed", w.Name(), path)
+			if err := w.loadOpt(path, group, name); err != nil {
+				w.conf.Printf("[%s] Failed to reload '%s': %s", w.Name(), path, err)
+			}
+		})
+	}
+
+	return nil
+}
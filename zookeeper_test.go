@@ -0,0 +1,150 @@
+/*
+Copyright 2017 xgfone
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testZkClient struct {
+	lock    sync.Mutex
+	nodes   map[string]string
+	watches map[string]chan struct{}
+}
+
+func newTestZkClient(nodes map[string]string) *testZkClient {
+	return &testZkClient{nodes: nodes, watches: make(map[string]chan struct{})}
+}
+
+func (c *testZkClient) Children(path string) (children []string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for node := range c.nodes {
+		if strings.HasPrefix(node, prefix) {
+			name := strings.SplitN(node[len(prefix):], "/", 2)[0]
+			if index := sort.SearchStrings(children, name); index == len(children) ||
+				children[index] != name {
+				children = append(children, name)
+				sort.Strings(children)
+			}
+		}
+	}
+	return
+}
+
+func (c *testZkClient) ChildrenW(path string) ([]string, <-chan struct{}, error) {
+	children, err := c.Children(path)
+	return children, c.watch(path), err
+}
+
+func (c *testZkClient) Get(path string) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if data, ok := c.nodes[path]; ok {
+		return []byte(data), nil
+	}
+	return nil, fmt.Errorf("no znode '%s'", path)
+}
+
+func (c *testZkClient) GetW(path string) ([]byte, <-chan struct{}, error) {
+	data, err := c.Get(path)
+	return data, c.watch(path), err
+}
+
+func (c *testZkClient) watch(path string) <-chan struct{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	event := make(chan struct{})
+	c.watches[path] = event
+	return event
+}
+
+func (c *testZkClient) set(path, data string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.nodes[path] = data
+	if event, ok := c.watches[path]; ok {
+		delete(c.watches, path)
+		close(event)
+	}
+}
+
+func TestZkParser(t *testing.T) {
+	client := newTestZkClient(map[string]string{
+		"/app/port":          "80",
+		"/app/redis/conn":    "redis://127.0.0.1",
+		"/app/db/mysql/conn": "root@tcp",
+		"/app/db/mysql/none": "xxx",
+		"/other/port":        "90",
+	})
+
+	updated := make(chan interface{}, 1)
+	conf := NewConfig().AddParser(NewZkParser(50, client, "/app/", true))
+	conf.Observe(func(group, name string, value interface{}) {
+		if name == "port" {
+			updated <- value
+		}
+	})
+	conf.RegisterOpt("", Int("port", 0, ""))
+	conf.RegisterOpt("redis", Str("conn", "", ""))
+	conf.RegisterOpt("db.mysql", Str("conn", "", ""))
+	if err := conf.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+
+	<-updated
+	if v := conf.Int("port"); v != 80 {
+		t.Errorf("port: expected 80, got %d", v)
+	}
+	if v := conf.Group("redis").String("conn"); v != "redis://127.0.0.1" {
+		t.Errorf("redis.conn: expected 'redis://127.0.0.1', got '%s'", v)
+	}
+	if v := conf.Group("db.mysql").String("conn"); v != "root@tcp" {
+		t.Errorf("db.mysql.conn: expected 'root@tcp', got '%s'", v)
+	}
+
+	// The znode is watched again after changed, until closed.
+	for _, port := range []int{8080, 8081} {
+		client.set("/app/port", fmt.Sprint(port))
+		select {
+		case v := <-updated:
+			if v != port {
+				t.Errorf("port: expected %d, got %v", port, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout to wait for the update")
+		}
+	}
+
+	conf.Close()
+	client.set("/app/port", "8082")
+	time.Sleep(time.Millisecond * 10)
+	if v := conf.Int("port"); v != 8081 {
+		t.Errorf("port: expected 8081, got %d", v)
+	}
+}